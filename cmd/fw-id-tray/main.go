@@ -0,0 +1,10 @@
+/*
+Fw-id-tray is the system tray indicator for the firewall identity agent.
+*/
+package main
+
+import "github.com/telekom-mms/fw-id-agent/internal/tray"
+
+func main() {
+	tray.Run()
+}
@@ -4,18 +4,33 @@ package agent
 import (
 	"errors"
 	"fmt"
+	"os/exec"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/telekom-mms/fw-id-agent/internal/client"
 	"github.com/telekom-mms/fw-id-agent/internal/dbusapi"
+	"github.com/telekom-mms/fw-id-agent/internal/diagnostics"
+	"github.com/telekom-mms/fw-id-agent/internal/i18n"
 	"github.com/telekom-mms/fw-id-agent/internal/krbmon"
+	"github.com/telekom-mms/fw-id-agent/internal/logging"
+	"github.com/telekom-mms/fw-id-agent/internal/metrics"
+	"github.com/telekom-mms/fw-id-agent/internal/netmon"
 	"github.com/telekom-mms/fw-id-agent/internal/notify"
+	"github.com/telekom-mms/fw-id-agent/internal/sdnotify"
+	"github.com/telekom-mms/fw-id-agent/internal/statefile"
 	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/fw-id-agent/pkg/history"
 	"github.com/telekom-mms/fw-id-agent/pkg/status"
 	"github.com/telekom-mms/tnd/pkg/tnd"
 )
 
+const (
+	// historySize is the maximum number of events in the event history.
+	historySize = 1000
+)
+
 // Agent is the firewall identity Agent.
 type Agent struct {
 	config *config.Config
@@ -24,8 +39,9 @@ type Agent struct {
 	krbcfg *krbmon.ConfMon
 	tnd    tnd.TND
 	sleep  *SleepMon
+	netmon *netmon.NetMon
 	client *client.Client
-	login  chan status.LoginState
+	login  chan *client.Result
 	errors chan error
 	done   chan struct{}
 	closed chan struct{}
@@ -37,16 +53,65 @@ type Agent struct {
 	// kerberos tgt times
 	kerberosTGT status.KerberosTicket
 
-	// trusted network and login status
+	// wake-up timer, fires when the network settled after wake-up
+	wake <-chan time.Time
+
+	// systemd watchdog ticker, only set if the watchdog is enabled
+	watchdog *time.Ticker
+
+	// current log level and revert timer, fires when a temporary log level
+	// set over D-Bus expires
+	logLevel       string
+	logLevelRevert <-chan time.Time
+
+	// config reload, re-login and state dump requests, e.g., from signals
+	reload  chan *config.Config
+	relogin chan struct{}
+	dump    chan struct{}
+
+	// session monitor, only set if the session policy is not keep,
+	// session events and lock timer, fires when the lock grace period
+	// is over
+	session       *SessionMon
+	sessionEvents chan *SessionEvent
+	lock          <-chan time.Time
+
+	// high-level agent state, paused by session policy, paused by user
+	// and sleeping flags
+	agentState status.AgentState
+	paused     bool
+	userPaused bool
+	sleeping   bool
+
+	// trusted network and login status, source ip used at login
 	trustedNetwork status.TrustedNetwork
 	loginState     status.LoginState
 	loggedIn       bool
+	sourceIP       string
 
-	// last client keep-alive
+	// last client login and keep-alive, consecutive login failures
+	lastLogin     int64
 	lastKeepAlive int64
+	loginFailures uint32
+
+	// persisted state and state file, restored specifies whether the
+	// restored state contains a valid session for the next client
+	state     *statefile.State
+	stateFile string
+	restored  bool
 
 	// notifier
 	notifier *notify.Notifier
+
+	// metrics, metrics server and textfile writer, only set if configured,
+	// and start time of the current login request
+	metrics       *metrics.Metrics
+	metricsServer *metrics.Server
+	metricsFile   *metrics.Textfile
+	loginStart    time.Time
+
+	// event history
+	history *history.History
 }
 
 // logTND logs whether we are connected to a trusted network.
@@ -67,54 +132,254 @@ func (a *Agent) logLogin() {
 	log.Info("Agent logged in successfully")
 }
 
-// notifyTND notifies the user whether we are connected to a trusted network.
-func (a *Agent) notifyTND() {
-	if !a.config.Notifications {
+// Notification actions.
+const (
+	notifyActionReLogin = "relogin"
+	notifyActionKinit   = "kinit"
+	notifyActionDetails = "details"
+)
+
+// notifyActions returns the notification actions the user can invoke for
+// the current state, if the agent is logged out. Details are always offered.
+func (a *Agent) notifyActions(kinit bool) []notify.Action {
+	actions := []notify.Action{}
+	if a.trustedNetwork.Trusted() && !a.paused && !a.loggedIn {
+		actions = append(actions, notify.Action{Key: notifyActionReLogin, Label: i18n.String(i18n.ActionReLogin)})
+	}
+	if kinit && a.config.KinitCommand != "" {
+		actions = append(actions, notify.Action{Key: notifyActionKinit, Label: i18n.String(i18n.ActionKinit)})
+	}
+	return append(actions, notify.Action{Key: notifyActionDetails, Label: i18n.String(i18n.ActionDetails)})
+}
+
+// Notification rate limit groups.
+const (
+	notifyGroupTND   = "tnd"
+	notifyGroupLogin = "login"
+)
+
+// notifyUrgencies maps config urgency levels to notification urgencies.
+var notifyUrgencies = map[string]notify.Urgency{
+	config.UrgencyLow:      notify.UrgencyLow,
+	config.UrgencyNormal:   notify.UrgencyNormal,
+	config.UrgencyCritical: notify.UrgencyCritical,
+}
+
+// applyNotificationEvent applies the settings of event e to the
+// notification n with the event's defaults.
+func applyNotificationEvent(e config.NotificationEvent, n *notify.Notification) {
+	if u, ok := notifyUrgencies[e.Urgency]; ok {
+		n.Urgency = u
+	}
+	if e.Icon != "" {
+		n.Icon = e.Icon
+	}
+	if e.Timeout != 0 {
+		n.Timeout = time.Duration(e.Timeout) * time.Second
+	}
+	if e.Category != "" {
+		n.Category = e.Category
+	}
+}
+
+// sendNotification sends the notification n with the settings of event e
+// to the user, unless desktop notifications or notifications of the event
+// are disabled.
+func (a *Agent) sendNotification(e config.NotificationEvent, n *notify.Notification) {
+	if !a.config.Notifications || e.Disabled {
 		// desktop notifications disabled
 		return
 	}
+	applyNotificationEvent(e, n)
+	a.notifier.Send(n)
+}
+
+// notifyTND notifies the user whether we are connected to a trusted network.
+func (a *Agent) notifyTND() {
+	settings := a.config.NotificationSettings
 	if !a.trustedNetwork.Trusted() {
-		a.notifier.Notify("No Trusted Network", "No trusted network detected")
+		a.sendNotification(settings.UntrustedNetwork, &notify.Notification{
+			Title:    i18n.String(i18n.NotifyUntrustedNetworkTitle),
+			Message:  i18n.String(i18n.NotifyUntrustedNetworkBody),
+			Icon:     "security-low",
+			Urgency:  notify.UrgencyLow,
+			Category: "network.disconnected",
+			Group:    notifyGroupTND,
+		})
 		return
 	}
-	a.notifier.Notify("Trusted Network", "Trusted network detected")
+	a.sendNotification(settings.TrustedNetwork, &notify.Notification{
+		Title:    i18n.String(i18n.NotifyTrustedNetworkTitle),
+		Message:  i18n.String(i18n.NotifyTrustedNetworkBody),
+		Icon:     "security-medium",
+		Urgency:  notify.UrgencyLow,
+		Category: "network.connected",
+		Group:    notifyGroupTND,
+	})
 }
 
 // notifyLogin notifies the user whether the identity agent is logged in.
 func (a *Agent) notifyLogin() {
-	if !a.config.Notifications {
-		// desktop notifications disabled
+	settings := a.config.NotificationSettings
+	if !a.loggedIn {
+		a.sendNotification(settings.Logout, &notify.Notification{
+			Title:    i18n.String(i18n.NotifyLogoutTitle),
+			Message:  i18n.String(i18n.NotifyLogoutBody),
+			Icon:     "security-low",
+			Category: "network",
+			Actions:  a.notifyActions(false),
+			Group:    notifyGroupLogin,
+		})
 		return
 	}
-	if !a.loggedIn {
-		a.notifier.Notify("Identity Agent Logout", "Identity Agent logged out")
+	a.sendNotification(settings.Login, &notify.Notification{
+		Title:    i18n.String(i18n.NotifyLoginTitle),
+		Message:  i18n.String(i18n.NotifyLoginBody),
+		Icon:     "security-high",
+		Category: "network",
+		Group:    notifyGroupLogin,
+	})
+}
+
+// notifyTicket notifies the user that the login failed because the kerberos
+// ticket is invalid or expired.
+func (a *Agent) notifyTicket() {
+	a.sendNotification(a.config.NotificationSettings.LoginFailed, &notify.Notification{
+		Title:    i18n.String(i18n.NotifyLoginFailedTitle),
+		Message:  i18n.String(i18n.NotifyLoginFailedBody),
+		Icon:     "dialog-error",
+		Urgency:  notify.UrgencyCritical,
+		Category: "network.error",
+		Actions:  a.notifyActions(true),
+		Group:    notifyGroupLogin,
+	})
+}
+
+// details returns the details of the current state for the user.
+func (a *Agent) details() []string {
+	details := []string{
+		fmt.Sprintf("%s: %s", i18n.String(i18n.LabelTrustedNetwork),
+			i18n.TrustedNetwork(a.trustedNetwork)),
+		fmt.Sprintf("%s: %s", i18n.String(i18n.LabelLoginState),
+			i18n.LoginState(a.loginState)),
+		fmt.Sprintf("%s: %s", i18n.String(i18n.LabelAgentState),
+			i18n.AgentState(a.agentState)),
+	}
+	if a.kerberosTGT.EndTime > 0 {
+		details = append(details, fmt.Sprintf("%s: %s", i18n.String(i18n.LabelKerberosTGTExpires),
+			time.Unix(a.kerberosTGT.EndTime, 0).Format(time.DateTime)))
+	}
+	if a.state != nil && a.state.LastError != "" && !a.loggedIn {
+		details = append(details, fmt.Sprintf("%s: %s",
+			i18n.String(i18n.LabelLastError), a.state.LastError))
+	}
+	return details
+}
+
+// notifyDetails notifies the user about the details of the current state.
+func (a *Agent) notifyDetails() {
+	a.notifier.Send(&notify.Notification{
+		Title:   i18n.String(i18n.NotifyStatusTitle),
+		Message: strings.Join(a.details(), "\n"),
+		Icon:    "dialog-information",
+	})
+}
+
+// execCommand is exec.Command for testing.
+var execCommand = exec.Command
+
+// runKinit runs the configured kinit command with the shell in the
+// background, so it can contain quoted arguments.
+func (a *Agent) runKinit() {
+	if strings.TrimSpace(a.config.KinitCommand) == "" {
 		return
 	}
-	a.notifier.Notify("Identity Agent Login", "Identity Agent logged in successfully")
+	cmd := execCommand("/bin/sh", "-c", a.config.KinitCommand)
+	if err := cmd.Start(); err != nil {
+		log.WithError(err).WithField("command", a.config.KinitCommand).
+			Error("Agent could not run kinit command")
+		return
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.WithError(err).WithField("command", a.config.KinitCommand).
+				Error("Agent kinit command failed")
+		}
+	}()
+}
+
+// handleNotificationAction handles the notification action with key the user
+// invoked.
+func (a *Agent) handleNotificationAction(key string) {
+	a.history.Add(history.NewEvent(history.TypeNotification, "Notification action").
+		WithDetail("Action", key))
+
+	switch key {
+	case notifyActionReLogin:
+		log.Info("Agent got relogin request from user via notification")
+		if !a.trustedNetwork.Trusted() || a.paused {
+			// no trusted network or paused, abort
+			return
+		}
+		a.handleRelogin()
+	case notifyActionKinit:
+		log.Info("Agent got kinit request from user via notification")
+		a.runKinit()
+	case notifyActionDetails:
+		a.notifyDetails()
+	default:
+		log.WithField("action", key).Error("Agent got unknown notification action")
+	}
+}
+
+// sdNotify is sdnotify.Notify for testing.
+var sdNotify = sdnotify.Notify
+
+// notifySystemd sends the service states in states to systemd.
+func (a *Agent) notifySystemd(states ...string) {
+	if err := sdNotify(states...); err != nil {
+		log.WithError(err).Error("Agent could not notify systemd")
+	}
+}
+
+// notifySystemdStatus sends the trusted network status and login state as
+// service status to systemd.
+func (a *Agent) notifySystemdStatus() {
+	a.notifySystemd(sdnotify.Status(fmt.Sprintf("Trusted Network: %s, Login State: %s",
+		a.trustedNetwork, a.loginState)))
 }
 
 // handleKerberosTGTChange handles a change of the kerberos TGT times.
 func (a *Agent) handleKerberosTGTChange() {
 	log.WithFields(log.Fields{
-		"StartTime": a.kerberosTGT.StartTime,
-		"EndTime":   a.kerberosTGT.EndTime,
+		"tgt_start_time": a.kerberosTGT.StartTime,
+		"tgt_end_time":   a.kerberosTGT.EndTime,
 	}).Info("Kerberos TGT times changed")
+	a.history.Add(history.NewEvent(history.TypeCCache, "Kerberos TGT changed").
+		WithDetail("StartTime", time.Unix(a.kerberosTGT.StartTime, 0).Format(time.RFC3339)).
+		WithDetail("EndTime", time.Unix(a.kerberosTGT.EndTime, 0).Format(time.RFC3339)))
 	a.dbus.SetProperty(dbusapi.PropertyKerberosTGTStartTime, a.kerberosTGT.StartTime)
 	a.dbus.SetProperty(dbusapi.PropertyKerberosTGTEndTime, a.kerberosTGT.EndTime)
+	a.metrics.SetKerberosTGTEndTime(a.kerberosTGT.EndTime)
 }
 
 // handleTrustedNetworkChange handles a change of the trusted network status.
 func (a *Agent) handleTrustedNetworkChange() {
-	log.WithField("trustedNetwork", a.trustedNetwork).
+	log.WithField("trusted_network", a.trustedNetwork).
 		Info("Trusted network status changed")
+	a.history.Add(history.NewEvent(history.TypeTND, "Trusted network status changed").
+		WithDetail("TrustedNetwork", a.trustedNetwork.String()))
 	a.logTND()
 	a.notifyTND()
 	a.dbus.SetProperty(dbusapi.PropertyTrustedNetwork, a.trustedNetwork)
+	a.metrics.SetTrustedNetwork(a.trustedNetwork.Trusted())
+	a.notifySystemdStatus()
+	a.updateAgentState()
 }
 
 // handleLoginStateChange handles a change of the login state.
 func (a *Agent) handleLoginStateChange() {
-	log.WithField("loginState", a.loginState).
+	log.WithField("login_state", a.loginState).
 		Info("Login state changed")
 
 	// if we switched from "logged in" to "logged out" or from "logged out"
@@ -136,13 +401,48 @@ func (a *Agent) handleLoginStateChange() {
 
 	// set d-bus property
 	a.dbus.SetProperty(dbusapi.PropertyLoginState, a.loginState)
+	a.metrics.SetLoggedIn(a.loggedIn)
+	a.notifySystemdStatus()
+	a.updateAgentState()
+}
+
+// handleSourceIPChange handles a change of the source IP.
+func (a *Agent) handleSourceIPChange() {
+	log.WithField("source_ip", a.sourceIP).
+		Info("Source IP changed")
+	a.dbus.SetProperty(dbusapi.PropertySourceIP, a.sourceIP)
+}
+
+// handleLastLoginChange handles a change of the last login time.
+func (a *Agent) handleLastLoginChange() {
+	log.WithField("last_login", a.lastLogin).
+		Info("Last login time changed")
+	a.dbus.SetProperty(dbusapi.PropertyLastLoginAt, a.lastLogin)
 }
 
 // handleLastKeepAliveChange handles a change of the last keep-alive time.
 func (a *Agent) handleLastKeepAliveChange() {
-	log.WithField("lastKeepAlive", a.lastKeepAlive).
+	log.WithField("last_keep_alive", a.lastKeepAlive).
 		Info("Last keep-alive time changed")
 	a.dbus.SetProperty(dbusapi.PropertyLastKeepAliveAt, a.lastKeepAlive)
+	a.metrics.SetLastKeepAlive(a.lastKeepAlive)
+}
+
+// handleLoginFailuresChange handles a change of the consecutive login
+// failures.
+func (a *Agent) handleLoginFailuresChange() {
+	log.WithField("login_failures", a.loginFailures).
+		Debug("Consecutive login failures changed")
+	a.dbus.SetProperty(dbusapi.PropertyLoginFailures, a.loginFailures)
+}
+
+// handleLogLevelChange handles a change of the log level.
+func (a *Agent) handleLogLevelChange() {
+	log.WithField("log_level", a.logLevel).
+		Info("Log level changed")
+	a.history.Add(history.NewEvent(history.TypeConfig, "Log level changed").
+		WithDetail("LogLevel", a.logLevel))
+	a.dbus.SetProperty(dbusapi.PropertyLogLevel, a.logLevel)
 }
 
 // setKerberosTGT sets the kerberos TGT times.
@@ -190,6 +490,30 @@ func (a *Agent) setLoginState(loginState status.LoginState) {
 	a.handleLoginStateChange()
 }
 
+// setSourceIP sets the source IP.
+func (a *Agent) setSourceIP(sourceIP string) {
+	if sourceIP == a.sourceIP {
+		// source ip not changed
+		return
+	}
+
+	// source ip changed
+	a.sourceIP = sourceIP
+	a.handleSourceIPChange()
+}
+
+// setLastLogin sets LastLogin.
+func (a *Agent) setLastLogin(lastLogin int64) {
+	if lastLogin == a.lastLogin {
+		// timestamp not changed
+		return
+	}
+
+	// timestamp changed
+	a.lastLogin = lastLogin
+	a.handleLastLoginChange()
+}
+
 // setLastKeepAlive sets LastKeepAlive.
 func (a *Agent) setLastKeepAlive(lastKeepAlive int64) {
 	if lastKeepAlive == a.lastKeepAlive {
@@ -202,18 +526,101 @@ func (a *Agent) setLastKeepAlive(lastKeepAlive int64) {
 	a.handleLastKeepAliveChange()
 }
 
-// initTND initializes the trusted network detection from the config.
-func (a *Agent) initTND() {
-	// add https servers
+// setLoginFailures sets the consecutive login failures.
+func (a *Agent) setLoginFailures(failures uint32) {
+	if failures == a.loginFailures {
+		// failures not changed
+		return
+	}
+
+	// failures changed
+	a.loginFailures = failures
+	a.handleLoginFailuresChange()
+}
+
+// setLogLevel sets the log level.
+func (a *Agent) setLogLevel(level log.Level) {
+	log.SetLevel(level)
+	if level.String() == a.logLevel {
+		// log level not changed
+		return
+	}
+
+	// log level changed
+	a.logLevel = level.String()
+	a.handleLogLevelChange()
+}
+
+// resetLogLevel resets the log level to the configured log level and stops
+// the revert timer.
+func (a *Agent) resetLogLevel() {
+	a.logLevelRevert = nil
+	level, err := log.ParseLevel(a.config.GetLogLevel())
+	if err != nil {
+		level = log.InfoLevel
+	}
+	a.setLogLevel(level)
+}
+
+// statefilePath is statefile.Path for testing.
+var statefilePath = statefile.Path
+
+// restoreState restores the agent state persisted in the state file.
+func (a *Agent) restoreState() {
+	// get state file
+	path, err := statefilePath()
+	if err != nil {
+		log.WithError(err).Error("Agent could not get state file, state will not be persisted")
+		return
+	}
+
+	// load state
+	s, err := statefile.Load(path)
+	if err != nil {
+		log.WithError(err).Error("Agent could not load state file, resetting state")
+		s = &statefile.State{}
+	}
+	log.WithField("state", s).Debug("Agent restoring state")
+	a.stateFile = path
+	a.state = s
+
+	// restore times and check if the last session is still valid
+	a.setLastLogin(s.LastLogin)
+	a.setLastKeepAlive(s.LastKeepAlive)
+	a.restored = s.SessionValid(time.Now())
+}
+
+// saveState saves the current agent state in the state file.
+func (a *Agent) saveState() {
+	if a.state == nil {
+		// state not restored, do not overwrite state file
+		return
+	}
+
+	a.state.LoggedIn = a.loggedIn
+	a.state.LastLogin = a.lastLogin
+	a.state.LastKeepAlive = a.lastKeepAlive
+	if err := a.state.Save(a.stateFile); err != nil {
+		log.WithError(err).Error("Agent could not save state file")
+	}
+}
+
+// tndServers returns the https servers and their hashes in config.
+func tndServers(config *config.Config) map[string]string {
 	servers := map[string]string{}
-	for _, s := range a.config.TND.HTTPSServers {
+	for _, s := range config.TND.HTTPSServers {
 		log.WithFields(log.Fields{
 			"url":  s.URL,
 			"hash": s.Hash,
 		}).Debug("Agent adding HTTPS server url and hash to TND")
 		servers[s.URL] = s.Hash
 	}
-	a.tnd.SetServers(servers)
+	return servers
+}
+
+// initTND initializes the trusted network detection from the config.
+func (a *Agent) initTND() {
+	a.tnd.SetServers(tndServers(a.config))
 }
 
 // startClient starts the client.
@@ -223,6 +630,17 @@ func (a *Agent) startClient() {
 		return
 	}
 
+	// make sure agent is not paused
+	if a.paused || a.userPaused {
+		return
+	}
+
+	// make sure agent is not sleeping, the wake-up timer probes the
+	// trusted network and starts the client after wake-up
+	if a.sleeping {
+		return
+	}
+
 	// make sure ccache is available
 	if a.ccacheUp == nil || a.ccacheUp.CCache == nil {
 		return
@@ -235,6 +653,12 @@ func (a *Agent) startClient() {
 
 	// start new client
 	a.client = client.NewClient(a.config, a.ccacheUp.CCache, a.krbcfgUp.Config)
+	if a.restored {
+		// only restore session in first client
+		a.client.Restore(time.Unix(a.state.LastKeepAlive, 0), a.state.GetKeepAlive(),
+			a.state.SourceIP)
+		a.restored = false
+	}
 	a.client.Start()
 	a.login = a.client.Results()
 }
@@ -252,6 +676,9 @@ func (a *Agent) stopClient() {
 	a.client = nil
 	a.login = nil
 	a.setLoginState(status.LoginStateLoggedOut)
+	a.setSourceIP(dbusapi.SourceIPInvalid)
+	a.history.Add(history.NewEvent(history.TypeLogout, "Client stopped and logged out"))
+	a.saveState()
 }
 
 // handleTNDResult handles a TND result.
@@ -270,16 +697,105 @@ func (a *Agent) handleTNDResult(r bool) {
 	}
 }
 
+// observeLogin records the duration and result of the current login request
+// in the metrics. Class is the error class of a failed login or empty.
+func (a *Agent) observeLogin(class string) {
+	if a.loginStart.IsZero() {
+		// no login request
+		return
+	}
+	a.metrics.ObserveLogin(time.Since(a.loginStart), class)
+	a.loginStart = time.Time{}
+}
+
 // handleLoginResult handles a login result.
-func (a *Agent) handleLoginResult(r status.LoginState) {
+func (a *Agent) handleLoginResult(r *client.Result) {
+	// reject login if the current trusted network status, kerberos ticket
+	// or paused and sleeping flags do not allow it, so the login state
+	// never contradicts the agent state, and stop the client that should
+	// not be running
+	if r.LoginState == status.LoginStateLoggedIn && !a.loginAllowed() {
+		log.WithField("agent_state", a.agentState).
+			Error("Agent got login while login is not allowed, stopping client")
+		a.history.Add(history.NewEvent(history.TypeLogin, "Login rejected").
+			WithDetail("AgentState", a.agentState.String()))
+		a.stopClient()
+		return
+	}
+
 	// update login state
-	a.setLoginState(r)
+	loggedIn := a.loggedIn
+	a.setLoginState(r.LoginState)
+
+	switch r.LoginState {
+	case status.LoginStateLoggingIn:
+		a.loginStart = time.Now()
+		return
 
-	// update last keep-alive
-	if r.LoggedIn() {
+	case status.LoginStateLoggingOut:
+		a.loginStart = time.Time{}
+		return
+
+	case status.LoginStateLoggedIn:
+		a.setSourceIP(r.SourceIP)
+		a.setLoginFailures(0)
+		if r.Restored {
+			// restored session, keep restored times
+			a.history.Add(history.NewEvent(history.TypeLogin, "Restored session from state file"))
+			break
+		}
+
+		// update metrics, last login and keep-alive
+		a.observeLogin("")
 		now := time.Now().Unix()
+		if !loggedIn {
+			a.setLastLogin(now)
+			e := history.NewEvent(history.TypeLogin, "Login successful").
+				WithDetail("KeepAlive", r.KeepAlive.String()).
+				WithDetail("SourceIP", r.SourceIP)
+			if r.SessionID != "" {
+				e.WithDetail("SessionID", r.SessionID)
+			}
+			a.history.Add(e)
+		} else {
+			a.history.Add(history.NewEvent(history.TypeKeepAlive, "Keep-alive successful").
+				WithDetail("KeepAlive", r.KeepAlive.String()))
+		}
 		a.setLastKeepAlive(now)
+
+		// update session in persisted state, clear last error
+		if a.state != nil {
+			a.state.SourceIP = r.SourceIP
+			a.state.KeepAlive = int(r.KeepAlive / time.Minute)
+			a.state.LastError = ""
+		}
+
+	case status.LoginStateLoggedOut:
+		a.setSourceIP(dbusapi.SourceIPInvalid)
+		if r.Err != nil {
+			a.observeLogin(client.ErrorClass(r.Err))
+			a.setLoginFailures(a.loginFailures + 1)
+			if a.loginFailures == 1 && client.ErrorClass(r.Err) == client.ClassToken {
+				// only notify about the first failure, not every retry
+				a.notifyTicket()
+			}
+			a.history.Add(history.NewEvent(history.TypeLogin, "Login failed").
+				WithError(r.Err))
+
+			// update last error in persisted state
+			if a.state != nil {
+				a.state.LastError = r.Err.Error()
+			}
+		}
+
+		// logout or failed login, no pending login request
+		a.loginStart = time.Time{}
+
+	default:
+		// do not persist intermediate states
+		return
 	}
+	a.saveState()
 }
 
 // handleCCacheUpdate handles a CCache update.
@@ -306,6 +822,7 @@ func (a *Agent) handleCCacheUpdate(u *krbmon.CCacheUpdate) {
 
 	// save update
 	a.ccacheUp = u
+	a.updateAgentState()
 
 	// set ccache in existing client or check if we
 	// can start new client now
@@ -325,6 +842,7 @@ func (a *Agent) handleKrbConfUpdate(u *krbmon.ConfUpdate) {
 
 	// save update
 	a.krbcfgUp = u
+	a.updateAgentState()
 
 	// set config in existing client or check if we can
 	// start a new client now
@@ -338,6 +856,21 @@ func (a *Agent) handleKrbConfUpdate(u *krbmon.ConfUpdate) {
 
 // handleDBusRequest handles a D-Bus API request.
 func (a *Agent) handleDBusRequest(request *dbusapi.Request) {
+	// only record requests that change the agent's state, recording
+	// read-only queries would change the history they return
+	switch request.Name {
+	case dbusapi.RequestGetHistory, dbusapi.RequestCollectDiagnostics:
+	default:
+		a.history.Add(history.NewEvent(history.TypeDBus, "D-Bus request").
+			WithDetail("Request", request.Name).
+			WithDetail("Sender", request.Sender))
+	}
+
+	if request.Name == dbusapi.RequestCollectDiagnostics {
+		// request is completed in the background
+		a.handleCollectDiagnostics(request)
+		return
+	}
 	defer request.Close()
 
 	switch request.Name {
@@ -352,22 +885,460 @@ func (a *Agent) handleDBusRequest(request *dbusapi.Request) {
 
 		// trusted network, restart client
 		log.Info("Agent is restarting client")
+		a.userResume("user re-login")
 		a.stopClient()
 		a.startClient()
+
+	case dbusapi.RequestLogout:
+		log.Info("Agent got logout request from user via D-Bus")
+		a.userPause("user logout")
+
+	case dbusapi.RequestPause:
+		log.Info("Agent got pause request from user via D-Bus")
+		a.userPause("user request")
+
+	case dbusapi.RequestResume:
+		log.Info("Agent got resume request from user via D-Bus")
+		a.userResume("user request")
+		if a.trustedNetwork.Trusted() {
+			a.startClient()
+		}
+
+	case dbusapi.RequestGetHistory:
+		// get events since timestamp in parameters
+		since := time.Time{}
+		if len(request.Parameters) > 0 {
+			if s, ok := request.Parameters[0].(int64); ok && s > 0 {
+				since = time.Unix(s, 0)
+			}
+		}
+		b, err := history.JSON(a.history.Events(since))
+		if err != nil {
+			log.WithError(err).Error("Agent could not convert history to JSON")
+			request.Error = err
+			return
+		}
+		request.Results = []any{string(b)}
+
+	case dbusapi.RequestSetLogLevel:
+		// get level and duration in seconds from parameters
+		level, duration := "", int64(0)
+		if len(request.Parameters) == 2 {
+			level, _ = request.Parameters[0].(string)
+			duration, _ = request.Parameters[1].(int64)
+		}
+		request.Error = a.handleSetLogLevel(level, time.Duration(duration)*time.Second)
+	}
+}
+
+// getStatus returns the current agent status.
+func (a *Agent) getStatus() *status.Status {
+	return &status.Status{
+		Config:          a.config.Copy(),
+		ConfigSignature: a.config.Signature(),
+		AgentState:      a.agentState,
+		TrustedNetwork:  a.trustedNetwork,
+		LoginState:      a.loginState,
+		SourceIP:        a.sourceIP,
+		LastLogin:       a.lastLogin,
+		LastKeepAlive:   a.lastKeepAlive,
+		KerberosTGT:     a.kerberosTGT,
+		LogLevel:        a.logLevel,
+		LoginFailures:   a.loginFailures,
+	}
+}
+
+// createBundle is diagnostics.Diagnostics.Bundle for testing.
+var createBundle = (*diagnostics.Diagnostics).Bundle
+
+// handleCollectDiagnostics handles a collect diagnostics request. It takes a
+// snapshot of the agent state and creates the support bundle in the
+// background, because it probes the TND servers, and completes request.
+func (a *Agent) handleCollectDiagnostics(request *dbusapi.Request) {
+	log.Info("Agent collecting diagnostics via D-Bus")
+	d := &diagnostics.Diagnostics{
+		Version: Version,
+		Config:  a.config.Copy(),
+		Status:  a.getStatus(),
+		History: a.history.Events(time.Time{}),
+		Logs:    logging.Recent(),
 	}
+	go func() {
+		defer request.Close()
+		b, err := createBundle(d)
+		if err != nil {
+			log.WithError(err).Error("Agent could not create support bundle")
+			request.Error = err
+			return
+		}
+		request.Results = []any{b}
+	}()
+}
+
+// handleSetLogLevel handles a set log level request. It sets the log level
+// to level for duration or, if duration is 0, until it is reset. An empty
+// level resets the log level to the configured log level.
+func (a *Agent) handleSetLogLevel(level string, duration time.Duration) error {
+	if level == "" {
+		log.Info("Agent resetting log level via D-Bus")
+		a.resetLogLevel()
+		return nil
+	}
+
+	l, err := log.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %s", level)
+	}
+	log.WithFields(log.Fields{
+		"log_level": level,
+		"duration":  duration,
+	}).Info("Agent setting log level via D-Bus")
+	a.setLogLevel(l)
+	a.logLevelRevert = nil
+	if duration > 0 {
+		a.logLevelRevert = time.After(duration)
+	}
+	return nil
+}
+
+// handleLogLevelRevertTimer handles the log level revert timer event.
+func (a *Agent) handleLogLevelRevertTimer() {
+	log.Info("Agent reverting temporary log level")
+	a.resetLogLevel()
 }
 
 // handleSleepEvent handles a sleep event.
 func (a *Agent) handleSleepEvent(sleep bool) {
-	// ignore wake-up event
+	// handle wake-up event
 	if !sleep {
+		a.history.Add(history.NewEvent(history.TypeSleep, "Woke up from sleep"))
+		a.sleeping = false
+		a.updateAgentState()
+
+		// wait for the network to settle, then probe trusted network
+		log.WithField("seconds", a.config.WakeDelay).
+			Info("Agent got wake-up event, probing trusted network after delay")
+		a.wake = time.After(a.config.GetWakeDelay())
 		return
 	}
+	a.history.Add(history.NewEvent(history.TypeSleep, "Going to sleep"))
+	a.sleeping = true
+	a.wake = nil
+	a.updateAgentState()
 
 	// reset trusted network status and stop client
 	log.Info("Agent got sleep event, resetting trusted network status and stopping client")
 	a.setTrustedNetwork(false)
 	a.stopClient()
+
+	// logout finished or timed out, let the system suspend
+	a.sleep.Release()
+}
+
+// handleWakeTimer handles the wake-up timer event.
+func (a *Agent) handleWakeTimer() {
+	a.wake = nil
+
+	// trigger trusted network probe, do not block the main loop because
+	// TND might be waiting for us to receive a result
+	log.Info("Agent probing trusted network after wake-up")
+	go a.tnd.Probe()
+}
+
+// handleNetworkUpdate handles a network monitor update.
+func (a *Agent) handleNetworkUpdate(u *netmon.Update) {
+	log.WithField("addresses", u.Addresses).
+		Debug("Agent got network update")
+
+	// ignore network changes while going to sleep, the wake-up timer
+	// handles them after wake-up
+	if a.sleeping {
+		return
+	}
+	a.history.Add(history.NewEvent(history.TypeNetwork, "Network changed").
+		WithDetail("Addresses", strings.Join(u.Addresses, " ")))
+
+	// trigger trusted network probe, do not block the main loop because
+	// TND might be waiting for us to receive a result
+	log.Info("Agent got network change, probing trusted network")
+	go a.tnd.Probe()
+
+	// check the source IP used to reach the identity service now instead
+	// of waiting for the next periodic check, the client logs out and in
+	// again if the address the firewall sees changed, changes of other
+	// local addresses do not affect the session
+	if a.client != nil {
+		a.client.CheckSourceIP()
+	}
+}
+
+// setConfigProperties sets the config D-Bus properties.
+func (a *Agent) setConfigProperties() error {
+	b, err := a.config.JSON()
+	if err != nil {
+		return fmt.Errorf("could not convert config to json: %w", err)
+	}
+	a.dbus.SetProperty(dbusapi.PropertyConfig, string(b))
+	a.dbus.SetProperty(dbusapi.PropertyConfigSignature, a.config.Signature())
+	return nil
+}
+
+// startMetrics starts the metrics server and textfile writer if configured.
+func (a *Agent) startMetrics() error {
+	if a.config.MetricsAddress != "" {
+		a.metricsServer = metrics.NewServer(a.metrics, a.config.MetricsAddress)
+		if err := a.metricsServer.Start(); err != nil {
+			a.metricsServer = nil
+			return err
+		}
+	}
+	if a.config.MetricsTextfileDir != "" {
+		a.metricsFile = metrics.NewTextfile(a.metrics, a.config.MetricsTextfileDir)
+		a.metricsFile.Start()
+	}
+	return nil
+}
+
+// stopMetrics stops the metrics server and textfile writer if running.
+func (a *Agent) stopMetrics() {
+	if a.metricsServer != nil {
+		a.metricsServer.Stop()
+		a.metricsServer = nil
+	}
+	if a.metricsFile != nil {
+		a.metricsFile.Stop()
+		a.metricsFile = nil
+	}
+}
+
+// handleReload handles a config reload request with the new config cfg.
+// The new trusted network detection, metrics and session monitor are
+// started before they replace the running ones, so the agent keeps running
+// with the old config if one of them fails to start.
+func (a *Agent) handleReload(cfg *config.Config) error {
+	log.WithField("config", cfg).Info("Agent reloading config")
+
+	// start trusted network detection with new servers, client is
+	// started again on next trusted network result
+	d := newTND(cfg.TND.Config)
+	d.SetServers(tndServers(cfg))
+	if err := d.Start(); err != nil {
+		return fmt.Errorf("could not start TND: %w", err)
+	}
+
+	// start metrics server if metrics address changed
+	addressChanged := cfg.MetricsAddress != a.config.MetricsAddress
+	textfileChanged := cfg.MetricsTextfileDir != a.config.MetricsTextfileDir
+	var server *metrics.Server
+	if addressChanged && cfg.MetricsAddress != "" {
+		server = metrics.NewServer(a.metrics, cfg.MetricsAddress)
+		if err := server.Start(); err != nil {
+			d.Stop()
+			return fmt.Errorf("could not start metrics: %w", err)
+		}
+	}
+
+	// start session monitor if session policy requires it
+	var session *SessionMon
+	if monitorSession(cfg) && a.session == nil {
+		session = NewSessionMon()
+		if err := session.Start(); err != nil {
+			if server != nil {
+				server.Stop()
+			}
+			d.Stop()
+			return fmt.Errorf("could not start session monitor: %w", err)
+		}
+	}
+
+	// everything started, switch to new config
+	a.history.Add(history.NewEvent(history.TypeConfig, "Config reloaded").
+		WithDetail("Signature", cfg.Signature().String()))
+
+	// reset trusted network status, stop client and TND, they use the
+	// old config
+	a.setTrustedNetwork(false)
+	a.stopClient()
+	a.tnd.Stop()
+	a.tnd = d
+	a.config = cfg
+	a.notifier.SetRateLimit(cfg.NotificationSettings.GetRateLimit())
+
+	// replace metrics server and textfile writer if their settings changed
+	if addressChanged {
+		if a.metricsServer != nil {
+			a.metricsServer.Stop()
+		}
+		a.metricsServer = server
+	}
+	if textfileChanged {
+		if a.metricsFile != nil {
+			a.metricsFile.Stop()
+			a.metricsFile = nil
+		}
+		if cfg.MetricsTextfileDir != "" {
+			a.metricsFile = metrics.NewTextfile(a.metrics, cfg.MetricsTextfileDir)
+			a.metricsFile.Start()
+		}
+	}
+
+	// replace or stop session monitor if session policy changed
+	switch {
+	case session != nil:
+		a.session = session
+		a.sessionEvents = session.Events()
+	case !monitorSession(cfg) && a.session != nil:
+		a.session.Stop()
+		a.session = nil
+		a.sessionEvents = nil
+		a.lock = nil
+		a.resume("session policy changed")
+	}
+
+	// set config D-Bus properties and reset temporary log level
+	if err := a.setConfigProperties(); err != nil {
+		log.WithError(err).Error("Agent could not set config properties")
+	}
+	a.resetLogLevel()
+	return nil
+}
+
+// handleRelogin handles a re-login request.
+func (a *Agent) handleRelogin() {
+	log.Info("Agent logging in again")
+	a.history.Add(history.NewEvent(history.TypeLogin, "Re-login requested"))
+	a.userResume("user re-login")
+	a.stopClient()
+	if a.trustedNetwork.Trusted() {
+		a.startClient()
+	}
+}
+
+// handleDump handles a state dump request, it logs the current state and
+// the event history.
+func (a *Agent) handleDump() {
+	log.WithFields(log.Fields{
+		"agent_state":     a.agentState,
+		"trusted_network": a.trustedNetwork,
+		"login_state":     a.loginState,
+		"source_ip":       a.sourceIP,
+		"last_login":      a.lastLogin,
+		"last_keep_alive": a.lastKeepAlive,
+		"kerberos_tgt":    a.kerberosTGT,
+		"paused":          a.paused,
+		"user_paused":     a.userPaused,
+		"sleeping":        a.sleeping,
+		"config":          a.config,
+	}).Info("Agent state")
+	for _, e := range a.history.Events(time.Time{}) {
+		log.WithFields(log.Fields{
+			"event_time":    e.Time,
+			"event_type":    e.Type,
+			"event_error":   e.Error,
+			"event_details": e.Details,
+		}).Info("Agent history: " + e.Message)
+	}
+}
+
+// handleWatchdogTimer handles the watchdog timer event.
+func (a *Agent) handleWatchdogTimer() {
+	// main loop is alive, keep systemd from restarting us
+	a.notifySystemd(sdnotify.Watchdog)
+}
+
+// pause pauses the agent and logs out the client.
+func (a *Agent) pause(reason string) {
+	if a.paused {
+		return
+	}
+	log.WithField("reason", reason).Info("Agent pausing and stopping client")
+	a.history.Add(history.NewEvent(history.TypeSession, "Agent paused").
+		WithDetail("Reason", reason))
+	a.paused = true
+	a.updateAgentState()
+	a.stopClient()
+}
+
+// resume resumes the paused agent and logs in the client immediately if
+// connected to a trusted network.
+func (a *Agent) resume(reason string) {
+	if !a.paused {
+		return
+	}
+	log.WithField("reason", reason).Info("Agent resuming")
+	a.history.Add(history.NewEvent(history.TypeSession, "Agent resumed").
+		WithDetail("Reason", reason))
+	a.paused = false
+	a.updateAgentState()
+	if a.trustedNetwork.Trusted() {
+		a.startClient()
+	}
+}
+
+// userPause pauses the agent by user request and logs out the client. Unlike
+// pause, the agent stays paused until the user resumes or logs in again,
+// session events and trusted network changes do not resume it.
+func (a *Agent) userPause(reason string) {
+	if a.userPaused {
+		return
+	}
+	log.WithField("reason", reason).Info("Agent pausing by user request and stopping client")
+	a.history.Add(history.NewEvent(history.TypeSession, "Agent paused by user").
+		WithDetail("Reason", reason))
+	a.userPaused = true
+	a.updateAgentState()
+	a.stopClient()
+}
+
+// userResume resumes the agent paused by the user, the caller starts the
+// client.
+func (a *Agent) userResume(reason string) {
+	if !a.userPaused {
+		return
+	}
+	log.WithField("reason", reason).Info("Agent resuming by user request")
+	a.history.Add(history.NewEvent(history.TypeSession, "Agent resumed by user").
+		WithDetail("Reason", reason))
+	a.userPaused = false
+	a.updateAgentState()
+}
+
+// handleSessionEvent handles a session event.
+func (a *Agent) handleSessionEvent(e *SessionEvent) {
+	log.WithFields(log.Fields{
+		"locked": e.Locked,
+		"active": e.Active,
+	}).Debug("Agent got session event")
+
+	switch a.config.SessionPolicy {
+	case config.SessionPolicyLogoutOnLock:
+		if !e.Locked {
+			// session unlocked, stop lock timer and resume
+			a.lock = nil
+			a.resume("session unlocked")
+			return
+		}
+
+		// session locked, start lock timer
+		if !a.paused && a.lock == nil {
+			log.WithField("seconds", a.config.LockGracePeriod).
+				Info("Agent got session lock, logging out after grace period")
+			a.lock = time.After(a.config.GetLockGracePeriod())
+		}
+
+	case config.SessionPolicyLogoutOnInactive:
+		if e.Active {
+			a.resume("session active")
+			return
+		}
+		a.pause("session inactive")
+	}
+}
+
+// handleLockTimer handles the lock timer event.
+func (a *Agent) handleLockTimer() {
+	a.lock = nil
+	a.pause("session locked")
 }
 
 // start starts the agent's main loop.
@@ -376,8 +1347,25 @@ func (a *Agent) start() {
 	defer a.dbus.Stop()
 	defer a.ccache.Stop()
 	defer a.krbcfg.Stop()
-	defer a.tnd.Stop()
+	defer func() {
+		// tnd might be replaced on config reload
+		a.tnd.Stop()
+	}()
 	defer a.sleep.Stop()
+	defer a.netmon.Stop()
+	defer func() {
+		if a.session != nil {
+			a.session.Stop()
+		}
+	}()
+	defer a.stopMetrics()
+
+	// watchdog timer, nil channel if the watchdog is not enabled
+	var watchdog <-chan time.Time
+	if a.watchdog != nil {
+		defer a.watchdog.Stop()
+		watchdog = a.watchdog.C
+	}
 
 	// start main loop
 	for {
@@ -424,8 +1412,49 @@ func (a *Agent) start() {
 			}
 			a.handleSleepEvent(s)
 
+		case u, ok := <-a.netmon.Updates():
+			if !ok {
+				a.errors <- errors.New("Agent NetMon updates channel closed")
+				return
+			}
+			a.handleNetworkUpdate(u)
+
+		case <-a.wake:
+			a.handleWakeTimer()
+
+		case e, ok := <-a.sessionEvents:
+			if !ok {
+				a.errors <- errors.New("Agent SessionMon events channel closed")
+				return
+			}
+			a.handleSessionEvent(e)
+
+		case <-a.lock:
+			a.handleLockTimer()
+
+		case <-watchdog:
+			a.handleWatchdogTimer()
+
+		case <-a.logLevelRevert:
+			a.handleLogLevelRevertTimer()
+
+		case cfg := <-a.reload:
+			if err := a.handleReload(cfg); err != nil {
+				log.WithError(err).Error("Agent could not reload config, keeping old config")
+			}
+
+		case <-a.relogin:
+			a.handleRelogin()
+
+		case key := <-a.notifier.Actions():
+			a.handleNotificationAction(key)
+
+		case <-a.dump:
+			a.handleDump()
+
 		case <-a.done:
 			log.Info("Agent stopping")
+			a.notifySystemd(sdnotify.Stopping)
 			a.stopClient()
 			return
 		}
@@ -460,19 +1489,51 @@ func (a *Agent) Start() error {
 		return fmt.Errorf("could not start sleep monitor: %w", err)
 	}
 
+	// start network monitor
+	if err := a.netmon.Start(); err != nil {
+		return fmt.Errorf("could not start network monitor: %w", err)
+	}
+
+	// start session monitor
+	if a.session != nil {
+		if err := a.session.Start(); err != nil {
+			return fmt.Errorf("could not start session monitor: %w", err)
+		}
+		a.sessionEvents = a.session.Events()
+	}
+
+	// start metrics server and textfile writer
+	if err := a.startMetrics(); err != nil {
+		return fmt.Errorf("could not start metrics: %w", err)
+	}
+
 	// set trusted network status to "not trusted" and
 	// login state to "logged out"
 	a.setTrustedNetwork(false)
 	a.setLoginState(status.LoginStateLoggedOut)
 
-	// set config D-Bus property
-	b, err := a.config.JSON()
-	if err != nil {
-		return fmt.Errorf("could not convert config to json: %w", err)
+	// restore persisted state
+	a.restoreState()
+
+	// set config D-Bus properties
+	if err := a.setConfigProperties(); err != nil {
+		return err
+	}
+
+	// set agent state D-Bus property and log level
+	a.dbus.SetProperty(dbusapi.PropertyAgentState, a.agentState)
+	a.resetLogLevel()
+
+	// start systemd watchdog, send notifications twice per interval
+	if interval := sdnotify.WatchdogInterval(); interval > 0 {
+		log.WithField("interval", interval).Debug("Agent starting systemd watchdog")
+		a.watchdog = time.NewTicker(interval / 2)
 	}
-	a.dbus.SetProperty(dbusapi.PropertyConfig, string(b))
 
 	go a.start()
+
+	// notify systemd that we are ready
+	a.notifySystemd(sdnotify.Ready)
 	return nil
 }
 
@@ -483,32 +1544,86 @@ func (a *Agent) Stop() {
 	<-a.closed
 }
 
+// Reload reloads the agent with the new config cfg.
+func (a *Agent) Reload(cfg *config.Config) {
+	select {
+	case a.reload <- cfg:
+	case <-a.closed:
+	}
+}
+
+// Relogin logs the agent out and in again.
+func (a *Agent) Relogin() {
+	select {
+	case a.relogin <- struct{}{}:
+	case <-a.closed:
+	}
+}
+
+// Dump logs the current agent state and event history.
+func (a *Agent) Dump() {
+	select {
+	case a.dump <- struct{}{}:
+	case <-a.closed:
+	}
+}
+
 // Errors returns the errors channel of the agent.
 func (a *Agent) Errors() chan error {
 	return a.errors
 }
 
+// newTND returns a new trusted network detection with config, for testing.
+var newTND = func(config *tnd.Config) tnd.TND {
+	return tnd.NewDetector(config)
+}
+
+// monitorSession returns whether the session policy in config requires
+// monitoring the session.
+func monitorSession(c *config.Config) bool {
+	switch c.SessionPolicy {
+	case config.SessionPolicyLogoutOnLock, config.SessionPolicyLogoutOnInactive:
+		return true
+	}
+	return false
+}
+
 // NewAgent returns a new agent.
-func NewAgent(config *config.Config) *Agent {
+func NewAgent(cfg *config.Config) *Agent {
 	dbus := dbusapi.NewService()
 	ccache := krbmon.NewCCacheMon()
 	krbcfg := krbmon.NewConfMon()
-	tnd := tnd.NewDetector(config.TND.Config)
+	tnd := newTND(cfg.TND.Config)
 	sleep := NewSleepMon()
+	netmon := netmon.NewNetMon()
+	var session *SessionMon
+	if monitorSession(cfg) {
+		session = NewSessionMon()
+	}
 	notifier, err := notify.NewNotifier()
 	if err != nil {
 		log.WithError(err).Error("Agent could not create notifier, no desktop notifications will be available")
 	}
+	notifier.SetRateLimit(cfg.NotificationSettings.GetRateLimit())
 	return &Agent{
-		config:   config,
+		config:   cfg,
 		dbus:     dbus,
 		ccache:   ccache,
 		krbcfg:   krbcfg,
 		tnd:      tnd,
 		sleep:    sleep,
+		netmon:   netmon,
+		session:  session,
 		errors:   make(chan error, 1),
+		reload:   make(chan *config.Config),
+		relogin:  make(chan struct{}),
+		dump:     make(chan struct{}),
 		done:     make(chan struct{}),
 		closed:   make(chan struct{}),
 		notifier: notifier,
+		history:  history.New(historySize),
+		metrics:  metrics.NewMetrics(client.ErrorClasses()),
+
+		agentState: status.AgentStateWaitingForTicket,
 	}
 }
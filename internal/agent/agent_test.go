@@ -1,18 +1,36 @@
 package agent
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/godbus/dbus/v5"
 	krbconfig "github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/credentials"
 	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	log "github.com/sirupsen/logrus"
 	"github.com/telekom-mms/fw-id-agent/internal/client"
 	"github.com/telekom-mms/fw-id-agent/internal/dbusapi"
+	"github.com/telekom-mms/fw-id-agent/internal/diagnostics"
+	"github.com/telekom-mms/fw-id-agent/internal/i18n"
 	"github.com/telekom-mms/fw-id-agent/internal/krbmon"
+	"github.com/telekom-mms/fw-id-agent/internal/netmon"
+	"github.com/telekom-mms/fw-id-agent/internal/notify"
+	"github.com/telekom-mms/fw-id-agent/internal/sdnotify"
+	"github.com/telekom-mms/fw-id-agent/internal/statefile"
 	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/fw-id-agent/pkg/history"
 	"github.com/telekom-mms/fw-id-agent/pkg/status"
+	"github.com/telekom-mms/tnd/pkg/tnd"
 	"github.com/telekom-mms/tnd/pkg/tnd/tndtest"
 )
 
@@ -24,6 +42,15 @@ func (n *nopDBusService) Stop()                           {}
 func (n *nopDBusService) Requests() chan *dbusapi.Request { return nil }
 func (n *nopDBusService) SetProperty(string, any)         {}
 
+// setTrustedLoggingIn sets kerberos ticket and config and the trusted network
+// in agent a, so it accepts login results like a running client's agent.
+func setTrustedLoggingIn(a *Agent) {
+	a.ccacheUp = &krbmon.CCacheUpdate{CCache: &credentials.CCache{}}
+	a.krbcfgUp = &krbmon.ConfUpdate{Config: krbconfig.New()}
+	a.trustedNetwork = status.TrustedNetworkTrusted
+	a.agentState = status.AgentStateTrustedLoggingIn
+}
+
 // TestAgentSetKerberosTGT tests setKerberosTGT of Agent.
 func TestAgentSetKerberosTGT(t *testing.T) {
 	// create agent
@@ -144,6 +171,79 @@ func TestAgentSetLastKeepAlive(t *testing.T) {
 	}
 }
 
+// TestAgentSetSourceIP tests setSourceIP of Agent.
+func TestAgentSetSourceIP(t *testing.T) {
+	// create agent
+	c := config.Default()
+	a := NewAgent(c)
+	a.dbus = &nopDBusService{}
+
+	// test values
+	for i, want := range []string{
+		// set source ip, set new value
+		"192.168.1.10",
+		// set source ip again, no change
+		"192.168.1.10",
+		// reset source ip
+		"",
+	} {
+		a.setSourceIP(want)
+
+		// check values
+		got := a.sourceIP
+		if got != want {
+			t.Errorf("test %d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+// TestAgentNotifySystemd tests notifySystemd and notifySystemdStatus of
+// Agent.
+func TestAgentNotifySystemd(t *testing.T) {
+	oldSdNotify := sdNotify
+	defer func() { sdNotify = oldSdNotify }()
+
+	var got []string
+	sdNotify = func(states ...string) error {
+		got = states
+		return nil
+	}
+
+	// create agent
+	c := config.Default()
+	a := NewAgent(c)
+	a.dbus = &nopDBusService{}
+
+	// test status
+	a.trustedNetwork = status.TrustedNetworkTrusted
+	a.loginState = status.LoginStateLoggedIn
+	a.notifySystemdStatus()
+	want := []string{"STATUS=Trusted Network: trusted, Login State: logged in"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test status on login state change
+	a.setLoginState(status.LoginStateLoggedOut)
+	want = []string{"STATUS=Trusted Network: trusted, Login State: logged out"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test watchdog
+	a.handleWatchdogTimer()
+	want = []string{sdnotify.Watchdog}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test error, should not panic
+	sdNotify = func(...string) error {
+		return errors.New("test error")
+	}
+	a.notifySystemd(sdnotify.Ready)
+}
+
 // TestInitTND tests initTND of Agent.
 func TestInitTND(t *testing.T) {
 	// create agent
@@ -247,17 +347,291 @@ func TestAgentHandleLoginResult(t *testing.T) {
 	c := config.Default()
 	a := NewAgent(c)
 	a.dbus = &nopDBusService{}
+	setTrustedLoggingIn(a)
 
 	// test logged in
-	a.handleLoginResult(status.LoginStateLoggedIn)
-	if !a.loginState.LoggedIn() {
-		t.Error("client should be logged in")
+	a.handleLoginResult(&client.Result{
+		LoginState: status.LoginStateLoggedIn,
+		SourceIP:   "192.168.1.10",
+	})
+	if !a.loginState.LoggedIn() || a.sourceIP != "192.168.1.10" {
+		t.Error("client should be logged in with source ip")
 	}
 
 	// test logged out
-	a.handleLoginResult(status.LoginStateLoggedOut)
-	if a.loginState != status.LoginStateLoggedOut {
-		t.Error("client should be logged out")
+	a.handleLoginResult(&client.Result{LoginState: status.LoginStateLoggedOut})
+	if a.loginState != status.LoginStateLoggedOut || a.sourceIP != "" {
+		t.Error("client should be logged out without source ip")
+	}
+	if a.loginFailures != 0 {
+		t.Errorf("got %d login failures, want 0", a.loginFailures)
+	}
+
+	// loginFailedEvents returns the number of login failed events
+	loginFailedEvents := func() int {
+		n := 0
+		for _, e := range a.history.Events(time.Time{}) {
+			if e.Message == "Login failed" {
+				n++
+			}
+		}
+		return n
+	}
+	if n := loginFailedEvents(); n != 0 {
+		t.Errorf("got %d login failed events, want 0", n)
+	}
+
+	// test failed logins
+	for range 2 {
+		a.handleLoginResult(&client.Result{
+			LoginState: status.LoginStateLoggedOut,
+			Err:        errors.New("test error"),
+		})
+	}
+	if a.loginFailures != 2 {
+		t.Errorf("got %d login failures, want 2", a.loginFailures)
+	}
+	if n := loginFailedEvents(); n != 2 {
+		t.Errorf("got %d login failed events, want 2", n)
+	}
+
+	// test login resets login failures
+	a.handleLoginResult(&client.Result{LoginState: status.LoginStateLoggedIn})
+	if a.loginFailures != 0 {
+		t.Errorf("got %d login failures, want 0", a.loginFailures)
+	}
+
+	// test failed login with token error, notifies user
+	a.handleLoginResult(&client.Result{
+		LoginState: status.LoginStateLoggedOut,
+		Err:        fmt.Errorf("%d: token error", client.TokenError),
+	})
+	if a.loginFailures != 1 {
+		t.Errorf("got %d login failures, want 1", a.loginFailures)
+	}
+}
+
+// TestAgentHandleLoginResultMetrics tests the metrics in handleLoginResult
+// of Agent.
+func TestAgentHandleLoginResultMetrics(t *testing.T) {
+	// create agent
+	c := config.Default()
+	a := NewAgent(c)
+	a.dbus = &nopDBusService{}
+	setTrustedLoggingIn(a)
+
+	// test successful login, failed login and logout during and after
+	// login, logouts should not be counted as failed logins
+	for _, r := range []*client.Result{
+		{LoginState: status.LoginStateLoggingIn},
+		{LoginState: status.LoginStateLoggedIn},
+		{LoginState: status.LoginStateLoggingIn},
+		{LoginState: status.LoginStateLoggedOut, Err: fmt.Errorf("%d: test", client.TokenError)},
+		{LoginState: status.LoginStateLoggedOut},
+		{LoginState: status.LoginStateLoggingIn},
+		{LoginState: status.LoginStateLoggedOut},
+		{LoginState: status.LoginStateLoggedIn},
+	} {
+		a.handleLoginResult(r)
+	}
+
+	b := &bytes.Buffer{}
+	if err := a.metrics.Write(b, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"fw_id_agent_login_attempts_total{uid=\"%d\"} 2\n",
+		"fw_id_agent_login_successes_total{uid=\"%d\"} 1\n",
+		"fw_id_agent_login_failures_total{uid=\"%d\",class=\"token\"} 1\n",
+		"fw_id_agent_logged_in{uid=\"%d\"} 1\n",
+	} {
+		want = fmt.Sprintf(want, os.Getuid())
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics should contain %q:\n%s", want, b.String())
+		}
+	}
+	unknown := fmt.Sprintf("fw_id_agent_login_failures_total{uid=\"%d\",class=\"unknown\"} 0\n", os.Getuid())
+	if !strings.Contains(b.String(), unknown) {
+		t.Errorf("metrics should contain %q:\n%s", unknown, b.String())
+	}
+}
+
+// TestAgentStartMetrics tests startMetrics and stopMetrics of Agent.
+func TestAgentStartMetrics(t *testing.T) {
+	// test without metrics
+	c := config.Default()
+	a := NewAgent(c)
+	if err := a.startMetrics(); err != nil {
+		t.Fatal(err)
+	}
+	if a.metricsServer != nil || a.metricsFile != nil {
+		t.Error("metrics should not be started")
+	}
+	a.stopMetrics()
+
+	// test with metrics
+	dir := t.TempDir()
+	c.MetricsAddress = "unix:" + filepath.Join(dir, "metrics.sock")
+	c.MetricsTextfileDir = dir
+	if err := a.startMetrics(); err != nil {
+		t.Fatal(err)
+	}
+	if a.metricsServer == nil || a.metricsFile == nil {
+		t.Error("metrics should be started")
+	}
+	a.stopMetrics()
+	if a.metricsServer != nil || a.metricsFile != nil {
+		t.Error("metrics should be stopped")
+	}
+
+	// test with invalid address
+	c.MetricsAddress = "invalid address"
+	c.MetricsTextfileDir = ""
+	if err := a.startMetrics(); err == nil {
+		t.Error("metrics should not start with invalid address")
+	}
+}
+
+// TestAgentRestoreSaveState tests restoreState and saveState of Agent.
+func TestAgentRestoreSaveState(t *testing.T) {
+	defer func() { statefilePath = statefile.Path }()
+
+	// test state file error, state should not be saved
+	statefilePath = func() (string, error) {
+		return "", errors.New("test error")
+	}
+	a := NewAgent(config.Default())
+	a.dbus = &nopDBusService{}
+	a.restoreState()
+	if a.state != nil {
+		t.Error("state should not be set")
+	}
+	a.handleLoginResult(&client.Result{LoginState: status.LoginStateLoggedIn})
+
+	// test invalid state file, state should be reset
+	path := filepath.Join(t.TempDir(), "state.json")
+	statefilePath = func() (string, error) {
+		return path, nil
+	}
+	if err := os.WriteFile(path, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a = NewAgent(config.Default())
+	a.dbus = &nopDBusService{}
+	a.restoreState()
+	if a.state == nil || a.restored {
+		t.Error("state should be reset")
+	}
+
+	setTrustedLoggingIn(a)
+
+	// test saving login results
+	a.handleLoginResult(&client.Result{
+		LoginState: status.LoginStateLoggedIn,
+		KeepAlive:  10 * time.Minute,
+		SessionID:  "session",
+		SourceIP:   "192.168.1.10",
+	})
+	if a.state.SourceIP != "192.168.1.10" || a.state.KeepAlive != 10 {
+		t.Errorf("unexpected state: %v", a.state)
+	}
+	a.handleLoginResult(&client.Result{
+		LoginState: status.LoginStateLoggedOut,
+		Err:        errors.New("test error"),
+	})
+	s, err := statefile.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.LoggedIn || s.LastError != "test error" {
+		t.Errorf("unexpected state: %v", s)
+	}
+	a.handleLoginResult(&client.Result{LoginState: status.LoginStateLoggedIn})
+	s, err = statefile.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.LoggedIn ||
+		s.LastLogin != a.lastLogin ||
+		s.LastKeepAlive != a.lastKeepAlive ||
+		s.LastError != "" {
+		t.Errorf("unexpected state: %v", s)
+	}
+
+	// test restoring valid session
+	s.KeepAlive = 10
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	a = NewAgent(config.Default())
+	a.dbus = &nopDBusService{}
+	a.restoreState()
+	if !a.restored ||
+		a.lastLogin != s.LastLogin ||
+		a.lastKeepAlive != s.LastKeepAlive {
+		t.Error("state should be restored")
+	}
+
+	// test restored result, should not change times
+	a.lastKeepAlive = 1
+	a.handleLoginResult(&client.Result{
+		LoginState: status.LoginStateLoggedIn,
+		Restored:   true,
+	})
+	if a.lastKeepAlive != 1 {
+		t.Error("restored result should not change times")
+	}
+
+	// test stopping client, should save logged out state
+	a.client = client.NewClient(a.config, nil, nil)
+	a.client.Start()
+	a.stopClient()
+	s, err = statefile.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.LoggedIn {
+		t.Error("state should be logged out")
+	}
+}
+
+// TestAgentDetailsLastError tests the last error in details of Agent.
+func TestAgentDetailsLastError(t *testing.T) {
+	defer func() { statefilePath = statefile.Path }()
+	path := filepath.Join(t.TempDir(), "state.json")
+	statefilePath = func() (string, error) {
+		return path, nil
+	}
+
+	a := NewAgent(config.Default())
+	a.dbus = &nopDBusService{}
+	a.restoreState()
+	setTrustedLoggingIn(a)
+
+	hasLastError := func() bool {
+		prefix := i18n.String(i18n.LabelLastError) + ":"
+		for _, d := range a.details() {
+			if strings.HasPrefix(d, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// test failed login, should show last error
+	a.handleLoginResult(&client.Result{
+		LoginState: status.LoginStateLoggedOut,
+		Err:        errors.New("test error"),
+	})
+	if !hasLastError() {
+		t.Error("last error should be shown after failed login")
+	}
+
+	// test successful login and logout, should not show last error
+	a.handleLoginResult(&client.Result{LoginState: status.LoginStateLoggedIn})
+	a.handleLoginResult(&client.Result{LoginState: status.LoginStateLoggedOut})
+	if hasLastError() || a.state.LastError != "" {
+		t.Error("last error should be cleared after successful login")
 	}
 }
 
@@ -344,6 +718,198 @@ func TestAgentHandleDBusRequest(t *testing.T) {
 	if request.Error != nil {
 		t.Error("request should be OK and and error should not be set")
 	}
+
+	// logout, pause and resume
+	a.ccacheUp = &krbmon.CCacheUpdate{CCache: &credentials.CCache{}}
+	a.krbcfgUp = &krbmon.ConfUpdate{Config: krbconfig.New()}
+	a.startClient()
+	request = dbusapi.NewRequest(dbusapi.RequestLogout, nil)
+	a.handleDBusRequest(request)
+	request.Wait()
+	if request.Error != nil || a.client != nil || !a.userPaused ||
+		a.agentState != status.AgentStatePaused {
+		t.Errorf("invalid logout request: %v, %v", request.Error, a.client)
+	}
+
+	// trusted network after logout, should not log in again
+	a.handleTNDResult(true)
+	if a.client != nil {
+		t.Error("client should not be started after logout")
+	}
+
+	// relogin after logout, should log in again
+	request = dbusapi.NewRequest(dbusapi.RequestReLogin, nil)
+	a.handleDBusRequest(request)
+	request.Wait()
+	if request.Error != nil || a.client == nil || a.userPaused {
+		t.Errorf("invalid relogin request: %v, %v", request.Error, a.client)
+	}
+
+	request = dbusapi.NewRequest(dbusapi.RequestPause, nil)
+	a.handleDBusRequest(request)
+	request.Wait()
+	if request.Error != nil || !a.userPaused || a.client != nil ||
+		a.agentState != status.AgentStatePaused {
+		t.Errorf("invalid pause request: %v, %t", request.Error, a.userPaused)
+	}
+
+	// session resume, should not resume user pause
+	a.resume("session active")
+	if !a.userPaused || a.client != nil {
+		t.Error("session resume should not resume user pause")
+	}
+
+	request = dbusapi.NewRequest(dbusapi.RequestResume, nil)
+	a.handleDBusRequest(request)
+	request.Wait()
+	if request.Error != nil || a.userPaused || a.client == nil {
+		t.Errorf("invalid resume request: %v, %t", request.Error, a.userPaused)
+	}
+	a.stopClient()
+
+	// get history, contains relogin, logout, relogin, pause and resume
+	// requests, pause and resume events and trusted network change, but
+	// not the history request itself
+	a.setTrustedNetwork(false)
+	request = dbusapi.NewRequest(dbusapi.RequestGetHistory, nil)
+	request.Parameters = []any{int64(0)}
+	a.handleDBusRequest(request)
+	request.Wait()
+	if request.Error != nil || len(request.Results) != 1 {
+		t.Fatalf("invalid history request: %v, %v", request.Error, request.Results)
+	}
+	events, err := history.NewFromJSON([]byte(request.Results[0].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []history.Type{
+		history.TypeDBus,
+		history.TypeDBus,
+		history.TypeDBus,
+		history.TypeSession,
+		history.TypeDBus,
+		history.TypeSession,
+		history.TypeDBus,
+		history.TypeSession,
+		history.TypeDBus,
+		history.TypeSession,
+		history.TypeTND,
+	}
+	got := []history.Type{}
+	for _, e := range events {
+		switch e.Type {
+		case history.TypeDBus, history.TypeSession, history.TypeTND:
+			got = append(got, e.Type)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// get history since now, should be empty
+	since := time.Now().Add(time.Hour).Unix()
+	request = dbusapi.NewRequest(dbusapi.RequestGetHistory, nil)
+	request.Parameters = []any{since}
+	a.handleDBusRequest(request)
+	request.Wait()
+	if request.Error != nil || request.Results[0] != "[]" {
+		t.Errorf("invalid history request: %v, %v", request.Error, request.Results)
+	}
+
+	// set log level
+	defer log.SetLevel(log.GetLevel())
+	request = dbusapi.NewRequest(dbusapi.RequestSetLogLevel, nil)
+	request.Parameters = []any{"debug", int64(600)}
+	a.handleDBusRequest(request)
+	request.Wait()
+	if request.Error != nil || a.logLevel != "debug" || a.logLevelRevert == nil {
+		t.Errorf("invalid set log level request: %v, %s", request.Error, a.logLevel)
+	}
+
+	// set invalid log level
+	request = dbusapi.NewRequest(dbusapi.RequestSetLogLevel, nil)
+	request.Parameters = []any{"invalid", int64(0)}
+	a.handleDBusRequest(request)
+	request.Wait()
+	if request.Error == nil {
+		t.Error("invalid log level should fail")
+	}
+
+	// collect diagnostics
+	defer func() { createBundle = (*diagnostics.Diagnostics).Bundle }()
+	createBundle = func(d *diagnostics.Diagnostics) ([]byte, error) {
+		if d.Version != Version || d.Config == nil || d.Status == nil ||
+			d.Status.LogLevel != "debug" || len(d.History) == 0 {
+			t.Errorf("invalid diagnostics: %v", d)
+		}
+		return []byte("bundle"), nil
+	}
+	request = dbusapi.NewRequest(dbusapi.RequestCollectDiagnostics, nil)
+	a.handleDBusRequest(request)
+	request.Wait()
+	if request.Error != nil || !reflect.DeepEqual(request.Results, []any{[]byte("bundle")}) {
+		t.Errorf("invalid collect diagnostics request: %v, %v", request.Error, request.Results)
+	}
+
+	// collect diagnostics with error
+	createBundle = func(*diagnostics.Diagnostics) ([]byte, error) {
+		return nil, errors.New("test error")
+	}
+	request = dbusapi.NewRequest(dbusapi.RequestCollectDiagnostics, nil)
+	a.handleDBusRequest(request)
+	request.Wait()
+	if request.Error == nil {
+		t.Error("collect diagnostics should fail")
+	}
+}
+
+// TestAgentHandleSetLogLevel tests handleSetLogLevel and
+// handleLogLevelRevertTimer of Agent.
+func TestAgentHandleSetLogLevel(t *testing.T) {
+	defer log.SetLevel(log.GetLevel())
+
+	// create agent
+	c := config.Default()
+	a := NewAgent(c)
+	a.dbus = &nopDBusService{}
+	a.resetLogLevel()
+	if a.logLevel != "info" {
+		t.Errorf("got %s, want info", a.logLevel)
+	}
+
+	// test temporary log level
+	if err := a.handleSetLogLevel("trace", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if a.logLevel != "trace" || log.GetLevel() != log.TraceLevel || a.logLevelRevert == nil {
+		t.Errorf("log level should be trace with revert timer")
+	}
+
+	// test revert
+	a.handleLogLevelRevertTimer()
+	if a.logLevel != "info" || log.GetLevel() != log.InfoLevel || a.logLevelRevert != nil {
+		t.Errorf("log level should be reverted to info")
+	}
+
+	// test permanent log level and reset
+	if err := a.handleSetLogLevel("debug", 0); err != nil {
+		t.Fatal(err)
+	}
+	if a.logLevel != "debug" || a.logLevelRevert != nil {
+		t.Errorf("log level should be debug without revert timer")
+	}
+	a.config.Verbose = true
+	if err := a.handleSetLogLevel("", 0); err != nil {
+		t.Fatal(err)
+	}
+	if a.logLevel != "debug" {
+		t.Errorf("log level should be reset to verbose debug")
+	}
+
+	// test invalid log level
+	if err := a.handleSetLogLevel("invalid", 0); err == nil {
+		t.Error("invalid log level should fail")
+	}
 }
 
 // TrestAgentHandleSleepEvent tests handleSleepEvent of Agent.
@@ -362,15 +928,546 @@ func TestAgentHandleSleepEvent(t *testing.T) {
 		t.Error("client and trusted network should not be changed")
 	}
 
-	// test sleep event, should stop client and reset trusted network
+	// test sleep event, should stop client, reset trusted network and
+	// release inhibitor lock
+	oldCloseFD := closeFD
+	defer func() { closeFD = oldCloseFD }()
+	closeFD = func(int) error { return nil }
+	a.sleep.inhibitor = 42
+
 	a.handleSleepEvent(true)
 	if a.client != nil && a.trustedNetwork.Trusted() {
 		t.Error("client should be stopped and network not trusted")
 	}
+	if a.sleep.inhibitor != -1 {
+		t.Error("inhibitor lock should be released")
+	}
+	if a.wake != nil {
+		t.Error("wake-up timer should not be set")
+	}
+
+	// test wake-up event, should set wake-up timer
+	a.handleSleepEvent(false)
+	if a.wake == nil {
+		t.Error("wake-up timer should be set")
+	}
+
+	// test sleep event before wake-up timer fired, should reset timer
+	a.handleSleepEvent(true)
+	if a.wake != nil {
+		t.Error("wake-up timer should be reset")
+	}
+}
+
+// TestAgentHandleWakeTimer tests handleWakeTimer of Agent.
+func TestAgentHandleWakeTimer(t *testing.T) {
+	// create agent
+	c := config.Default()
+	c.WakeDelay = 0
+	a := NewAgent(c)
+	a.dbus = &nopDBusService{}
+	probes := make(chan struct{})
+	tnd := tndtest.NewDetector()
+	tnd.Funcs.Probe = func() {
+		probes <- struct{}{}
+	}
+	a.tnd = tnd
+
+	// test wake-up, timer should fire after delay and trigger probe
+	a.handleSleepEvent(false)
+	select {
+	case <-a.wake:
+	case <-time.After(time.Second):
+		t.Fatal("wake-up timer should fire")
+	}
+	a.handleWakeTimer()
+	if a.wake != nil {
+		t.Error("wake-up timer should be reset")
+	}
+	select {
+	case <-probes:
+	case <-time.After(time.Second):
+		t.Fatal("trusted network should be probed")
+	}
+
+	// test trusted result after probe, should start client and log in
+	a.ccacheUp = &krbmon.CCacheUpdate{CCache: &credentials.CCache{}}
+	a.krbcfgUp = &krbmon.ConfUpdate{Config: krbconfig.New()}
+	a.handleTNDResult(true)
+	if a.client == nil {
+		t.Fatal("client should be started")
+	}
+	a.stopClient()
+}
+
+// TestAgentHandleNetworkUpdate tests handleNetworkUpdate of Agent.
+func TestAgentHandleNetworkUpdate(t *testing.T) {
+	// create agent
+	c := config.Default()
+	a := NewAgent(c)
+	a.dbus = &nopDBusService{}
+	probes := make(chan struct{})
+	tnd := tndtest.NewDetector()
+	tnd.Funcs.Probe = func() {
+		probes <- struct{}{}
+	}
+	a.tnd = tnd
+	a.ccacheUp = &krbmon.CCacheUpdate{CCache: &credentials.CCache{}}
+	a.krbcfgUp = &krbmon.ConfUpdate{Config: krbconfig.New()}
+
+	// test while sleeping, should be ignored
+	a.sleeping = true
+	a.handleNetworkUpdate(&netmon.Update{Addresses: []string{"10.0.0.2"}})
+	select {
+	case <-probes:
+		t.Error("trusted network should not be probed")
+	case <-time.After(10 * time.Millisecond):
+	}
+	a.sleeping = false
+
+	// test network change, should trigger probe
+	a.handleNetworkUpdate(&netmon.Update{Addresses: []string{"192.168.1.10"}})
+	select {
+	case <-probes:
+	case <-time.After(time.Second):
+		t.Fatal("trusted network should be probed")
+	}
+
+	// test network change while logged in, should not restart client,
+	// the client checks the source IP and logs in again if it changed
+	a.trustedNetwork = status.TrustedNetworkTrusted
+	a.startClient()
+	old := a.client
+	a.loginState = status.LoginStateLoggedIn
+	a.handleNetworkUpdate(&netmon.Update{Addresses: []string{"10.0.0.2"}})
+	<-probes
+	if a.client != old {
+		t.Error("client should not be restarted")
+	}
+
+	// test address change of an unrelated interface while logged in,
+	// e.g., a container bridge, should not restart client and keep the
+	// session
+	a.handleNetworkUpdate(&netmon.Update{Addresses: []string{"10.0.0.2", "172.17.0.1"}})
+	<-probes
+	if a.client != old || a.loginState != status.LoginStateLoggedIn {
+		t.Error("client should not be restarted and keep its session")
+	}
+	a.stopClient()
+}
+
+// TestAgentHandleReload tests handleReload of Agent.
+func TestAgentHandleReload(t *testing.T) {
+	oldNewTND := newTND
+	defer func() { newTND = oldNewTND }()
+
+	// create agent
+	c := config.Default()
+	a := NewAgent(c)
+	a.dbus = &nopDBusService{}
+	oldTND := tndtest.NewDetector()
+	oldStopped := false
+	oldTND.Funcs.Stop = func() { oldStopped = true }
+	a.tnd = oldTND
+	a.trustedNetwork = status.TrustedNetworkTrusted
+	a.client = client.NewClient(a.config, nil, nil)
+	a.client.Start()
+
+	// test reload with new tnd servers
+	var servers map[string]string
+	newTND = func(*tnd.Config) tnd.TND {
+		d := tndtest.NewDetector()
+		d.Funcs.SetServers = func(s map[string]string) { servers = s }
+		return d
+	}
+	cfg := config.Default()
+	cfg.TND.HTTPSServers = []config.TNDHTTPSConfig{
+		{URL: "https://tnd.example.com", Hash: "abcdef"},
+	}
+	if err := a.handleReload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if a.config != cfg {
+		t.Errorf("got %p, want %p", a.config, cfg)
+	}
+	if !oldStopped || a.tnd == oldTND {
+		t.Error("old TND should be stopped and replaced")
+	}
+	want := map[string]string{"https://tnd.example.com": "abcdef"}
+	if !reflect.DeepEqual(servers, want) {
+		t.Errorf("got %v, want %v", servers, want)
+	}
+	if a.client != nil || a.trustedNetwork.Trusted() {
+		t.Error("client should be stopped and network not trusted")
+	}
+
+	// test reload with tnd start error
+	newTND = func(*tnd.Config) tnd.TND {
+		d := tndtest.NewDetector()
+		d.Funcs.Start = func() error { return errors.New("test error") }
+		return d
+	}
+	running := a.tnd
+	if err := a.handleReload(config.Default()); err == nil {
+		t.Error("reload should fail with TND start error")
+	}
+	if a.config != cfg || a.tnd != running {
+		t.Error("old config and TND should be kept on error")
+	}
+
+	// test reload with metrics start error, new TND should be stopped
+	// and old config kept
+	newStopped := false
+	newTND = func(*tnd.Config) tnd.TND {
+		d := tndtest.NewDetector()
+		d.Funcs.Stop = func() { newStopped = true }
+		return d
+	}
+	invalid := config.Default()
+	invalid.MetricsAddress = "invalid address"
+	if err := a.handleReload(invalid); err == nil {
+		t.Error("reload should fail with metrics start error")
+	}
+	if !newStopped || a.config != cfg || a.tnd != running {
+		t.Error("new TND should be stopped and old config and TND kept")
+	}
+
+	// test reload with session policy keep, should stop session monitor
+	// and resume agent
+	newTND = func(*tnd.Config) tnd.TND {
+		return tndtest.NewDetector()
+	}
+	a.session = NewSessionMon()
+	a.session.conn, _ = dbus.NewConn(&testRWC{})
+	go a.session.start()
+	a.sessionEvents = a.session.Events()
+	<-a.sessionEvents
+	a.paused = true
+	if err := a.handleReload(config.Default()); err != nil {
+		t.Fatal(err)
+	}
+	if a.session != nil || a.sessionEvents != nil || a.paused {
+		t.Error("session monitor should be stopped and agent resumed")
+	}
+}
+
+// TestAgentHandleRelogin tests handleRelogin of Agent.
+func TestAgentHandleRelogin(t *testing.T) {
+	// create agent
+	c := config.Default()
+	a := NewAgent(c)
+	a.dbus = &nopDBusService{}
+	a.ccacheUp = &krbmon.CCacheUpdate{CCache: &credentials.CCache{}}
+	a.krbcfgUp = &krbmon.ConfUpdate{Config: krbconfig.New()}
+
+	// test not trusted, should not start client
+	a.handleRelogin()
+	if a.client != nil {
+		t.Error("client should not be started")
+	}
+
+	// test trusted, should restart client
+	a.trustedNetwork = status.TrustedNetworkTrusted
+	a.startClient()
+	old := a.client
+	a.handleRelogin()
+	if a.client == nil || a.client == old {
+		t.Error("client should be restarted")
+	}
+	a.stopClient()
+}
+
+// TestApplyNotificationEvent tests applyNotificationEvent.
+func TestApplyNotificationEvent(t *testing.T) {
+	defaults := func() *notify.Notification {
+		return &notify.Notification{
+			Title:    "test",
+			Icon:     "security-low",
+			Urgency:  notify.UrgencyLow,
+			Category: "network",
+		}
+	}
+
+	// test empty settings, keep defaults
+	n := defaults()
+	applyNotificationEvent(config.NotificationEvent{}, n)
+	if !reflect.DeepEqual(n, defaults()) {
+		t.Errorf("got %v, want %v", n, defaults())
+	}
+
+	// test settings
+	n = defaults()
+	applyNotificationEvent(config.NotificationEvent{
+		Urgency:  config.UrgencyCritical,
+		Icon:     "dialog-warning",
+		Timeout:  -1,
+		Category: "network.error",
+	}, n)
+	want := &notify.Notification{
+		Title:    "test",
+		Icon:     "dialog-warning",
+		Urgency:  notify.UrgencyCritical,
+		Category: "network.error",
+		Timeout:  -time.Second,
+	}
+	if !reflect.DeepEqual(n, want) {
+		t.Errorf("got %v, want %v", n, want)
+	}
+}
+
+// TestAgentNotifyActions tests notifyActions of Agent.
+func TestAgentNotifyActions(t *testing.T) {
+	// create agent
+	c := config.Default()
+	a := NewAgent(c)
+
+	keys := func(kinit bool) (k []string) {
+		for _, action := range a.notifyActions(kinit) {
+			k = append(k, action.Key)
+		}
+		return
+	}
+
+	// test not trusted, only details
+	want := []string{notifyActionDetails}
+	if got := keys(true); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test trusted with kinit command
+	a.trustedNetwork = status.TrustedNetworkTrusted
+	a.config.KinitCommand = "krb5-auth-dialog"
+	want = []string{notifyActionReLogin, notifyActionKinit, notifyActionDetails}
+	if got := keys(true); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	want = []string{notifyActionReLogin, notifyActionDetails}
+	if got := keys(false); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test paused, no re-login
+	a.paused = true
+	want = []string{notifyActionKinit, notifyActionDetails}
+	if got := keys(true); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestAgentHandleNotificationAction tests handleNotificationAction of Agent.
+func TestAgentHandleNotificationAction(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+
+	// create agent
+	c := config.Default()
+	a := NewAgent(c)
+	a.dbus = &nopDBusService{}
+	a.ccacheUp = &krbmon.CCacheUpdate{CCache: &credentials.CCache{}}
+	a.krbcfgUp = &krbmon.ConfUpdate{Config: krbconfig.New()}
+
+	// test re-login, not trusted, should not start client
+	a.handleNotificationAction(notifyActionReLogin)
+	if a.client != nil {
+		t.Error("client should not be started")
+	}
+
+	// test re-login, trusted, should restart client
+	a.trustedNetwork = status.TrustedNetworkTrusted
+	a.startClient()
+	old := a.client
+	a.handleNotificationAction(notifyActionReLogin)
+	if a.client == nil || a.client == old {
+		t.Error("client should be restarted")
+	}
+	a.stopClient()
+
+	// test kinit without command
+	var got []string
+	execCommand = func(name string, arg ...string) *exec.Cmd {
+		got = append([]string{name}, arg...)
+		return exec.Command("true")
+	}
+	a.handleNotificationAction(notifyActionKinit)
+	if got != nil {
+		t.Errorf("kinit command should not run: %v", got)
+	}
+
+	// test kinit with command
+	a.config.KinitCommand = `kinit -k -t "/path with space/keytab"`
+	a.handleNotificationAction(notifyActionKinit)
+	want := []string{"/bin/sh", "-c", `kinit -k -t "/path with space/keytab"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test kinit with command error
+	execCommand = func(string, ...string) *exec.Cmd {
+		return exec.Command("/does/not/exist")
+	}
+	a.handleNotificationAction(notifyActionKinit)
+
+	// test details and unknown action
+	a.state = &statefile.State{LastError: "test error"}
+	a.kerberosTGT.EndTime = time.Now().Unix()
+	a.handleNotificationAction(notifyActionDetails)
+	a.handleNotificationAction("unknown")
+
+	// check history
+	n := 0
+	for _, e := range a.history.Events(time.Time{}) {
+		if e.Type == history.TypeNotification {
+			n++
+		}
+	}
+	if n != 7 {
+		t.Errorf("got %d notification events, want 7", n)
+	}
+}
+
+// TestAgentHandleDump tests handleDump of Agent.
+func TestAgentHandleDump(t *testing.T) {
+	// create agent
+	c := config.Default()
+	a := NewAgent(c)
+	a.history.Add(history.NewEvent(history.TypeLogin, "test").
+		WithDetail("Key", "Value"))
+
+	// dump state and history
+	b := &bytes.Buffer{}
+	oldOut := log.StandardLogger().Out
+	defer log.SetOutput(oldOut)
+	log.SetOutput(b)
+	a.handleDump()
+
+	for _, want := range []string{
+		"msg=\"Agent state\"",
+		"msg=\"Agent history: test\"",
+		"details=\"map[Key:Value]\"",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("%q not in %q", want, b.String())
+		}
+	}
+}
+
+// TestAgentReloadReloginDump tests Reload, Relogin and Dump of Agent.
+func TestAgentReloadReloginDump(t *testing.T) {
+	c := config.Default()
+	a := NewAgent(c)
+
+	// test with main loop
+	go a.Reload(c)
+	if got := <-a.reload; got != c {
+		t.Errorf("got %p, want %p", got, c)
+	}
+	go a.Relogin()
+	<-a.relogin
+	go a.Dump()
+	<-a.dump
+
+	// test with stopped main loop, should not block
+	close(a.closed)
+	a.Reload(c)
+	a.Relogin()
+	a.Dump()
+}
+
+// TestAgentHandleSessionEvent tests handleSessionEvent of Agent.
+func TestAgentHandleSessionEvent(t *testing.T) {
+	newAgent := func(policy string) *Agent {
+		c := config.Default()
+		c.SessionPolicy = policy
+		c.LockGracePeriod = 0
+		a := NewAgent(c)
+		a.dbus = &nopDBusService{}
+		a.ccacheUp = &krbmon.CCacheUpdate{CCache: &credentials.CCache{}}
+		a.krbcfgUp = &krbmon.ConfUpdate{Config: krbconfig.New()}
+		a.setTrustedNetwork(true)
+		a.startClient()
+		return a
+	}
+
+	// test keep policy, should not pause
+	a := newAgent(config.SessionPolicyKeep)
+	a.handleSessionEvent(&SessionEvent{Locked: true, Active: false})
+	if a.paused || a.lock != nil || a.client == nil {
+		t.Error("agent should not be paused")
+	}
+	a.stopClient()
+
+	// test logout on lock policy, lock should start lock timer
+	a = newAgent(config.SessionPolicyLogoutOnLock)
+	a.handleSessionEvent(&SessionEvent{Locked: true, Active: true})
+	if a.paused || a.lock == nil || a.client == nil {
+		t.Fatal("agent should start lock timer")
+	}
+
+	// unlock before grace period is over should stop lock timer
+	a.handleSessionEvent(&SessionEvent{Locked: false, Active: true})
+	if a.paused || a.lock != nil || a.client == nil {
+		t.Error("agent should stop lock timer")
+	}
+
+	// lock timer should pause agent and stop client
+	a.handleSessionEvent(&SessionEvent{Locked: true, Active: true})
+	<-a.lock
+	a.handleLockTimer()
+	if !a.paused || a.client != nil || a.agentState != status.AgentStatePaused {
+		t.Error("agent should be paused and client stopped")
+	}
+
+	// paused agent should not start client
+	a.handleTNDResult(true)
+	if a.client != nil {
+		t.Error("client should not be started while paused")
+	}
+
+	// unlock should resume agent and start client
+	a.handleSessionEvent(&SessionEvent{Locked: false, Active: true})
+	if a.paused || a.client == nil || a.agentState != status.AgentStateTrustedLoggingIn {
+		t.Error("agent should be resumed and client started")
+	}
+	a.stopClient()
+
+	// test logout on inactive policy
+	a = newAgent(config.SessionPolicyLogoutOnInactive)
+	a.handleSessionEvent(&SessionEvent{Locked: true, Active: true})
+	if a.paused || a.client == nil {
+		t.Error("agent should not be paused")
+	}
+	a.handleSessionEvent(&SessionEvent{Locked: false, Active: false})
+	if !a.paused || a.client != nil {
+		t.Error("agent should be paused and client stopped")
+	}
+	a.handleSessionEvent(&SessionEvent{Locked: false, Active: true})
+	if a.paused || a.client == nil {
+		t.Error("agent should be resumed and client started")
+	}
+	a.stopClient()
+}
+
+// TestMonitorSession tests monitorSession.
+func TestMonitorSession(t *testing.T) {
+	for policy, want := range map[string]bool{
+		"":                                   false,
+		config.SessionPolicyKeep:             false,
+		config.SessionPolicyLogoutOnLock:     true,
+		config.SessionPolicyLogoutOnInactive: true,
+	} {
+		c := config.Default()
+		c.SessionPolicy = policy
+		if got := monitorSession(c); got != want {
+			t.Errorf("%s: got %t, want %t", policy, got, want)
+		}
+		if got := NewAgent(c).session != nil; got != want {
+			t.Errorf("%s: got session monitor %t, want %t", policy, got, want)
+		}
+	}
 }
 
 // TestAgentStartStop tests Start and Stop of Agent.
 func TestAgentStartStop(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
 	c := config.Default()
 	a := NewAgent(c)
 	a.dbus = &nopDBusService{}
@@ -402,6 +1499,7 @@ func TestNewAgent(t *testing.T) {
 		a.krbcfg == nil ||
 		a.tnd == nil ||
 		a.sleep == nil ||
+		a.netmon == nil ||
 		a.errors == nil ||
 		a.done == nil ||
 		a.closed == nil {
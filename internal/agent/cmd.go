@@ -6,9 +6,13 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/fw-id-agent/internal/cmdline"
+	"github.com/telekom-mms/fw-id-agent/internal/i18n"
+	"github.com/telekom-mms/fw-id-agent/internal/logging"
 	"github.com/telekom-mms/fw-id-agent/pkg/config"
 )
 
@@ -29,8 +33,16 @@ const (
 	argRetryTimer    = "retrytimer"
 	argTNDServers    = "tndservers"
 	argVerbose       = "verbose"
+	argLogFormat     = "logformat"
+	argLogLevel      = "loglevel"
 	argStartDelay    = "startdelay"
 	argNotifications = "notifications"
+	argKinitCommand  = "kinitcommand"
+	argWakeDelay     = "wakedelay"
+	argSessionPolicy = "sessionpolicy"
+	argLockGrace     = "lockgraceperiod"
+	argMetricsAddr   = "metricsaddress"
+	argMetricsDir    = "metricstextfiledir"
 )
 
 // flagIsSet returns whether flag with name is set as command line argument.
@@ -44,6 +56,32 @@ func flagIsSet(flags *flag.FlagSet, name string) bool {
 	return isSet
 }
 
+// signedArgs are the command line arguments that must not override the
+// settings in a signed config file.
+var signedArgs = []string{argServiceURL, argRealm, argTNDServers}
+
+// checkSignedArgs checks that the config file is set and no security-relevant
+// settings are overridden with command line arguments in flags if config
+// signatures are verified.
+func checkSignedArgs(flags *flag.FlagSet) error {
+	required, err := config.SignatureRequired()
+	if err != nil {
+		return err
+	}
+	if !required {
+		return nil
+	}
+	if !flagIsSet(flags, argConfig) {
+		return fmt.Errorf("signed config file required, set -%s", argConfig)
+	}
+	for _, name := range signedArgs {
+		if flagIsSet(flags, name) {
+			return fmt.Errorf("argument -%s not allowed with signed config file", name)
+		}
+	}
+	return nil
+}
+
 // parseTNDServers parses the TND servers command line argument.
 func parseTNDServers(servers string) ([]config.TNDHTTPSConfig, bool) {
 	if servers == "" {
@@ -63,40 +101,111 @@ func parseTNDServers(servers string) ([]config.TNDHTTPSConfig, bool) {
 	return list, true
 }
 
+// arguments are the command line arguments of the agent.
+type arguments struct {
+	cfgFile       string
+	ver           bool
+	serviceURL    string
+	realm         string
+	keepAlive     int
+	loginTimeout  int
+	logoutTimeout int
+	retryTimer    int
+	tndServers    string
+	verbose       bool
+	logFormat     string
+	logLevel      string
+	startDelay    int
+	notifications bool
+	kinitCommand  string
+	wakeDelay     int
+	sessionPolicy string
+	lockGrace     int
+	metricsAddr   string
+	metricsDir    string
+}
+
+// newCommand returns the command line definition of the agent with name. The
+// flags are bound to a.
+func newCommand(name string, a *arguments) *cmdline.Command {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	defaults := config.Default()
+	flags.StringVar(&a.cfgFile, argConfig, "", "Set config `file`")
+	flags.BoolVar(&a.ver, argVersion, false, "print version")
+	flags.StringVar(&a.serviceURL, argServiceURL, "", "Set service URL")
+	flags.StringVar(&a.realm, argRealm, "", "Set kerberos realm")
+	flags.IntVar(&a.keepAlive, argKeepAlive, defaults.KeepAlive, "Set default client keep-alive in `minutes`")
+	flags.IntVar(&a.loginTimeout, argLoginTimeout, defaults.LoginTimeout, "Set client login request timeout in `seconds`")
+	flags.IntVar(&a.logoutTimeout, argLogoutTimeout, defaults.LogoutTimeout, "Set client logout request timeout in `seconds`")
+	flags.IntVar(&a.retryTimer, argRetryTimer, defaults.RetryTimer, "Set client login retry timer in case of errors in `seconds`")
+	flags.StringVar(&a.tndServers, argTNDServers, "", "Set comma-separated `list` of TND server url:hash pairs")
+	flags.BoolVar(&a.verbose, argVerbose, defaults.Verbose, "Set verbose output")
+	flags.StringVar(&a.logFormat, argLogFormat, defaults.LogFormat, "Set log `format` (text, json, journald)")
+	flags.StringVar(&a.logLevel, argLogLevel, defaults.LogLevel,
+		"Set log `level` (trace, debug, info, warn, error), overrides verbose")
+	flags.IntVar(&a.startDelay, argStartDelay, defaults.StartDelay, "Set agent start delay in `seconds`")
+	flags.BoolVar(&a.notifications, argNotifications, defaults.Notifications, "Set desktop notifications")
+	flags.StringVar(&a.kinitCommand, argKinitCommand, defaults.KinitCommand,
+		"Set `command` for the kinit action of desktop notifications")
+	flags.IntVar(&a.wakeDelay, argWakeDelay, defaults.WakeDelay, "Set network settle delay after wake-up in `seconds`")
+	flags.StringVar(&a.sessionPolicy, argSessionPolicy, defaults.SessionPolicy,
+		"Set session `policy` (keep, logout-on-lock, logout-on-inactive)")
+	flags.IntVar(&a.lockGrace, argLockGrace, defaults.LockGracePeriod,
+		"Set grace period before logout on session lock in `seconds`")
+	flags.StringVar(&a.metricsAddr, argMetricsAddr, defaults.MetricsAddress,
+		"Set metrics `address` (unix:/path/to/socket or loopback host:port)")
+	flags.StringVar(&a.metricsDir, argMetricsDir, defaults.MetricsTextfileDir,
+		"Set node_exporter textfile collector `directory` for metrics")
+
+	return &cmdline.Command{
+		Name:  name,
+		Short: "firewall identity agent",
+		Long: "fw-id-agent logs the user on to the corporate firewall with " +
+			"Kerberos when the host is connected to a trusted network and " +
+			"keeps the login alive. It runs as systemd user service in the " +
+			"user session and is controlled with fw-id-cli.\n\n" +
+			"Command line arguments override the settings in the config file. " +
+			"If config signatures are verified, the config file is required " +
+			"and the service URL, realm and TND servers cannot be overridden.",
+		Flags: flags,
+		FlagValues: map[string][]string{
+			argLogFormat:     {"text", "json", "journald"},
+			argLogLevel:      {"trace", "debug", "info", "warn", "error"},
+			argSessionPolicy: {"keep", "logout-on-lock", "logout-on-inactive"},
+		},
+	}
+}
+
+// Command returns the command line definition of the agent.
+func Command() *cmdline.Command {
+	return newCommand("fw-id-agent", &arguments{})
+}
+
 // getConfig gets the config from the config file and command line arguments.
 func getConfig(args []string) (*config.Config, error) {
 	// parse command line arguments
-	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
-	defaults := config.Default()
-	cfgFile := flags.String(argConfig, "", "Set config `file`")
-	ver := flags.Bool(argVersion, false, "print version")
-	serviceURL := flags.String(argServiceURL, "", "Set service URL")
-	realm := flags.String(argRealm, "", "Set kerberos realm")
-	keepAlive := flags.Int(argKeepAlive, defaults.KeepAlive, "Set default client keep-alive in `minutes`")
-	loginTimeout := flags.Int(argLoginTimeout, defaults.LoginTimeout, "Set client login request timeout in `seconds`")
-	logoutTimeout := flags.Int(argLogoutTimeout, defaults.LogoutTimeout, "Set client logout request timeout in `seconds`")
-	retryTimer := flags.Int(argRetryTimer, defaults.RetryTimer, "Set client login retry timer in case of errors in `seconds`")
-	tndServers := flags.String(argTNDServers, "", "Set comma-separated `list` of TND server url:hash pairs")
-	verbose := flags.Bool(argVerbose, defaults.Verbose, "Set verbose output")
-	startDelay := flags.Int(argStartDelay, defaults.StartDelay, "Set agent start delay in `seconds`")
-	notifications := flags.Bool(argNotifications, defaults.Notifications, "Set desktop notifications")
-	if err := flags.Parse(args[1:]); err != nil {
+	a := &arguments{}
+	cmd := newCommand(args[0], a)
+	if _, err := cmd.Parse(args[1:]); err != nil {
 		return nil, err
 	}
+	flags := cmd.Flags
 
 	// print version?
-	if *ver {
+	if a.ver {
 		fmt.Println(Version)
 		return nil, flag.ErrHelp
 	}
 
-	// log version
-	log.WithField("version", Version).Info("Starting Agent")
+	// with config signatures, only allow signed security-relevant settings
+	if err := checkSignedArgs(flags); err != nil {
+		return nil, err
+	}
 
 	// load config or try defaults
 	cfg := config.Default()
 	if flagIsSet(flags, argConfig) {
-		c, err := config.Load(*cfgFile)
+		c, err := config.Load(a.cfgFile)
 		if err != nil {
 			return nil, fmt.Errorf("could not load config: %w", err)
 		}
@@ -105,38 +214,62 @@ func getConfig(args []string) (*config.Config, error) {
 
 	// overwrite config settings with command line arguments
 	if flagIsSet(flags, argServiceURL) {
-		cfg.ServiceURL = *serviceURL
+		cfg.ServiceURL = a.serviceURL
 	}
 	if flagIsSet(flags, argRealm) {
-		cfg.Realm = *realm
+		cfg.Realm = a.realm
 	}
 	if flagIsSet(flags, argKeepAlive) {
-		cfg.KeepAlive = *keepAlive
+		cfg.KeepAlive = a.keepAlive
 	}
 	if flagIsSet(flags, argLoginTimeout) {
-		cfg.LoginTimeout = *loginTimeout
+		cfg.LoginTimeout = a.loginTimeout
 	}
 	if flagIsSet(flags, argLogoutTimeout) {
-		cfg.LogoutTimeout = *logoutTimeout
+		cfg.LogoutTimeout = a.logoutTimeout
 	}
 	if flagIsSet(flags, argRetryTimer) {
-		cfg.RetryTimer = *retryTimer
+		cfg.RetryTimer = a.retryTimer
 	}
 	if flagIsSet(flags, argTNDServers) {
-		servers, ok := parseTNDServers(*tndServers)
+		servers, ok := parseTNDServers(a.tndServers)
 		if !ok {
-			return nil, fmt.Errorf("could not parse TND servers %#v", *tndServers)
+			return nil, fmt.Errorf("could not parse TND servers %#v", a.tndServers)
 		}
 		cfg.TND.HTTPSServers = servers
 	}
 	if flagIsSet(flags, argVerbose) {
-		cfg.Verbose = *verbose
+		cfg.Verbose = a.verbose
+	}
+	if flagIsSet(flags, argLogFormat) {
+		cfg.LogFormat = a.logFormat
+	}
+	if flagIsSet(flags, argLogLevel) {
+		cfg.LogLevel = a.logLevel
 	}
 	if flagIsSet(flags, argStartDelay) {
-		cfg.StartDelay = *startDelay
+		cfg.StartDelay = a.startDelay
 	}
 	if flagIsSet(flags, argNotifications) {
-		cfg.Notifications = *notifications
+		cfg.Notifications = a.notifications
+	}
+	if flagIsSet(flags, argKinitCommand) {
+		cfg.KinitCommand = a.kinitCommand
+	}
+	if flagIsSet(flags, argWakeDelay) {
+		cfg.WakeDelay = a.wakeDelay
+	}
+	if flagIsSet(flags, argSessionPolicy) {
+		cfg.SessionPolicy = a.sessionPolicy
+	}
+	if flagIsSet(flags, argLockGrace) {
+		cfg.LockGracePeriod = a.lockGrace
+	}
+	if flagIsSet(flags, argMetricsAddr) {
+		cfg.MetricsAddress = a.metricsAddr
+	}
+	if flagIsSet(flags, argMetricsDir) {
+		cfg.MetricsTextfileDir = a.metricsDir
 	}
 
 	// check if config is valid
@@ -147,10 +280,80 @@ func getConfig(args []string) (*config.Config, error) {
 	return cfg, nil
 }
 
-// setVerbose sets verbose mode based on the configuration.
-func setVerbose(cfg *config.Config) {
-	if cfg.Verbose {
-		log.SetLevel(log.DebugLevel)
+// setLogging sets the log format and log level based on the configuration.
+func setLogging(cfg *config.Config) {
+	if err := logging.Setup(cfg.LogFormat, cfg.GetLogLevel()); err != nil {
+		log.WithError(err).Error("Agent could not set up logging, using text format")
+	}
+}
+
+// reloadConfig reloads the config from the config file and command line
+// arguments in args and sets it in agent a. If the new config is not valid,
+// e.g., because of an invalid signature, the current config is kept.
+func reloadConfig(a *Agent, args []string) {
+	cfg, err := getConfig(args)
+	if err != nil {
+		log.WithError(err).Error("Agent could not reload config, keeping current config")
+		return
+	}
+	setLogging(cfg)
+	a.Reload(cfg)
+}
+
+// handleSignals handles the signals received over c until the agent a should
+// terminate or returns an error.
+func handleSignals(a *Agent, args []string, c chan os.Signal) error {
+	for {
+		select {
+		case sig := <-c:
+			switch sig {
+			case syscall.SIGHUP:
+				log.Info("Agent got hangup signal, reloading config")
+				reloadConfig(a, args)
+			case syscall.SIGUSR1:
+				log.Info("Agent got user signal 1, logging in again")
+				a.Relogin()
+			case syscall.SIGUSR2:
+				log.Info("Agent got user signal 2, dumping state")
+				a.Dump()
+			default:
+				log.WithField("signal", sig).Info("Agent got terminate signal")
+				return nil
+			}
+		case err := <-a.Errors():
+			return err
+		}
+	}
+}
+
+// waitStartDelay waits for the start delay in config cfg while handling the
+// signals received over c. It returns the config, reloaded on hangup signal,
+// and false if the agent should terminate before it is started.
+func waitStartDelay(cfg *config.Config, args []string, c chan os.Signal) (*config.Config, bool) {
+	timer := time.NewTimer(cfg.GetStartDelay())
+	defer timer.Stop()
+	for {
+		select {
+		case sig := <-c:
+			switch sig {
+			case syscall.SIGHUP:
+				log.Info("Agent got hangup signal before start, reloading config")
+				newCfg, err := getConfig(args)
+				if err != nil {
+					log.WithError(err).Error("Agent could not reload config, keeping current config")
+					continue
+				}
+				setLogging(newCfg)
+				cfg = newCfg
+			case syscall.SIGUSR1, syscall.SIGUSR2:
+				log.WithField("signal", sig).Info("Agent ignoring user signal before start")
+			default:
+				log.WithField("signal", sig).Info("Agent got terminate signal before start")
+				return cfg, false
+			}
+		case <-timer.C:
+			return cfg, true
+		}
 	}
 }
 
@@ -161,15 +364,33 @@ func run(args []string) error {
 		return err
 	}
 
-	// set verbose output
-	setVerbose(cfg)
+	// set log format and level
+	setLogging(cfg)
+
+	// log version
+	log.WithField("version", Version).Info("Starting Agent")
+
+	// set language of user-facing messages
+	i18n.SetLocale(i18n.EnvLocale())
 
 	log.WithField("config", cfg).Debug("Agent starting with valid config")
 
+	// catch interrupt and terminate signals for clean logout, hangup
+	// signal for config reload and user signals for re-login and state
+	// dump, before the start delay so signals are not lost or terminate
+	// the agent without logout
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP,
+		syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(c)
+
 	// give the user's desktop environment some time to start after login,
 	// so we do not miss notifications
 	log.WithField("seconds", cfg.StartDelay).Debug("Agent sleeping before starting")
-	time.Sleep(cfg.GetStartDelay())
+	cfg, ok := waitStartDelay(cfg, args, c)
+	if !ok {
+		return nil
+	}
 
 	// start agent
 	log.Debug("Agent starting")
@@ -179,18 +400,8 @@ func run(args []string) error {
 	}
 	defer a.Stop()
 
-	// catch interrupt signal
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	// wait for interrupt signal or agent error
-	select {
-	case <-c:
-		log.Info("Agent got interrupt signal")
-	case err = <-a.Errors():
-	}
-
-	return err
+	// handle signals until terminate signal or agent error
+	return handleSignals(a, args, c)
 }
 
 // Run is the main entry point.
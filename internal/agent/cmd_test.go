@@ -1,11 +1,17 @@
 package agent
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"syscall"
 	"testing"
 
 	log "github.com/sirupsen/logrus"
@@ -108,6 +114,14 @@ func TestGetConfig(t *testing.T) {
 		}
 	})
 
+	t.Run("unexpected positional argument", func(t *testing.T) {
+		args := []string{"test", "extra"}
+		_, err := getConfig(args)
+		if err == nil {
+			t.Error("unexpected positional argument should fail")
+		}
+	})
+
 	t.Run("invalid TND servers", func(t *testing.T) {
 		args := []string{"test", fmt.Sprintf("--%s=invalid", argTNDServers)}
 		_, err := getConfig(args)
@@ -141,8 +155,16 @@ func TestGetConfig(t *testing.T) {
 			fmt.Sprintf("--%s=1", argRetryTimer),
 			fmt.Sprintf("--%s=example:abcdef", argTNDServers),
 			fmt.Sprintf("--%s=false", argVerbose),
+			fmt.Sprintf("--%s=json", argLogFormat),
+			fmt.Sprintf("--%s=warn", argLogLevel),
 			fmt.Sprintf("--%s=0", argStartDelay),
 			fmt.Sprintf("--%s=false", argNotifications),
+			fmt.Sprintf("--%s=krb5-auth-dialog", argKinitCommand),
+			fmt.Sprintf("--%s=1", argWakeDelay),
+			fmt.Sprintf("--%s=logout-on-lock", argSessionPolicy),
+			fmt.Sprintf("--%s=30", argLockGrace),
+			fmt.Sprintf("--%s=127.0.0.1:9101", argMetricsAddr),
+			fmt.Sprintf("--%s=%s", argMetricsDir, dir),
 		}
 
 		cfg, err := getConfig(args)
@@ -150,21 +172,229 @@ func TestGetConfig(t *testing.T) {
 			t.Errorf("should get valid config: cfg %v, err %v", cfg, err)
 		}
 	})
+
+	t.Run("signed config", func(t *testing.T) {
+		oldPublicKey := config.PublicKey
+		defer func() { config.PublicKey = oldPublicKey }()
+
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.PublicKey = base64.StdEncoding.EncodeToString(pub)
+
+		dir := t.TempDir()
+		conf := filepath.Join(dir, "config.json")
+		content := []byte(`{"ServiceURL": "https://myservice.mycompany.com:443", ` +
+			`"Realm": "MYCOMPANY.COM", ` +
+			`"TND": {"HTTPSServers": [{"URL": "https://tnd.mycompany.com", "Hash": "abcdef"}]}}`)
+		if err := os.WriteFile(conf, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(conf+config.SignatureExtension, ed25519.Sign(priv, content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// test missing config file
+		args := []string{"test",
+			fmt.Sprintf("--%s=example", argServiceURL),
+			fmt.Sprintf("--%s=example", argRealm),
+			fmt.Sprintf("--%s=example:abcdef", argTNDServers),
+		}
+		if _, err := getConfig(args); err == nil {
+			t.Error("missing signed config file should fail")
+		}
+
+		// test overriding signed settings
+		for _, arg := range []string{
+			fmt.Sprintf("--%s=example", argServiceURL),
+			fmt.Sprintf("--%s=example", argRealm),
+			fmt.Sprintf("--%s=example:abcdef", argTNDServers),
+		} {
+			args := []string{"test", fmt.Sprintf("--%s=%s", argConfig, conf), arg}
+			if _, err := getConfig(args); err == nil {
+				t.Errorf("overriding signed config with %s should fail", arg)
+			}
+		}
+
+		// test other arguments
+		args = []string{"test",
+			fmt.Sprintf("--%s=%s", argConfig, conf),
+			fmt.Sprintf("--%s=5", argWakeDelay),
+		}
+		cfg, err := getConfig(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Signature() != config.SignatureValid || cfg.WakeDelay != 5 {
+			t.Errorf("got %s, %d, want %s, 5", cfg.Signature(), cfg.WakeDelay,
+				config.SignatureValid)
+		}
+	})
 }
 
-// TestSetVerbose tests setVerbose.
-func TestSetVerbose(t *testing.T) {
+// TestCommand tests Command.
+func TestCommand(t *testing.T) {
+	c := Command()
+	b := &bytes.Buffer{}
+	if err := c.ManPage(b, 1, "test", "2024-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`.TH "FW-ID-AGENT"`, `\fB\-serviceurl\fR`, `\fIfile\fR`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("man page should contain %s: %s", want, b)
+		}
+	}
+	b.Reset()
+	if err := c.Completion(b, "bash"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "keep logout-on-lock logout-on-inactive") {
+		t.Errorf("completion should contain session policies: %s", b)
+	}
+}
+
+// TestSetLogging tests setLogging.
+func TestSetLogging(t *testing.T) {
+	defer setLogging(config.Default())
+
 	// test normal output
 	cfg := config.Default()
-	setVerbose(cfg)
+	setLogging(cfg)
 	if log.GetLevel() != log.InfoLevel {
 		t.Error("log level should be info")
 	}
 
 	// test verbose output
 	cfg.Verbose = true
-	setVerbose(cfg)
+	setLogging(cfg)
 	if log.GetLevel() != log.DebugLevel {
 		t.Error("log level should be debug")
 	}
+
+	// test log level, overrides verbose
+	cfg.LogLevel = "warn"
+	setLogging(cfg)
+	if log.GetLevel() != log.WarnLevel {
+		t.Error("log level should be warn")
+	}
+
+	// test json output
+	cfg.LogFormat = config.LogFormatJSON
+	setLogging(cfg)
+	if _, ok := log.StandardLogger().Formatter.(*log.JSONFormatter); !ok {
+		t.Error("log format should be json")
+	}
+}
+
+// TestReloadConfig tests reloadConfig.
+func TestReloadConfig(t *testing.T) {
+	a := NewAgent(config.Default())
+
+	// test invalid config, should keep current config
+	close(a.closed)
+	reloadConfig(a, []string{"test", "-this-argument-does-not-exist"})
+	select {
+	case <-a.reload:
+		t.Error("invalid config should not be reloaded")
+	default:
+	}
+
+	// test valid config
+	a = NewAgent(config.Default())
+	args := []string{"test",
+		fmt.Sprintf("--%s=example", argServiceURL),
+		fmt.Sprintf("--%s=example", argRealm),
+		fmt.Sprintf("--%s=example:abcdef", argTNDServers),
+		fmt.Sprintf("--%s=5", argWakeDelay),
+	}
+	go reloadConfig(a, args)
+	cfg := <-a.reload
+	if cfg.WakeDelay != 5 {
+		t.Errorf("got %d, want 5", cfg.WakeDelay)
+	}
+}
+
+// TestHandleSignals tests handleSignals.
+func TestHandleSignals(t *testing.T) {
+	a := NewAgent(config.Default())
+	c := make(chan os.Signal, 1)
+	errs := make(chan error)
+	args := []string{"test",
+		fmt.Sprintf("--%s=example", argServiceURL),
+		fmt.Sprintf("--%s=example", argRealm),
+		fmt.Sprintf("--%s=example:abcdef", argTNDServers),
+	}
+	go func() {
+		errs <- handleSignals(a, args, c)
+	}()
+
+	// test hangup signal, should reload config
+	c <- syscall.SIGHUP
+	<-a.reload
+
+	// test user signals, should request re-login and state dump
+	c <- syscall.SIGUSR1
+	<-a.relogin
+	c <- syscall.SIGUSR2
+	<-a.dump
+
+	// test terminate signals
+	c <- syscall.SIGTERM
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+	go func() {
+		errs <- handleSignals(a, args, c)
+	}()
+	c <- os.Interrupt
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+
+	// test agent error
+	go func() {
+		errs <- handleSignals(a, args, c)
+	}()
+	a.errors <- errors.New("test error")
+	if err := <-errs; err == nil {
+		t.Error("agent error should be returned")
+	}
+}
+
+// TestWaitStartDelay tests waitStartDelay.
+func TestWaitStartDelay(t *testing.T) {
+	args := []string{"test",
+		fmt.Sprintf("--%s=example", argServiceURL),
+		fmt.Sprintf("--%s=example", argRealm),
+		fmt.Sprintf("--%s=example:abcdef", argTNDServers),
+		fmt.Sprintf("--%s=5", argWakeDelay),
+	}
+
+	// test without signals, should return after delay
+	cfg := config.Default()
+	c := make(chan os.Signal, 1)
+	if got, ok := waitStartDelay(cfg, args, c); !ok || got != cfg {
+		t.Errorf("got %p, %t, want %p, true", got, ok, cfg)
+	}
+
+	// test hangup and user signals, should reload config and keep waiting,
+	// then terminate signal, should return false
+	cfg.StartDelay = 3600
+	type result struct {
+		cfg *config.Config
+		ok  bool
+	}
+	results := make(chan result)
+	go func() {
+		cfg, ok := waitStartDelay(cfg, args, c)
+		results <- result{cfg, ok}
+	}()
+	c <- syscall.SIGHUP
+	c <- syscall.SIGUSR1
+	c <- syscall.SIGTERM
+	r := <-results
+	if r.ok || r.cfg.WakeDelay != 5 {
+		t.Errorf("got %d, %t, want 5, false", r.cfg.WakeDelay, r.ok)
+	}
 }
@@ -0,0 +1,252 @@
+package agent
+
+import (
+	"errors"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// session and user interfaces, methods, properties, signals.
+	sessionIface      = dest + ".Session"
+	userIface         = dest + ".User"
+	getSession        = iface + ".GetSession"
+	getUser           = iface + ".GetUser"
+	propLockedHint    = sessionIface + ".LockedHint"
+	propActive        = sessionIface + ".Active"
+	propDisplay       = userIface + ".Display"
+	propertiesIface   = "org.freedesktop.DBus.Properties"
+	propertiesChanged = propertiesIface + ".PropertiesChanged"
+)
+
+// SessionEvent is a session lock or activation change event.
+type SessionEvent struct {
+	Locked bool
+	Active bool
+}
+
+// SessionMon is a monitor for the lock and activation state of the user's
+// login session.
+type SessionMon struct {
+	conn    *dbus.Conn
+	path    dbus.ObjectPath
+	signals chan *dbus.Signal
+	events  chan *SessionEvent
+	done    chan struct{}
+	closed  chan struct{}
+
+	// current lock and activation state of the session
+	locked bool
+	active bool
+}
+
+// sendEvent sends the current session state over the event channel.
+func (s *SessionMon) sendEvent() {
+	e := &SessionEvent{
+		Locked: s.locked,
+		Active: s.active,
+	}
+	select {
+	case s.events <- e:
+	case <-s.done:
+	}
+}
+
+// handleSignal handles signal.
+func (s *SessionMon) handleSignal(signal *dbus.Signal) {
+	log.WithField("signal", signal).Debug("SessionMon got signal")
+	if signal.Path != s.path || signal.Name != propertiesChanged {
+		return
+	}
+
+	// check properties changed signal
+	if len(signal.Body) < 2 {
+		log.Error("SessionMon got invalid properties changed signal")
+		return
+	}
+	if i, ok := signal.Body[0].(string); !ok || i != sessionIface {
+		return
+	}
+	changed, ok := signal.Body[1].(map[string]dbus.Variant)
+	if !ok {
+		log.Error("SessionMon could not parse properties changed signal")
+		return
+	}
+
+	// get changed lock and activation state
+	locked, active := s.locked, s.active
+	if v, ok := changed["LockedHint"]; ok {
+		if err := v.Store(&locked); err != nil {
+			log.WithError(err).Error("SessionMon could not parse LockedHint")
+			return
+		}
+	}
+	if v, ok := changed["Active"]; ok {
+		if err := v.Store(&active); err != nil {
+			log.WithError(err).Error("SessionMon could not parse Active")
+			return
+		}
+	}
+	if locked == s.locked && active == s.active {
+		// state not changed
+		return
+	}
+	log.WithFields(log.Fields{
+		"locked": locked,
+		"active": active,
+	}).Debug("SessionMon got session state change")
+
+	// send event
+	s.locked, s.active = locked, active
+	s.sendEvent()
+}
+
+// start starts the session monitor.
+func (s *SessionMon) start() {
+	defer close(s.closed)
+	defer close(s.events)
+	defer func() {
+		_ = s.conn.Close()
+	}()
+
+	// send initial session state
+	s.sendEvent()
+
+	// handle session signals
+	for {
+		select {
+		case sig, ok := <-s.signals:
+			if !ok {
+				log.Error("SessionMon got unexpected close of signals channel")
+				return
+			}
+			s.handleSignal(sig)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// findSession returns the object path of the user's login session on conn.
+// It uses the session in $XDG_SESSION_ID or the user's display session.
+var findSession = func(conn *dbus.Conn) (dbus.ObjectPath, error) {
+	manager := conn.Object(dest, path)
+
+	// get session from session id
+	if id := os.Getenv("XDG_SESSION_ID"); id != "" {
+		var p dbus.ObjectPath
+		err := manager.Call(getSession, 0, id).Store(&p)
+		return p, err
+	}
+
+	// get display session of user
+	var u dbus.ObjectPath
+	if err := manager.Call(getUser, 0, uint32(os.Getuid())).Store(&u); err != nil {
+		return "", err
+	}
+	v, err := conn.Object(dest, u).GetProperty(propDisplay)
+	if err != nil {
+		return "", err
+	}
+	display, ok := v.Value().([]any)
+	if !ok || len(display) != 2 {
+		return "", errors.New("invalid display session")
+	}
+	p, ok := display[1].(dbus.ObjectPath)
+	if !ok || p == "/" {
+		return "", errors.New("no display session")
+	}
+	return p, nil
+}
+
+// getSessionState returns the lock and activation state of the session in
+// path on conn.
+var getSessionState = func(conn *dbus.Conn, path dbus.ObjectPath) (locked, active bool, err error) {
+	session := conn.Object(dest, path)
+	v, err := session.GetProperty(propLockedHint)
+	if err != nil {
+		return
+	}
+	if err = v.Store(&locked); err != nil {
+		return
+	}
+	v, err = session.GetProperty(propActive)
+	if err != nil {
+		return
+	}
+	err = v.Store(&active)
+	return
+}
+
+// Start starts the session monitor.
+func (s *SessionMon) Start() error {
+	// connect to system bus
+	conn, err := dbusConnectSystemBus()
+	if err != nil {
+		log.WithError(err).Error("SessionMon could not connect to system bus")
+		return err
+	}
+	s.conn = conn
+
+	// find session
+	p, err := findSession(conn)
+	if err != nil {
+		log.WithError(err).Error("SessionMon could not find login session")
+		_ = conn.Close()
+		return err
+	}
+	s.path = p
+	log.WithField("session", p).Debug("SessionMon found login session")
+
+	// subscribe to session signals
+	if err = connAddMatchSignal(
+		conn,
+		dbus.WithMatchObjectPath(p),
+		dbus.WithMatchInterface(propertiesIface),
+		dbus.WithMatchMember("PropertiesChanged"),
+	); err != nil {
+		log.WithError(err).Error("SessionMon could not subscribe to session signals")
+		_ = conn.Close()
+		return err
+	}
+
+	// get initial session state
+	locked, active, err := getSessionState(conn, p)
+	if err != nil {
+		log.WithError(err).Error("SessionMon could not get session state")
+		_ = conn.Close()
+		return err
+	}
+	s.locked, s.active = locked, active
+
+	// set channel for signals
+	conn.Signal(s.signals)
+
+	go s.start()
+
+	return nil
+}
+
+// Stop stops the session monitor.
+func (s *SessionMon) Stop() {
+	close(s.done)
+	<-s.closed
+}
+
+// Events returns the session event channel.
+func (s *SessionMon) Events() chan *SessionEvent {
+	return s.events
+}
+
+// NewSessionMon returns a new session monitor.
+func NewSessionMon() *SessionMon {
+	return &SessionMon{
+		signals: make(chan *dbus.Signal, 10),
+		events:  make(chan *SessionEvent),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+		active:  true,
+	}
+}
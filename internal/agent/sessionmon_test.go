@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// testSessionSignal returns a properties changed signal for testing.
+func testSessionSignal(changed map[string]dbus.Variant) *dbus.Signal {
+	return &dbus.Signal{
+		Path: "/test/session",
+		Name: propertiesChanged,
+		Body: []any{sessionIface, changed, []string{}},
+	}
+}
+
+// TestSessionMonHandleSignal tests handleSignal of SessionMon.
+func TestSessionMonHandleSignal(t *testing.T) {
+	s := NewSessionMon()
+	s.path = "/test/session"
+
+	// test invalid signals, should not block
+	for _, signal := range []*dbus.Signal{
+		{},
+		{Path: "/other/session", Name: propertiesChanged},
+		{Path: "/test/session", Name: propertiesChanged},
+		{Path: "/test/session", Name: propertiesChanged, Body: []any{"other", nil}},
+		{Path: "/test/session", Name: propertiesChanged, Body: []any{sessionIface, nil}},
+		testSessionSignal(map[string]dbus.Variant{"LockedHint": dbus.MakeVariant("invalid")}),
+		testSessionSignal(map[string]dbus.Variant{"Active": dbus.MakeVariant("invalid")}),
+		testSessionSignal(map[string]dbus.Variant{"Other": dbus.MakeVariant(true)}),
+		testSessionSignal(map[string]dbus.Variant{"Active": dbus.MakeVariant(true)}),
+	} {
+		s.handleSignal(signal)
+	}
+
+	// test valid signals
+	for _, test := range []struct {
+		changed map[string]dbus.Variant
+		want    *SessionEvent
+	}{
+		{
+			changed: map[string]dbus.Variant{"LockedHint": dbus.MakeVariant(true)},
+			want:    &SessionEvent{Locked: true, Active: true},
+		},
+		{
+			changed: map[string]dbus.Variant{"Active": dbus.MakeVariant(false)},
+			want:    &SessionEvent{Locked: true, Active: false},
+		},
+		{
+			changed: map[string]dbus.Variant{
+				"LockedHint": dbus.MakeVariant(false),
+				"Active":     dbus.MakeVariant(true),
+			},
+			want: &SessionEvent{Locked: false, Active: true},
+		},
+	} {
+		go s.handleSignal(testSessionSignal(test.changed))
+		got := <-s.Events()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("got %v, want %v", got, test.want)
+		}
+	}
+}
+
+// TestSessionMonStartSignalsChan tests start of SessionMon, signals channel.
+func TestSessionMonStartSignalsChan(t *testing.T) {
+	// start session monitor
+	s := NewSessionMon()
+	s.path = "/test/session"
+	conn, err := dbus.NewConn(&testRWC{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.conn = conn
+	go s.start()
+
+	// initial event
+	want := &SessionEvent{Active: true}
+	if got := <-s.events; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// send signal
+	s.signals <- testSessionSignal(map[string]dbus.Variant{
+		"LockedHint": dbus.MakeVariant(true),
+	})
+	want = &SessionEvent{Locked: true, Active: true}
+	if got := <-s.events; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// unexpected close of signals channel should result in stop
+	close(s.signals)
+	<-s.events
+}
+
+// TestSessionMonStartStop tests Start and Stop of SessionMon.
+func TestSessionMonStartStop(t *testing.T) {
+	oldConnectSystemBus := dbusConnectSystemBus
+	oldFindSession := findSession
+	oldAddMatchSignal := connAddMatchSignal
+	oldGetSessionState := getSessionState
+	defer func() {
+		dbusConnectSystemBus = oldConnectSystemBus
+		findSession = oldFindSession
+		connAddMatchSignal = oldAddMatchSignal
+		getSessionState = oldGetSessionState
+	}()
+	dbusConnectSystemBus = func(...dbus.ConnOption) (*dbus.Conn, error) {
+		return dbus.NewConn(&testRWC{})
+	}
+	findSession = func(*dbus.Conn) (dbus.ObjectPath, error) {
+		return "/test/session", nil
+	}
+	connAddMatchSignal = func(*dbus.Conn, ...dbus.MatchOption) error {
+		return nil
+	}
+
+	// test errors
+	getSessionState = func(*dbus.Conn, dbus.ObjectPath) (bool, bool, error) {
+		return false, false, errors.New("test error")
+	}
+	if err := NewSessionMon().Start(); err == nil {
+		t.Error("start should fail with session state error")
+	}
+	connAddMatchSignal = func(*dbus.Conn, ...dbus.MatchOption) error {
+		return errors.New("test error")
+	}
+	if err := NewSessionMon().Start(); err == nil {
+		t.Error("start should fail with match signal error")
+	}
+	findSession = func(*dbus.Conn) (dbus.ObjectPath, error) {
+		return "", errors.New("test error")
+	}
+	if err := NewSessionMon().Start(); err == nil {
+		t.Error("start should fail with find session error")
+	}
+	dbusConnectSystemBus = func(...dbus.ConnOption) (*dbus.Conn, error) {
+		return nil, errors.New("test error")
+	}
+	if err := NewSessionMon().Start(); err == nil {
+		t.Error("start should fail with systembus error")
+	}
+
+	// test without errors
+	dbusConnectSystemBus = func(...dbus.ConnOption) (*dbus.Conn, error) {
+		return dbus.NewConn(&testRWC{})
+	}
+	findSession = func(*dbus.Conn) (dbus.ObjectPath, error) {
+		return "/test/session", nil
+	}
+	connAddMatchSignal = func(*dbus.Conn, ...dbus.MatchOption) error {
+		return nil
+	}
+	getSessionState = func(*dbus.Conn, dbus.ObjectPath) (bool, bool, error) {
+		return true, false, nil
+	}
+	s := NewSessionMon()
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	want := &SessionEvent{Locked: true, Active: false}
+	if got := <-s.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	s.Stop()
+}
+
+// TestSessionMonEvents tests Events of SessionMon.
+func TestSessionMonEvents(t *testing.T) {
+	s := NewSessionMon()
+	got := s.Events()
+	want := s.events
+	if got != want {
+		t.Errorf("got %p, want %p", got, want)
+	}
+}
+
+// TestNewSessionMon tests NewSessionMon.
+func TestNewSessionMon(t *testing.T) {
+	s := NewSessionMon()
+	if s == nil ||
+		s.signals == nil ||
+		s.events == nil ||
+		s.done == nil ||
+		s.closed == nil ||
+		s.locked ||
+		!s.active {
+
+		t.Errorf("invalid session monitor: %v", s)
+	}
+}
@@ -1,6 +1,9 @@
 package agent
 
 import (
+	"sync"
+	"syscall"
+
 	"github.com/godbus/dbus/v5"
 	log "github.com/sirupsen/logrus"
 )
@@ -11,6 +14,15 @@ const (
 	dest            = "org.freedesktop.login1"
 	iface           = dest + ".Manager"
 	prepareForSleep = iface + ".PrepareForSleep"
+	inhibit         = iface + ".Inhibit"
+)
+
+// inhibitor settings.
+const (
+	inhibitWhat = "sleep"
+	inhibitWho  = "FW-ID-Agent"
+	inhibitWhy  = "Logging out of Firewall Identity Service"
+	inhibitMode = "delay"
 )
 
 // SleepMon is a suspend/hibernate monitor.
@@ -20,6 +32,60 @@ type SleepMon struct {
 	events  chan bool
 	done    chan struct{}
 	closed  chan struct{}
+
+	// inhibitor is the file descriptor of the delay inhibitor lock,
+	// -1 if no lock is held
+	mutex     sync.Mutex
+	inhibitor int
+}
+
+// connInhibit takes a delay inhibitor lock on conn and returns its file
+// descriptor, for testing.
+var connInhibit = func(conn *dbus.Conn) (int, error) {
+	var fd dbus.UnixFD
+	err := conn.Object(dest, path).
+		Call(inhibit, 0, inhibitWhat, inhibitWho, inhibitWhy, inhibitMode).
+		Store(&fd)
+	return int(fd), err
+}
+
+// closeFD is syscall.Close for testing.
+var closeFD = syscall.Close
+
+// acquire takes the delay inhibitor lock if it is not already held, so
+// suspend is delayed until the lock is released.
+func (s *SleepMon) acquire() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.inhibitor >= 0 {
+		// lock already held
+		return
+	}
+	fd, err := connInhibit(s.conn)
+	if err != nil {
+		log.WithError(err).Error("SleepMon could not take delay inhibitor lock")
+		return
+	}
+	log.Debug("SleepMon took delay inhibitor lock")
+	s.inhibitor = fd
+}
+
+// Release releases the delay inhibitor lock, if it is held, so suspend can
+// continue.
+func (s *SleepMon) Release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.inhibitor < 0 {
+		// lock not held
+		return
+	}
+	if err := closeFD(s.inhibitor); err != nil {
+		log.WithError(err).Error("SleepMon could not release delay inhibitor lock")
+	}
+	log.Debug("SleepMon released delay inhibitor lock")
+	s.inhibitor = -1
 }
 
 // sendEvent sends sleep over the event channel.
@@ -49,6 +115,12 @@ func (s *SleepMon) handleSignal(signal *dbus.Signal) {
 		}
 		log.WithField("sleep", sleep).Debug("SleepMon got prepare for sleep signal")
 
+		// re-acquire inhibitor lock on resume, on suspend the lock is
+		// released by the user after handling the event
+		if !sleep {
+			s.acquire()
+		}
+
 		// send event
 		s.sendEvent(sleep)
 	}
@@ -62,6 +134,7 @@ func (s *SleepMon) start() {
 	defer func() {
 		_ = s.conn.Close()
 	}()
+	defer s.Release()
 
 	// handle login signals
 	for {
@@ -109,6 +182,10 @@ func (s *SleepMon) Start() error {
 	// set channel for signals
 	conn.Signal(s.signals)
 
+	// take inhibitor lock, failure is not fatal, suspend just will not be
+	// delayed until logout
+	s.acquire()
+
 	go s.start()
 
 	return nil
@@ -132,5 +209,7 @@ func NewSleepMon() *SleepMon {
 		events:  make(chan bool),
 		done:    make(chan struct{}),
 		closed:  make(chan struct{}),
+
+		inhibitor: -1,
 	}
 }
@@ -7,8 +7,78 @@ import (
 	"github.com/godbus/dbus/v5"
 )
 
+// TestSleepMonAcquireRelease tests acquire and Release of SleepMon.
+func TestSleepMonAcquireRelease(t *testing.T) {
+	oldInhibit := connInhibit
+	oldCloseFD := closeFD
+	defer func() {
+		connInhibit = oldInhibit
+		closeFD = oldCloseFD
+	}()
+	inhibits := 0
+	closed := []int{}
+	closeFD = func(fd int) error {
+		closed = append(closed, fd)
+		return nil
+	}
+
+	// test inhibit error
+	connInhibit = func(*dbus.Conn) (int, error) {
+		inhibits++
+		return -1, errors.New("test error")
+	}
+	s := NewSleepMon()
+	s.acquire()
+	if s.inhibitor != -1 {
+		t.Errorf("got %d, want -1", s.inhibitor)
+	}
+
+	// test release without lock
+	s.Release()
+	if len(closed) != 0 {
+		t.Errorf("release without lock should not close: %v", closed)
+	}
+
+	// test acquire
+	inhibits = 0
+	connInhibit = func(*dbus.Conn) (int, error) {
+		inhibits++
+		return 42, nil
+	}
+	s.acquire()
+	s.acquire()
+	if s.inhibitor != 42 || inhibits != 1 {
+		t.Errorf("got %d, %d inhibits, want 42, 1 inhibit", s.inhibitor, inhibits)
+	}
+
+	// test release
+	s.Release()
+	s.Release()
+	if s.inhibitor != -1 || len(closed) != 1 || closed[0] != 42 {
+		t.Errorf("got %d, closed %v, want -1, closed [42]", s.inhibitor, closed)
+	}
+
+	// test release with close error
+	s.acquire()
+	closeFD = func(int) error {
+		return errors.New("test error")
+	}
+	s.Release()
+	if s.inhibitor != -1 {
+		t.Errorf("got %d, want -1", s.inhibitor)
+	}
+}
+
 // TestSleepMonHandleSignal tests handleSignal of SleepMon.
 func TestSleepMonHandleSignal(t *testing.T) {
+	oldInhibit := connInhibit
+	defer func() { connInhibit = oldInhibit }()
+	inhibits := 0
+	connInhibit = func(*dbus.Conn) (int, error) {
+		inhibits++
+		return 42, nil
+	}
+
 	s := NewSleepMon()
 
 	// test invalid signals, should not block
@@ -32,6 +102,11 @@ func TestSleepMonHandleSignal(t *testing.T) {
 			t.Errorf("got %t, want %t", got, want)
 		}
 	}
+
+	// resume should re-acquire inhibitor lock
+	if inhibits != 1 || s.inhibitor != 42 {
+		t.Errorf("got %d inhibits, lock %d, want 1, 42", inhibits, s.inhibitor)
+	}
 }
 
 // testRWC is a reader writer closer for testing.
@@ -120,7 +195,8 @@ func TestNewSleepMon(t *testing.T) {
 		s.signals == nil ||
 		s.events == nil ||
 		s.done == nil ||
-		s.closed == nil {
+		s.closed == nil ||
+		s.inhibitor != -1 {
 
 		t.Errorf("got nil, want != nil")
 	}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/fw-id-agent/internal/dbusapi"
+	"github.com/telekom-mms/fw-id-agent/pkg/history"
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// transitions is the transition table of the agent state machine. It maps
+// each state to the states it can change to, all other transitions are
+// illegal.
+var transitions = map[status.AgentState][]status.AgentState{
+	status.AgentStateWaitingForTicket: {
+		status.AgentStateUntrusted,
+		status.AgentStateTrustedLoggingIn,
+		status.AgentStatePaused,
+		status.AgentStateSleeping,
+	},
+	status.AgentStateUntrusted: {
+		status.AgentStateWaitingForTicket,
+		status.AgentStateTrustedLoggingIn,
+		status.AgentStatePaused,
+		status.AgentStateSleeping,
+	},
+	status.AgentStateTrustedLoggingIn: {
+		status.AgentStateWaitingForTicket,
+		status.AgentStateUntrusted,
+		status.AgentStateLoggedIn,
+		status.AgentStatePaused,
+		status.AgentStateSleeping,
+	},
+	status.AgentStateLoggedIn: {
+		status.AgentStateWaitingForTicket,
+		status.AgentStateUntrusted,
+		status.AgentStateTrustedLoggingIn,
+		status.AgentStatePaused,
+		status.AgentStateSleeping,
+	},
+	status.AgentStatePaused: {
+		status.AgentStateWaitingForTicket,
+		status.AgentStateUntrusted,
+		status.AgentStateTrustedLoggingIn,
+		status.AgentStateSleeping,
+	},
+	status.AgentStateSleeping: {
+		status.AgentStateWaitingForTicket,
+		status.AgentStateUntrusted,
+		status.AgentStateTrustedLoggingIn,
+		status.AgentStateLoggedIn,
+		status.AgentStatePaused,
+	},
+}
+
+// validTransition returns whether the agent state can change from state from
+// to state to.
+func validTransition(from, to status.AgentState) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// currentAgentState returns the agent state derived from the trusted network
+// status, login state, kerberos ticket and config, and the paused and
+// sleeping flags.
+func (a *Agent) currentAgentState() status.AgentState {
+	switch {
+	case a.sleeping:
+		return status.AgentStateSleeping
+	case a.paused || a.userPaused:
+		return status.AgentStatePaused
+	case a.ccacheUp == nil || a.ccacheUp.CCache == nil ||
+		a.krbcfgUp == nil || a.krbcfgUp.Config == nil:
+		return status.AgentStateWaitingForTicket
+	case !a.trustedNetwork.Trusted():
+		return status.AgentStateUntrusted
+	case a.loginState.LoggedIn():
+		return status.AgentStateLoggedIn
+	default:
+		return status.AgentStateTrustedLoggingIn
+	}
+}
+
+// loginAllowed returns whether the trusted network status, kerberos ticket and
+// config, and the paused and sleeping flags allow the agent to be logged in.
+func (a *Agent) loginAllowed() bool {
+	switch a.currentAgentState() {
+	case status.AgentStateTrustedLoggingIn, status.AgentStateLoggedIn:
+		return true
+	}
+	return false
+}
+
+// handleAgentStateChange handles a change of the agent state.
+func (a *Agent) handleAgentStateChange() {
+	log.WithField("agent_state", a.agentState).
+		Info("Agent state changed")
+	a.history.Add(history.NewEvent(history.TypeState, "Agent state changed").
+		WithDetail("AgentState", a.agentState.String()))
+	a.dbus.SetProperty(dbusapi.PropertyAgentState, a.agentState)
+}
+
+// setAgentState sets the agent state to state. Illegal transitions are
+// rejected and return an error.
+func (a *Agent) setAgentState(state status.AgentState) error {
+	if state == a.agentState {
+		// state not changed
+		return nil
+	}
+
+	// check transition
+	if !validTransition(a.agentState, state) {
+		return fmt.Errorf("illegal agent state transition from %s to %s",
+			a.agentState, state)
+	}
+
+	// state changed
+	a.agentState = state
+	a.handleAgentStateChange()
+	return nil
+}
+
+// updateAgentState updates the agent state from the current trusted network
+// status, login state, kerberos ticket and config, and paused and sleeping
+// flags. The agent state is always set to the derived state, so it never
+// contradicts the other published properties. An illegal transition is a bug
+// in the agent and only logged.
+func (a *Agent) updateAgentState() {
+	state := a.currentAgentState()
+	if err := a.setAgentState(state); err != nil {
+		log.WithError(err).Error("Agent got illegal state change, this is a bug, setting derived state")
+		a.agentState = state
+		a.handleAgentStateChange()
+	}
+}
@@ -0,0 +1,293 @@
+package agent
+
+import (
+	"testing"
+
+	krbconfig "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/telekom-mms/fw-id-agent/internal/client"
+	"github.com/telekom-mms/fw-id-agent/internal/dbusapi"
+	"github.com/telekom-mms/fw-id-agent/internal/krbmon"
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// agentStates are all agent states for testing.
+var agentStates = []status.AgentState{
+	status.AgentStateUnknown,
+	status.AgentStateWaitingForTicket,
+	status.AgentStateUntrusted,
+	status.AgentStateTrustedLoggingIn,
+	status.AgentStateLoggedIn,
+	status.AgentStatePaused,
+	status.AgentStateSleeping,
+}
+
+// TestValidTransition tests validTransition.
+func TestValidTransition(t *testing.T) {
+	const (
+		waiting   = status.AgentStateWaitingForTicket
+		untrusted = status.AgentStateUntrusted
+		loggingIn = status.AgentStateTrustedLoggingIn
+		loggedIn  = status.AgentStateLoggedIn
+		paused    = status.AgentStatePaused
+		sleeping  = status.AgentStateSleeping
+	)
+
+	// valid transitions, all others are illegal
+	valid := map[[2]status.AgentState]bool{
+		{waiting, untrusted}: true,
+		{waiting, loggingIn}: true,
+		{waiting, paused}:    true,
+		{waiting, sleeping}:  true,
+
+		{untrusted, waiting}:   true,
+		{untrusted, loggingIn}: true,
+		{untrusted, paused}:    true,
+		{untrusted, sleeping}:  true,
+
+		{loggingIn, waiting}:   true,
+		{loggingIn, untrusted}: true,
+		{loggingIn, loggedIn}:  true,
+		{loggingIn, paused}:    true,
+		{loggingIn, sleeping}:  true,
+
+		{loggedIn, waiting}:   true,
+		{loggedIn, untrusted}: true,
+		{loggedIn, loggingIn}: true,
+		{loggedIn, paused}:    true,
+		{loggedIn, sleeping}:  true,
+
+		{paused, waiting}:   true,
+		{paused, untrusted}: true,
+		{paused, loggingIn}: true,
+		{paused, sleeping}:  true,
+
+		{sleeping, waiting}:   true,
+		{sleeping, untrusted}: true,
+		{sleeping, loggingIn}: true,
+		{sleeping, loggedIn}:  true,
+		{sleeping, paused}:    true,
+	}
+
+	for _, from := range agentStates {
+		for _, to := range agentStates {
+			want := valid[[2]status.AgentState{from, to}]
+			if got := validTransition(from, to); got != want {
+				t.Errorf("transition from %s to %s: got %t, want %t",
+					from, to, got, want)
+			}
+		}
+	}
+}
+
+// TestAgentSetAgentState tests setAgentState of Agent.
+func TestAgentSetAgentState(t *testing.T) {
+	for _, from := range agentStates {
+		for _, to := range agentStates {
+			a := NewAgent(config.Default())
+			a.dbus = &nopDBusService{}
+			a.agentState = from
+
+			err := a.setAgentState(to)
+			switch {
+			case from == to:
+				// no change
+				if err != nil || a.agentState != from {
+					t.Errorf("%s to %s: got %s, %v, want no change",
+						from, to, a.agentState, err)
+				}
+			case validTransition(from, to):
+				// valid transition
+				if err != nil || a.agentState != to {
+					t.Errorf("%s to %s: got %s, %v, want %s",
+						from, to, a.agentState, err, to)
+				}
+			default:
+				// illegal transition, rejected
+				if err == nil || a.agentState != from {
+					t.Errorf("%s to %s: got %s, %v, want rejected",
+						from, to, a.agentState, err)
+				}
+			}
+		}
+	}
+}
+
+// TestAgentCurrentAgentState tests currentAgentState of Agent.
+func TestAgentCurrentAgentState(t *testing.T) {
+	ccache := &krbmon.CCacheUpdate{CCache: &credentials.CCache{}}
+	krbcfg := &krbmon.ConfUpdate{Config: krbconfig.New()}
+
+	for i, test := range []struct {
+		sleeping bool
+		paused   bool
+		user     bool
+		ccache   *krbmon.CCacheUpdate
+		krbcfg   *krbmon.ConfUpdate
+		trusted  status.TrustedNetwork
+		login    status.LoginState
+		want     status.AgentState
+	}{
+		{want: status.AgentStateWaitingForTicket},
+		{ccache: ccache, want: status.AgentStateWaitingForTicket},
+		{krbcfg: krbcfg, want: status.AgentStateWaitingForTicket},
+		{ccache: &krbmon.CCacheUpdate{}, krbcfg: krbcfg,
+			want: status.AgentStateWaitingForTicket},
+		{ccache: ccache, krbcfg: &krbmon.ConfUpdate{},
+			want: status.AgentStateWaitingForTicket},
+		{ccache: ccache, krbcfg: krbcfg, trusted: status.TrustedNetworkTrusted,
+			want: status.AgentStateTrustedLoggingIn},
+		{ccache: ccache, krbcfg: krbcfg, trusted: status.TrustedNetworkNotTrusted,
+			want: status.AgentStateUntrusted},
+		{ccache: ccache, krbcfg: krbcfg, trusted: status.TrustedNetworkNotTrusted,
+			login: status.LoginStateLoggedIn, want: status.AgentStateUntrusted},
+		{ccache: ccache, krbcfg: krbcfg, trusted: status.TrustedNetworkTrusted,
+			login: status.LoginStateLoggingIn, want: status.AgentStateTrustedLoggingIn},
+		{ccache: ccache, krbcfg: krbcfg, trusted: status.TrustedNetworkTrusted,
+			login: status.LoginStateLoggedIn, want: status.AgentStateLoggedIn},
+		{paused: true, ccache: ccache, krbcfg: krbcfg, trusted: status.TrustedNetworkTrusted,
+			login: status.LoginStateLoggedIn, want: status.AgentStatePaused},
+		{user: true, ccache: ccache, krbcfg: krbcfg, trusted: status.TrustedNetworkTrusted,
+			want: status.AgentStatePaused},
+		{sleeping: true, paused: true, want: status.AgentStateSleeping},
+	} {
+		a := NewAgent(config.Default())
+		a.sleeping = test.sleeping
+		a.paused = test.paused
+		a.userPaused = test.user
+		a.ccacheUp = test.ccache
+		a.krbcfgUp = test.krbcfg
+		a.trustedNetwork = test.trusted
+		a.loginState = test.login
+
+		if got := a.currentAgentState(); got != test.want {
+			t.Errorf("test %d: got %s, want %s", i, got, test.want)
+		}
+	}
+}
+
+// TestAgentUpdateAgentState tests updateAgentState of Agent.
+func TestAgentUpdateAgentState(t *testing.T) {
+	a := NewAgent(config.Default())
+	a.dbus = &nopDBusService{}
+
+	// test initial state
+	a.updateAgentState()
+	if a.agentState != status.AgentStateWaitingForTicket {
+		t.Errorf("got %s, want %s", a.agentState, status.AgentStateWaitingForTicket)
+	}
+
+	// test ticket and config available, trusted network, logged in
+	a.ccacheUp = &krbmon.CCacheUpdate{CCache: &credentials.CCache{}}
+	a.krbcfgUp = &krbmon.ConfUpdate{Config: krbconfig.New()}
+	a.setTrustedNetwork(true)
+	if a.agentState != status.AgentStateTrustedLoggingIn {
+		t.Errorf("got %s, want %s", a.agentState, status.AgentStateTrustedLoggingIn)
+	}
+	a.setLoginState(status.LoginStateLoggedIn)
+	if a.agentState != status.AgentStateLoggedIn {
+		t.Errorf("got %s, want %s", a.agentState, status.AgentStateLoggedIn)
+	}
+
+	// test sleep and wake-up
+	a.handleSleepEvent(true)
+	if a.agentState != status.AgentStateSleeping {
+		t.Errorf("got %s, want %s", a.agentState, status.AgentStateSleeping)
+	}
+	a.handleSleepEvent(false)
+	if a.agentState != status.AgentStateUntrusted {
+		t.Errorf("got %s, want %s", a.agentState, status.AgentStateUntrusted)
+	}
+
+	// test login result while untrusted is rejected
+	a.setLoginState(status.LoginStateLoggedOut)
+	a.handleLoginResult(&client.Result{LoginState: status.LoginStateLoggedIn})
+	if a.loginState != status.LoginStateLoggedOut ||
+		a.agentState != status.AgentStateUntrusted {
+		t.Errorf("got %s, %s, want %s, %s", a.loginState, a.agentState,
+			status.LoginStateLoggedOut, status.AgentStateUntrusted)
+	}
+
+	// test illegal transition sets derived state, untrusted to logged in
+	a.trustedNetwork = status.TrustedNetworkTrusted
+	a.loginState = status.LoginStateLoggedIn
+	a.updateAgentState()
+	if a.agentState != status.AgentStateLoggedIn {
+		t.Errorf("got %s, want %s", a.agentState, status.AgentStateLoggedIn)
+	}
+
+	// test trusted network while sleeping, no login before wake-up
+	a.setLoginState(status.LoginStateLoggedOut)
+	a.handleSleepEvent(true)
+	a.handleTNDResult(true)
+	if a.client != nil {
+		t.Error("client should not be started while sleeping")
+	}
+	if a.agentState != status.AgentStateSleeping {
+		t.Errorf("got %s, want %s", a.agentState, status.AgentStateSleeping)
+	}
+	a.handleSleepEvent(false)
+	if a.agentState != status.AgentStateTrustedLoggingIn {
+		t.Errorf("got %s, want %s", a.agentState, status.AgentStateTrustedLoggingIn)
+	}
+}
+
+// propDBusService is a D-Bus Service that stores the properties for testing.
+type propDBusService struct {
+	nopDBusService
+	props map[string]any
+}
+
+func (p *propDBusService) SetProperty(name string, value any) { p.props[name] = value }
+
+// TestAgentStateProperties tests that the agent state, trusted network and
+// login state properties agree on inconsistent state changes.
+func TestAgentStateProperties(t *testing.T) {
+	d := &propDBusService{props: make(map[string]any)}
+	a := NewAgent(config.Default())
+	a.dbus = d
+
+	// check checks that the properties agree with the agent
+	check := func() {
+		t.Helper()
+		agentState := d.props[dbusapi.PropertyAgentState]
+		trusted := d.props[dbusapi.PropertyTrustedNetwork]
+		loginState := d.props[dbusapi.PropertyLoginState]
+		if agentState != a.currentAgentState() ||
+			trusted != a.trustedNetwork ||
+			loginState != a.loginState {
+			t.Fatalf("properties do not agree with agent: %v", d.props)
+		}
+		loggedIn := agentState == status.AgentStateLoggedIn
+		if loggedIn != (loginState == status.LoginStateLoggedIn &&
+			trusted == status.TrustedNetworkTrusted) {
+			t.Errorf("properties contradict each other: %v", d.props)
+		}
+	}
+
+	// untrusted, logged in by a stale client result
+	a.ccacheUp = &krbmon.CCacheUpdate{CCache: &credentials.CCache{}}
+	a.krbcfgUp = &krbmon.ConfUpdate{Config: krbconfig.New()}
+	a.setTrustedNetwork(false)
+	a.setLoginState(status.LoginStateLoggedOut)
+	a.handleLoginResult(&client.Result{LoginState: status.LoginStateLoggedIn})
+	check()
+
+	// logged in while untrusted, then trusted, illegal transition from
+	// untrusted to logged in
+	a.setLoginState(status.LoginStateLoggedIn)
+	check()
+	a.setTrustedNetwork(true)
+	check()
+
+	// paused, logged in by a stale client result
+	a.setLoginState(status.LoginStateLoggedOut)
+	a.paused = true
+	a.updateAgentState()
+	a.handleLoginResult(&client.Result{LoginState: status.LoginStateLoggedIn})
+	check()
+	if a.loginState != status.LoginStateLoggedOut {
+		t.Errorf("got %s, want %s", a.loginState, status.LoginStateLoggedOut)
+	}
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/telekom-mms/fw-id-agent/pkg/client"
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// Exit codes of the check command, see the monitoring plugins guidelines.
+const (
+	CheckOK       = 0
+	CheckWarning  = 1
+	CheckCritical = 2
+	CheckUnknown  = 3
+)
+
+// checkStates are the names of the check exit codes.
+var checkStates = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// checkThresholds are the warning and critical thresholds of check, 0
+// disables a threshold.
+type checkThresholds struct {
+	keepAliveWarn time.Duration
+	keepAliveCrit time.Duration
+	tgtWarn       time.Duration
+	tgtCrit       time.Duration
+	failuresWarn  uint
+	failuresCrit  uint
+}
+
+// thresholds are the check thresholds set on the command line.
+var thresholds = checkThresholds{
+	keepAliveWarn: 15 * time.Minute,
+	keepAliveCrit: 30 * time.Minute,
+	tgtWarn:       time.Hour,
+	tgtCrit:       10 * time.Minute,
+	failuresWarn:  1,
+	failuresCrit:  3,
+}
+
+// checkResult is the result of check.
+type checkResult struct {
+	code     int
+	messages []string
+	perfdata []string
+}
+
+// add adds message with exit code to the result. Messages of the worst
+// exit code come first.
+func (r *checkResult) add(code int, message string) {
+	switch {
+	case code > r.code:
+		r.code = code
+		r.messages = append([]string{message}, r.messages...)
+	default:
+		r.messages = append(r.messages, message)
+	}
+}
+
+// exceeds returns whether value exceeds threshold, a threshold of 0 is
+// never exceeded.
+func exceeds[T time.Duration | uint](value, threshold T) bool {
+	return threshold > 0 && value >= threshold
+}
+
+// below returns whether value is below threshold, a threshold of 0 is never
+// undercut.
+func below(value, threshold time.Duration) bool {
+	return threshold > 0 && value < threshold
+}
+
+// threshold returns the perfdata threshold of t in seconds, empty if t is 0.
+func threshold(t time.Duration, suffix string) string {
+	if t <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d%s", int64(t/time.Second), suffix)
+}
+
+// checkStatus checks status s at time now with thresholds t.
+func checkStatus(s *status.Status, now time.Time, t checkThresholds) *checkResult {
+	r := &checkResult{}
+
+	// keep-alive age, only meaningful on a trusted network
+	switch {
+	case !s.TrustedNetwork.Trusted():
+		r.add(CheckOK, "not connected to a trusted network")
+	case s.LastKeepAlive <= 0:
+		r.add(CheckCritical, fmt.Sprintf("%s, no keep-alive", s.LoginState))
+	default:
+		age := now.Sub(time.Unix(s.LastKeepAlive, 0)).Truncate(time.Second)
+		r.perfdata = append(r.perfdata, fmt.Sprintf("keepalive_age=%ds;%s;%s;0;",
+			int64(age/time.Second), threshold(t.keepAliveWarn, ""), threshold(t.keepAliveCrit, "")))
+		msg := fmt.Sprintf("%s, last keep-alive %s ago", s.LoginState, age)
+		switch {
+		case exceeds(age, t.keepAliveCrit):
+			r.add(CheckCritical, msg)
+		case exceeds(age, t.keepAliveWarn):
+			r.add(CheckWarning, msg)
+		default:
+			r.add(CheckOK, msg)
+		}
+	}
+
+	// kerberos tgt remaining lifetime
+	if s.KerberosTGT.EndTime <= 0 {
+		r.add(CheckCritical, "no kerberos TGT")
+	} else {
+		remaining := time.Unix(s.KerberosTGT.EndTime, 0).Sub(now).Truncate(time.Second)
+		r.perfdata = append(r.perfdata, fmt.Sprintf("tgt_remaining=%ds;%s;%s;0;",
+			int64(remaining/time.Second), threshold(t.tgtWarn, ":"), threshold(t.tgtCrit, ":")))
+		msg := fmt.Sprintf("TGT expires in %s", remaining)
+		if remaining <= 0 {
+			msg = "TGT expired"
+		}
+		switch {
+		case remaining <= 0, below(remaining, t.tgtCrit):
+			r.add(CheckCritical, msg)
+		case below(remaining, t.tgtWarn):
+			r.add(CheckWarning, msg)
+		default:
+			r.add(CheckOK, msg)
+		}
+	}
+
+	// consecutive login failures
+	failures := uint(s.LoginFailures)
+	perf := func(v uint) string {
+		if v == 0 {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+	r.perfdata = append(r.perfdata, fmt.Sprintf("login_failures=%d;%s;%s;0;",
+		failures, perf(t.failuresWarn), perf(t.failuresCrit)))
+	msg := fmt.Sprintf("%d consecutive login failures", failures)
+	switch {
+	case exceeds(failures, t.failuresCrit):
+		r.add(CheckCritical, msg)
+	case exceeds(failures, t.failuresWarn):
+		r.add(CheckWarning, msg)
+	default:
+		r.add(CheckOK, msg)
+	}
+
+	return r
+}
+
+// printCheckResult prints the check result r in monitoring plugin format.
+func printCheckResult(out io.Writer, r *checkResult) error {
+	line := fmt.Sprintf("FW-ID %s - %s", checkStates[r.code], strings.Join(r.messages, ", "))
+	if len(r.perfdata) > 0 {
+		line += " | " + strings.Join(r.perfdata, " ")
+	}
+	_, err := fmt.Fprintln(out, line)
+	return err
+}
+
+// check checks the agent status with the thresholds, prints the result in
+// monitoring plugin format and returns an exitError with the check exit
+// code.
+func check(out io.Writer, c client.Client) error {
+	s, err := c.Query()
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "FW-ID %s - agent not running: %v\n", checkStates[CheckUnknown], err)
+		return &exitError{code: CheckUnknown}
+	}
+
+	r := checkStatus(s, time.Now(), thresholds)
+	if err := printCheckResult(out, r); err != nil {
+		return err
+	}
+	if r.code != CheckOK {
+		return &exitError{code: r.code}
+	}
+	return nil
+}
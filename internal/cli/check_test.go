@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// TestCheckStatus tests checkStatus.
+func TestCheckStatus(t *testing.T) {
+	now := time.Unix(100000, 0)
+	healthy := func() *status.Status {
+		s := status.New()
+		s.TrustedNetwork = status.TrustedNetworkTrusted
+		s.LoginState = status.LoginStateLoggedIn
+		s.LastKeepAlive = now.Add(-time.Minute).Unix()
+		s.KerberosTGT.EndTime = now.Add(2 * time.Hour).Unix()
+		return s
+	}
+
+	for _, test := range []struct {
+		name   string
+		modify func(s *status.Status)
+		want   int
+	}{
+		{"healthy", func(*status.Status) {}, CheckOK},
+		{"untrusted", func(s *status.Status) {
+			s.TrustedNetwork = status.TrustedNetworkNotTrusted
+			s.LastKeepAlive = 0
+		}, CheckOK},
+		{"no keep-alive", func(s *status.Status) { s.LastKeepAlive = 0 }, CheckCritical},
+		{"keep-alive warning", func(s *status.Status) {
+			s.LastKeepAlive = now.Add(-20 * time.Minute).Unix()
+		}, CheckWarning},
+		{"keep-alive critical", func(s *status.Status) {
+			s.LastKeepAlive = now.Add(-time.Hour).Unix()
+		}, CheckCritical},
+		{"no tgt", func(s *status.Status) { s.KerberosTGT.EndTime = 0 }, CheckCritical},
+		{"tgt expired", func(s *status.Status) {
+			s.KerberosTGT.EndTime = now.Add(-time.Minute).Unix()
+		}, CheckCritical},
+		{"tgt warning", func(s *status.Status) {
+			s.KerberosTGT.EndTime = now.Add(30 * time.Minute).Unix()
+		}, CheckWarning},
+		{"tgt critical", func(s *status.Status) {
+			s.KerberosTGT.EndTime = now.Add(5 * time.Minute).Unix()
+		}, CheckCritical},
+		{"failures warning", func(s *status.Status) { s.LoginFailures = 1 }, CheckWarning},
+		{"failures critical", func(s *status.Status) { s.LoginFailures = 3 }, CheckCritical},
+	} {
+		s := healthy()
+		test.modify(s)
+		if got := checkStatus(s, now, thresholds).code; got != test.want {
+			t.Errorf("%s: got %d, want %d", test.name, got, test.want)
+		}
+	}
+
+	// test disabled thresholds
+	s := healthy()
+	s.LastKeepAlive = now.Add(-time.Hour).Unix()
+	s.KerberosTGT.EndTime = now.Add(time.Minute).Unix()
+	s.LoginFailures = 5
+	if got := checkStatus(s, now, checkThresholds{}).code; got != CheckOK {
+		t.Errorf("disabled thresholds: got %d, want %d", got, CheckOK)
+	}
+
+	// test worst message first and perfdata
+	s = healthy()
+	s.LoginFailures = 3
+	r := checkStatus(s, now, thresholds)
+	if r.messages[0] != "3 consecutive login failures" {
+		t.Errorf("got messages %v", r.messages)
+	}
+	want := []string{
+		"keepalive_age=60s;900;1800;0;",
+		"tgt_remaining=7200s;3600:;600:;0;",
+		"login_failures=3;1;3;0;",
+	}
+	if strings.Join(r.perfdata, " ") != strings.Join(want, " ") {
+		t.Errorf("got perfdata %v, want %v", r.perfdata, want)
+	}
+}
+
+// TestPrintCheckResult tests printCheckResult.
+func TestPrintCheckResult(t *testing.T) {
+	b := &bytes.Buffer{}
+	r := &checkResult{}
+	r.add(CheckOK, "ok")
+	r.add(CheckWarning, "warning")
+	r.perfdata = []string{"a=1;;;0;", "b=2;;;0;"}
+	if err := printCheckResult(b, r); err != nil {
+		t.Fatal(err)
+	}
+	want := "FW-ID WARNING - warning, ok | a=1;;;0; b=2;;;0;\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+
+	// test without perfdata
+	b.Reset()
+	if err := printCheckResult(b, &checkResult{code: CheckOK, messages: []string{"ok"}}); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "FW-ID OK - ok\n" {
+		t.Errorf("got %q", b.String())
+	}
+}
+
+// TestCheck tests check.
+func TestCheck(t *testing.T) {
+	// test query error
+	b := &bytes.Buffer{}
+	err := check(b, &testClient{err: errors.New("test error")})
+	e := &exitError{}
+	if !errors.As(err, &e) || e.code != CheckUnknown {
+		t.Errorf("got %v, want exit code %d", err, CheckUnknown)
+	}
+	if !strings.HasPrefix(b.String(), "FW-ID UNKNOWN - ") {
+		t.Errorf("got %q", b.String())
+	}
+
+	// test critical
+	b.Reset()
+	err = check(b, &testClient{status: status.New()})
+	if !errors.As(err, &e) || e.code != CheckCritical {
+		t.Errorf("got %v, want exit code %d", err, CheckCritical)
+	}
+	if !strings.HasPrefix(b.String(), "FW-ID CRITICAL - ") {
+		t.Errorf("got %q", b.String())
+	}
+
+	// test ok
+	b.Reset()
+	s := status.New()
+	s.KerberosTGT.EndTime = time.Now().Add(24 * time.Hour).Unix()
+	if err := check(b, &testClient{status: s}); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+	if !strings.HasPrefix(b.String(), "FW-ID OK - ") {
+		t.Errorf("got %q", b.String())
+	}
+}
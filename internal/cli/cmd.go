@@ -2,18 +2,68 @@
 package cli
 
 import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/telekom-mms/fw-id-agent/internal/agent"
+	"github.com/telekom-mms/fw-id-agent/internal/cmdline"
+	"github.com/telekom-mms/fw-id-agent/internal/doctor"
+	"github.com/telekom-mms/fw-id-agent/internal/i18n"
 	"github.com/telekom-mms/fw-id-agent/pkg/client"
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/fw-id-agent/pkg/history"
 	"github.com/telekom-mms/fw-id-agent/pkg/status"
 )
 
+// Exit codes of the status and wait commands. ExitError is the exit code of
+// all commands on other errors, e.g., D-Bus errors.
+const (
+	ExitLoggedIn   = 0
+	ExitLoggedOut  = 1
+	ExitUntrusted  = 2
+	ExitNotRunning = 3
+	ExitTimeout    = 4
+	ExitError      = 5
+)
+
+// Wait states of the wait command.
+const (
+	WaitStateLoggedIn  = "logged-in"
+	WaitStateLoggedOut = "logged-out"
+	WaitStateTrusted   = "trusted"
+	WaitStateUntrusted = "untrusted"
+)
+
+// exitError is an error that sets the exit code of fw-id-cli. If err is nil,
+// fw-id-cli exits silently with the exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+// Error returns the error as string.
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit code %d", e.code)
+	}
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
 var (
 	// command is the command specified on the command line.
 	command = ""
@@ -23,120 +73,460 @@ var (
 
 	// json specifies whether output should be formatted as json.
 	json = false
+
+	// historySince specifies the duration of the event history to show.
+	historySince time.Duration
+
+	// configFormat is the output format of config convert.
+	configFormat = ""
+
+	// configInput and configOutput are the input and output files of
+	// config convert.
+	configInput  = ""
+	configOutput = ""
+
+	// debugMode is "on" or "off" for debug, debugFor is the duration of
+	// debug logging.
+	debugMode = ""
+	debugFor  = 10 * time.Minute
+
+	// doctorConfig is the config file checked by doctor.
+	doctorConfig = ""
+
+	// completionShell is the shell of completion.
+	completionShell = ""
+
+	// bundleOutput is the output file of support-bundle.
+	bundleOutput = ""
+
+	// waitState is the state wait waits for, waitTimeout is the maximum
+	// duration wait waits.
+	waitState   = WaitStateLoggedIn
+	waitTimeout time.Duration
+
+	// monitorFields are the status fields shown by monitor.
+	monitorFields = statusFields
 )
 
-// parseCommandLine parses the command line arguments.
-func parseCommandLine(args []string) error {
+// statusFields are the names of the status fields in the order monitor shows
+// them.
+var statusFields = []string{
+	"TrustedNetwork",
+	"LoginState",
+	"AgentState",
+	"SourceIP",
+	"LastLogin",
+	"LastKeepAlive",
+	"LoginFailures",
+	"KerberosTGT",
+	"LogLevel",
+	"ConfigSignature",
+	"Config",
+}
+
+// parseFields parses the comma-separated list of status fields in s. Field
+// names are case-insensitive.
+func parseFields(s string) ([]string, error) {
+	fields := []string{}
+	for f := range strings.SplitSeq(s, ",") {
+		f = strings.TrimSpace(f)
+		i := slices.IndexFunc(statusFields, func(sf string) bool {
+			return strings.EqualFold(sf, f)
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("invalid field: %s", f)
+		}
+		fields = append(fields, statusFields[i])
+	}
+	return fields, nil
+}
+
+// newCommand returns the command line definition of fw-id-cli with name. The
+// flags are bound to the package variables.
+func newCommand(name string) *cmdline.Command {
 	// status subcommand
 	statusCmd := flag.NewFlagSet("status", flag.ContinueOnError)
 	statusCmd.BoolVar(&verbose, "verbose", verbose, "set verbose output")
 	statusCmd.BoolVar(&json, "json", json, "set json output")
 
+	// history subcommand
+	historyCmd := flag.NewFlagSet("history", flag.ContinueOnError)
+	historyCmd.DurationVar(&historySince, "since", historySince,
+		"show only events of the last `duration`, e.g., 1h, default: all events")
+	historyCmd.BoolVar(&json, "json", json, "set json output")
+
+	// config subcommand
+	configCmd := flag.NewFlagSet("config convert", flag.ContinueOnError)
+	configCmd.StringVar(&configFormat, "format", configFormat,
+		"set output `format` (json, yaml, toml), default: detected from output file extension")
+
+	// debug subcommand
+	debugCmd := flag.NewFlagSet("debug", flag.ContinueOnError)
+	debugCmd.DurationVar(&debugFor, "for", debugFor,
+		"enable debug logging for `duration`, 0 keeps it enabled until debug off")
+
+	// doctor subcommand
+	doctorCmd := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	doctorCmd.StringVar(&doctorConfig, "config", doctorConfig,
+		"check config `file`, default: config of running agent")
+	doctorCmd.BoolVar(&json, "json", json, "set json output")
+
+	// support-bundle subcommand
+	bundleCmd := flag.NewFlagSet("support-bundle", flag.ContinueOnError)
+	bundleCmd.StringVar(&bundleOutput, "output", bundleOutput,
+		"write support bundle to `file`, default: fw-id-agent-support-<time>.tar.gz")
+
+	// monitor subcommand
+	fields := ""
+	monitorCmd := flag.NewFlagSet("monitor", flag.ContinueOnError)
+	monitorCmd.BoolVar(&verbose, "verbose", verbose, "set verbose output, show full status on every update")
+	monitorCmd.BoolVar(&json, "json", json, "set json lines output")
+	monitorCmd.StringVar(&fields, "fields", fields,
+		"show only comma-separated `list` of fields, e.g., LoginState,TrustedNetwork, default: all fields")
+
+	// wait subcommand
+	waitCmd := flag.NewFlagSet("wait", flag.ContinueOnError)
+	waitCmd.StringVar(&waitState, "state", waitState,
+		"wait for `state` (logged-in, logged-out, trusted, untrusted)")
+	waitCmd.DurationVar(&waitTimeout, "timeout", waitTimeout,
+		"wait at most `duration`, e.g., 60s, default: wait forever")
+
+	// check subcommand
+	checkCmd := flag.NewFlagSet("check", flag.ContinueOnError)
+	checkCmd.DurationVar(&thresholds.keepAliveWarn, "keepalive-warning", thresholds.keepAliveWarn,
+		"warning if last keep-alive is older than `duration`, 0 disables it")
+	checkCmd.DurationVar(&thresholds.keepAliveCrit, "keepalive-critical", thresholds.keepAliveCrit,
+		"critical if last keep-alive is older than `duration`, 0 disables it")
+	checkCmd.DurationVar(&thresholds.tgtWarn, "tgt-warning", thresholds.tgtWarn,
+		"warning if kerberos TGT expires in less than `duration`, 0 disables it")
+	checkCmd.DurationVar(&thresholds.tgtCrit, "tgt-critical", thresholds.tgtCrit,
+		"critical if kerberos TGT expires in less than `duration`, 0 disables it")
+	checkCmd.UintVar(&thresholds.failuresWarn, "failures-warning", thresholds.failuresWarn,
+		"warning at `number` of consecutive login failures, 0 disables it")
+	checkCmd.UintVar(&thresholds.failuresCrit, "failures-critical", thresholds.failuresCrit,
+		"critical at `number` of consecutive login failures, 0 disables it")
+
+	// completion subcommand
+	completionCmd := flag.NewFlagSet("completion", flag.ContinueOnError)
+
 	// command line arguments
-	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
 	ver := flags.Bool("version", false, "print version")
 
-	// usage output
-	flags.Usage = func() {
-		cmd := flags.Name()
-		w := flags.Output()
-		usage := func(f string, args ...any) {
-			_, _ = fmt.Fprintf(w, f, args...)
-		}
-		usage("Usage:\n")
-		usage("  %s [options] [command]\n", cmd)
-		usage("\nOptions:\n")
-		flags.PrintDefaults()
-		usage("\nCommands:\n")
-		usage("  status\n")
-		usage("        show agent status\n")
-		usage("  monitor\n")
-		usage("        monitor agent status updates\n")
-		usage("  relogin\n")
-		usage("        relogin agent\n")
-	}
-
-	// parse command line arguments
-	if err := flags.Parse(args[1:]); err != nil {
-		return err
+	return &cmdline.Command{
+		Name:  name,
+		Short: "command line interface of the firewall identity agent",
+		Long: "fw-id-cli shows and monitors the status of the firewall identity " +
+			"agent fw-id-agent, sends requests to the agent and helps to " +
+			"diagnose problems. Most commands need a running agent in the " +
+			"user session.",
+		Flags: flags,
+		Check: func([]string) error {
+			// print version?
+			if *ver {
+				fmt.Println(agent.Version)
+				// treat -version like -help
+				return flag.ErrHelp
+			}
+			return nil
+		},
+		Commands: []*cmdline.Command{
+			{
+				Name:  "status",
+				Short: "show agent status",
+				Long: "Show the current status of the agent. Exits with 0 if " +
+					"logged in, 1 if connected to a trusted network but not " +
+					"logged in, 2 if not connected to a trusted network and 3 " +
+					"if the agent is not running.",
+				Flags: statusCmd,
+			},
+			{
+				Name:  "monitor",
+				Short: "monitor agent status updates",
+				Long: "Monitor status updates of the agent. By default, one " +
+					"line with the time and the changed fields is shown for " +
+					"every update.",
+				Flags:      monitorCmd,
+				FlagValues: map[string][]string{"fields": statusFields},
+				Check: func([]string) error {
+					if fields == "" {
+						return nil
+					}
+					f, err := parseFields(fields)
+					if err != nil {
+						monitorCmd.Usage()
+						return err
+					}
+					monitorFields = f
+					return nil
+				},
+			},
+			{
+				Name:  "relogin",
+				Short: "relogin agent",
+				Long:  "Send a re-login request to the agent.",
+			},
+			{
+				Name:  "history",
+				Short: "show agent event history",
+				Long: "Show the event history of the agent like trusted " +
+					"network changes, login attempts, keep-alives and " +
+					"Kerberos ticket changes.",
+				Flags: historyCmd,
+			},
+			{
+				Name:  "config",
+				Short: "handle config files",
+				Commands: []*cmdline.Command{
+					{
+						Name:  "convert",
+						Args:  "input [output]",
+						Short: "convert config file between json, yaml and toml",
+						Long: "Convert the config file input to the format " +
+							"of the config file output. If output is " +
+							"omitted, the converted config is printed.",
+						Flags:      configCmd,
+						FlagValues: map[string][]string{"format": {"json", "yaml", "toml"}},
+						ArgFiles:   true,
+						Check: func(args []string) error {
+							if len(args) < 1 || len(args) > 2 {
+								configCmd.Usage()
+								return fmt.Errorf("invalid number of config files")
+							}
+							configInput = configCmd.Arg(0)
+							configOutput = configCmd.Arg(1)
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "debug",
+				Args:  "on|off",
+				Short: "enable or disable debug logging of agent temporarily",
+				Long: "Enable or disable debug logging of the agent. Debug " +
+					"logging is disabled automatically after the duration " +
+					"set with -for.",
+				Flags:     debugCmd,
+				ArgValues: []string{"on", "off"},
+				Check: func(args []string) error {
+					// allow options before and after on|off
+					debugMode = debugCmd.Arg(0)
+					if len(args) > 0 {
+						if err := debugCmd.Parse(args[1:]); err != nil {
+							return err
+						}
+					}
+					if (debugMode != "on" && debugMode != "off") || debugCmd.NArg() > 0 {
+						debugCmd.Usage()
+						return fmt.Errorf("invalid debug mode")
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "doctor",
+				Short: "check agent, kerberos, trusted network and service setup",
+				Long: "Check the agent, the config, the Kerberos setup, the " +
+					"trusted network servers and the firewall identity " +
+					"service and show hints for failed checks.",
+				Flags: doctorCmd,
+			},
+			{
+				Name:  "support-bundle",
+				Short: "collect diagnostics of agent in a tar.gz archive",
+				Long: "Collect version, config, status, event history, logs " +
+					"and Kerberos metadata of the agent in a tar.gz archive. " +
+					"Secrets are redacted.",
+				Flags: bundleCmd,
+			},
+			{
+				Name:  "wait",
+				Short: "wait until agent reaches a state",
+				Long: "Wait until the agent reaches a state. Exits with 0 " +
+					"when the state is reached, 3 if the agent is not " +
+					"running and 4 if the timeout is over.",
+				Flags: waitCmd,
+				FlagValues: map[string][]string{"state": {
+					WaitStateLoggedIn,
+					WaitStateLoggedOut,
+					WaitStateTrusted,
+					WaitStateUntrusted,
+				}},
+				Check: func([]string) error {
+					switch waitState {
+					case WaitStateLoggedIn, WaitStateLoggedOut, WaitStateTrusted, WaitStateUntrusted:
+						return nil
+					}
+					waitCmd.Usage()
+					return fmt.Errorf("invalid wait state: %s", waitState)
+				},
+			},
+			{
+				Name:  "check",
+				Short: "check agent health as monitoring plugin",
+				Long: "Check the age of the last keep-alive, the remaining " +
+					"lifetime of the Kerberos TGT and the number of " +
+					"consecutive login failures as Nagios, Icinga or " +
+					"Checkmk plugin. Exits with 0 (OK), 1 (WARNING), 2 " +
+					"(CRITICAL) or 3 (UNKNOWN).",
+				Flags: checkCmd,
+			},
+			{
+				Name:  "completion",
+				Args:  "bash|zsh|fish",
+				Short: "print shell completion script",
+				Long: "Print the completion script for the shell bash, zsh " +
+					"or fish.",
+				Flags:     completionCmd,
+				ArgValues: cmdline.Shells,
+				Check: func(args []string) error {
+					if len(args) != 1 || !slices.Contains(cmdline.Shells, args[0]) {
+						completionCmd.Usage()
+						return fmt.Errorf("invalid shell")
+					}
+					completionShell = args[0]
+					return nil
+				},
+			},
+		},
 	}
+}
 
-	// print version?
-	if *ver {
-		fmt.Println(agent.Version)
-		// treat -version like -help
-		return flag.ErrHelp
-	}
+// Command returns the command line definition of fw-id-cli.
+func Command() *cmdline.Command {
+	return newCommand("fw-id-cli")
+}
 
-	// parse subcommands
-	command = flags.Arg(0)
-	switch command {
-	case "status":
-		if err := statusCmd.Parse(args[2:]); err != nil {
-			return err
-		}
-	case "monitor":
-	case "relogin":
-	default:
-		flags.Usage()
-		return fmt.Errorf("unknown command")
+// parseCommandLine parses the command line arguments.
+func parseCommandLine(args []string) error {
+	cmd, err := newCommand(args[0]).Parse(args[1:])
+	if err != nil {
+		return err
 	}
-
+	command = cmd.Path()[1]
 	return nil
 }
 
+// statusLabels are the labels in the status output.
+var statusLabels = []i18n.Message{
+	i18n.LabelTrustedNetwork,
+	i18n.LabelLoginState,
+	i18n.LabelAgentState,
+	i18n.LabelSourceIP,
+	i18n.LabelLastLogin,
+	i18n.LabelLastKeepAlive,
+	i18n.LabelLoginFailures,
+	i18n.LabelKerberosTGT,
+	i18n.LabelStartTime,
+	i18n.LabelEndTime,
+	i18n.LabelConfig,
+	i18n.LabelConfigSignature,
+	i18n.LabelLogLevel,
+}
+
+// statusLabelWidth returns the width of the labels in the status output so
+// the values are aligned, at least 20 characters.
+func statusLabelWidth() int {
+	width := 20
+	for _, l := range statusLabels {
+		// sub labels are prefixed with "- " and all labels end with ": "
+		width = max(width, utf8.RuneCountInString(i18n.String(l))+4)
+	}
+	return width
+}
+
 // printStatus prints status.
 func printStatus(out io.Writer, s *status.Status, verbose bool) error {
+	width := statusLabelWidth()
 	printf := func(format string, a ...any) {
 		_, _ = fmt.Fprintf(out, format, a...)
 	}
-	printf("Trusted Network:    %s\n", s.TrustedNetwork)
-	printf("Login State:        %s\n", s.LoginState)
+	// field prints the label and the aligned value, only the label if the
+	// value is empty
+	field := func(prefix string, label i18n.Message, value string) {
+		if value == "" {
+			printf("%s%s:\n", prefix, i18n.String(label))
+			return
+		}
+		printf("%-*s%s\n", width, prefix+i18n.String(label)+":", value)
+	}
+	field("", i18n.LabelTrustedNetwork, i18n.TrustedNetwork(s.TrustedNetwork))
+	field("", i18n.LabelLoginState, i18n.LoginState(s.LoginState))
 	if verbose {
+		// agent state
+		field("", i18n.LabelAgentState, i18n.AgentState(s.AgentState))
+
+		// source ip used to reach the identity service
+		field("", i18n.LabelSourceIP, s.SourceIP)
+
+		// last login info
+		lastLogin := ""
+		if s.LastLogin > 0 {
+			lastLogin = time.Unix(s.LastLogin, 0).String()
+		}
+		field("", i18n.LabelLastLogin, lastLogin)
+
 		// last keep-alive info
-		if s.LastKeepAlive <= 0 {
-			printf("Last Keep-Alive:\n")
-		} else {
-			lastKeepAlive := time.Unix(s.LastKeepAlive, 0)
-			printf("Last Keep-Alive:    %s\n", lastKeepAlive)
+		lastKeepAlive := ""
+		if s.LastKeepAlive > 0 {
+			lastKeepAlive = time.Unix(s.LastKeepAlive, 0).String()
 		}
+		field("", i18n.LabelLastKeepAlive, lastKeepAlive)
+
+		// consecutive login failures
+		field("", i18n.LabelLoginFailures, fmt.Sprint(s.LoginFailures))
 
 		// kerberos info
-		printf("Kerberos TGT:\n")
+		field("", i18n.LabelKerberosTGT, "")
 
 		// kerberos tgt start time
-		if s.KerberosTGT.StartTime <= 0 {
-			printf("- Start Time:\n")
-		} else {
-			tgtStartTime := time.Unix(s.KerberosTGT.StartTime, 0)
-			printf("- Start Time:       %s\n", tgtStartTime)
+		tgtStartTime := ""
+		if s.KerberosTGT.StartTime > 0 {
+			tgtStartTime = time.Unix(s.KerberosTGT.StartTime, 0).String()
 		}
+		field("- ", i18n.LabelStartTime, tgtStartTime)
 
 		// kerberos tgt end time
-		if s.KerberosTGT.EndTime <= 0 {
-			printf("- End Time:\n")
-		} else {
-			tgtEndTime := time.Unix(s.KerberosTGT.EndTime, 0)
-			printf("- End Time:         %s\n", tgtEndTime)
+		tgtEndTime := ""
+		if s.KerberosTGT.EndTime > 0 {
+			tgtEndTime = time.Unix(s.KerberosTGT.EndTime, 0).String()
 		}
+		field("- ", i18n.LabelEndTime, tgtEndTime)
 
 		// agent config
 		config, err := s.Config.JSON()
 		if err != nil {
 			return err
 		}
-		printf("Config:             %s\n", config)
+		printf("%-*s%s\n", width, i18n.String(i18n.LabelConfig)+":", config)
+		field("", i18n.LabelConfigSignature, i18n.SignatureStatus(s.ConfigSignature))
+
+		// runtime log level
+		field("", i18n.LabelLogLevel, s.LogLevel)
 	}
 
 	return nil
 }
 
-// getStatus retrieves the agent status and prints it.
+// statusExitCode returns the exit code of status s.
+func statusExitCode(s *status.Status) int {
+	switch {
+	case !s.TrustedNetwork.Trusted():
+		return ExitUntrusted
+	case s.LoginState.LoggedIn():
+		return ExitLoggedIn
+	}
+	return ExitLoggedOut
+}
+
+// getStatus retrieves the agent status and prints it. It returns an
+// exitError with the exit code of the status.
 func getStatus(c client.Client) error {
 	// query status
 	s, err := c.Query()
 	if err != nil {
-		return fmt.Errorf("query failed: %w", err)
+		return &exitError{ExitNotRunning, fmt.Errorf("query failed: %w", err)}
 	}
 
 	if json {
@@ -146,11 +536,56 @@ func getStatus(c client.Client) error {
 			return fmt.Errorf("error converting status to json: %w", err)
 		}
 		fmt.Println(string(j))
-		return nil
+	} else if err := printStatus(os.Stdout, s, verbose); err != nil {
+		return err
+	}
+
+	if code := statusExitCode(s); code != ExitLoggedIn {
+		return &exitError{code: code}
+	}
+	return nil
+}
+
+// waitStateReached returns whether status s is in the wait state.
+func waitStateReached(s *status.Status) bool {
+	switch waitState {
+	case WaitStateLoggedIn:
+		return s.LoginState.LoggedIn()
+	case WaitStateLoggedOut:
+		return s.LoginState == status.LoginStateLoggedOut
+	case WaitStateTrusted:
+		return s.TrustedNetwork.Trusted()
+	case WaitStateUntrusted:
+		return s.TrustedNetwork == status.TrustedNetworkNotTrusted
+	}
+	return false
+}
+
+// wait waits until the agent reaches the wait state or the wait timeout is
+// over.
+func wait(c client.Client) error {
+	updates, err := c.Subscribe()
+	if err != nil {
+		return &exitError{ExitNotRunning, fmt.Errorf("error subscribing to status updates: %w", err)}
 	}
 
-	// print status
-	return printStatus(os.Stdout, s, verbose)
+	var timeout <-chan time.Time
+	if waitTimeout > 0 {
+		timeout = time.After(waitTimeout)
+	}
+	for {
+		select {
+		case s, ok := <-updates:
+			if !ok {
+				return &exitError{ExitNotRunning, errors.New("status updates stopped")}
+			}
+			if waitStateReached(s) {
+				return nil
+			}
+		case <-timeout:
+			return &exitError{ExitTimeout, fmt.Errorf("timeout waiting for state %s", waitState)}
+		}
+	}
 }
 
 // relogin sends a relogin request to the agent.
@@ -162,6 +597,181 @@ func relogin(c client.Client) error {
 	return nil
 }
 
+// debug enables or disables debug logging of the agent.
+func debug(c client.ControlClient) error {
+	if debugMode == "off" {
+		if err := c.SetLogLevel("", 0); err != nil {
+			return fmt.Errorf("disabling debug logging failed: %w", err)
+		}
+		return nil
+	}
+	if err := c.SetLogLevel("debug", debugFor); err != nil {
+		return fmt.Errorf("enabling debug logging failed: %w", err)
+	}
+	return nil
+}
+
+// printHistory prints the events in history.
+func printHistory(out io.Writer, events []*history.Event) error {
+	for _, e := range events {
+		line := fmt.Sprintf("%s  %-10s  %s", e.Time.Format(time.DateTime), e.Type, e.Message)
+
+		// add details sorted by key
+		details := []string{}
+		for k, v := range e.Details {
+			details = append(details, k+"="+v)
+		}
+		slices.Sort(details)
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+
+		// add error
+		if e.Error != "" {
+			line += ": " + e.Error
+		}
+
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getHistory retrieves the agent event history and prints it.
+func getHistory(c client.ControlClient) error {
+	// get history
+	since := time.Time{}
+	if historySince > 0 {
+		since = time.Now().Add(-historySince)
+	}
+	events, err := c.GetHistory(since)
+	if err != nil {
+		return fmt.Errorf("getting history failed: %w", err)
+	}
+
+	if json {
+		// print history as json
+		j, err := history.JSONIndent(events)
+		if err != nil {
+			return fmt.Errorf("error converting history to json: %w", err)
+		}
+		fmt.Println(string(j))
+		return nil
+	}
+
+	// print history
+	return printHistory(os.Stdout, events)
+}
+
+// formatField returns field name of status s in human readable format.
+func formatField(s *status.Status, name string) string {
+	formatTime := func(t int64) string {
+		if t <= 0 {
+			return ""
+		}
+		return time.Unix(t, 0).Format(time.DateTime)
+	}
+	switch name {
+	case "TrustedNetwork":
+		return s.TrustedNetwork.String()
+	case "LoginState":
+		return s.LoginState.String()
+	case "AgentState":
+		return s.AgentState.String()
+	case "SourceIP":
+		return s.SourceIP
+	case "LastLogin":
+		return formatTime(s.LastLogin)
+	case "LastKeepAlive":
+		return formatTime(s.LastKeepAlive)
+	case "LoginFailures":
+		return fmt.Sprint(s.LoginFailures)
+	case "KerberosTGT":
+		return formatTime(s.KerberosTGT.StartTime) + " - " + formatTime(s.KerberosTGT.EndTime)
+	case "LogLevel":
+		return s.LogLevel
+	case "ConfigSignature":
+		return s.ConfigSignature.String()
+	case "Config":
+		b, _ := s.Config.JSON()
+		return string(b)
+	}
+	return ""
+}
+
+// statusUpdate is a status update printed by monitor in json lines format.
+type statusUpdate struct {
+	Time    time.Time
+	Changed []string
+	Status  map[string]stdjson.RawMessage
+}
+
+// getStatusFields returns the status fields of s as JSON.
+func getStatusFields(s *status.Status) (map[string]stdjson.RawMessage, error) {
+	b, err := s.JSON()
+	if err != nil {
+		return nil, err
+	}
+	f := make(map[string]stdjson.RawMessage)
+	if err := stdjson.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// printStatusUpdate prints the monitor fields of the status update s at time
+// t that changed since the previous status prev. If prev is nil, all monitor
+// fields are printed.
+func printStatusUpdate(out io.Writer, prev, s *status.Status, t time.Time) error {
+	// get changed fields
+	cur, err := getStatusFields(s)
+	if err != nil {
+		return err
+	}
+	old := map[string]stdjson.RawMessage{}
+	if prev != nil {
+		if old, err = getStatusFields(prev); err != nil {
+			return err
+		}
+	}
+	changed := []string{}
+	for _, f := range monitorFields {
+		if prev == nil || !bytes.Equal(old[f], cur[f]) {
+			changed = append(changed, f)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if json {
+		// print one json object per line
+		u := &statusUpdate{
+			Time:    t,
+			Changed: changed,
+			Status:  make(map[string]stdjson.RawMessage),
+		}
+		for _, f := range monitorFields {
+			u.Status[f] = cur[f]
+		}
+		b, err := stdjson.Marshal(u)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(b))
+		return err
+	}
+
+	// print one line with changed fields
+	values := []string{}
+	for _, f := range changed {
+		values = append(values, fmt.Sprintf("%s=%q", f, formatField(s, f)))
+	}
+	_, err = fmt.Fprintf(out, "%s  %s\n", t.Format(time.DateTime), strings.Join(values, " "))
+	return err
+}
+
 // monitor subscribes to status updates from the agent and displays them.
 func monitor(c client.Client) error {
 	// get status updates
@@ -169,17 +779,105 @@ func monitor(c client.Client) error {
 	if err != nil {
 		return fmt.Errorf("error subscribing to status updates: %w", err)
 	}
+	var prev *status.Status
 	for u := range updates {
-		log.Println("Got status update:")
-		if err := printStatus(os.Stdout, u, true); err != nil {
+		if verbose && !json {
+			// print full status
+			log.Println("Got status update:")
+			if err := printStatus(os.Stdout, u, true); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := printStatusUpdate(os.Stdout, prev, u, time.Now()); err != nil {
+			return err
+		}
+		prev = u
+	}
+	return nil
+}
+
+// runDoctor runs the diagnostic checks with client c and prints the report.
+// c is nil if the client could not be created.
+func runDoctor(out io.Writer, c client.Client) error {
+	r := doctor.Run(c, doctorConfig)
+	if json {
+		// print report as json
+		j, err := r.JSONIndent()
+		if err != nil {
+			return fmt.Errorf("error converting report to json: %w", err)
+		}
+		if _, err := fmt.Fprintln(out, string(j)); err != nil {
+			return err
+		}
+	} else if err := r.Write(out); err != nil {
+		return err
+	}
+
+	if !r.Passed() {
+		return errors.New("some checks failed")
+	}
+	return nil
+}
+
+// supportBundle retrieves the support bundle from the agent and writes it to
+// the output file.
+func supportBundle(c client.ControlClient) error {
+	b, err := c.CollectDiagnostics()
+	if err != nil {
+		return fmt.Errorf("collecting diagnostics failed: %w", err)
+	}
+
+	output := bundleOutput
+	if output == "" {
+		output = fmt.Sprintf("fw-id-agent-support-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+	if err := os.WriteFile(output, b, 0600); err != nil {
+		return fmt.Errorf("could not write support bundle: %w", err)
+	}
+	fmt.Println("Wrote support bundle to", output)
+	return nil
+}
+
+// convertConfig converts the config in file input to the format of file output
+// or format and writes it to output or out.
+func convertConfig(out io.Writer, input, output, format string) error {
+	// get output format
+	to := config.FormatJSON
+	switch {
+	case format != "":
+		f, err := config.ParseFormat(format)
+		if err != nil {
 			return err
 		}
+		to = f
+	case output != "":
+		to = config.FormatFromPath(output)
+	}
+
+	// read and convert input file
+	b, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("could not read config: %w", err)
+	}
+	c, err := config.Convert(b, config.FormatFromPath(input), to)
+	if err != nil {
+		return fmt.Errorf("could not convert config: %w", err)
+	}
+
+	// write result
+	if output == "" {
+		_, err = out.Write(c)
+		return err
+	}
+	if err := os.WriteFile(output, c, 0644); err != nil {
+		return fmt.Errorf("could not write config: %w", err)
 	}
 	return nil
 }
 
 // runCommand runs command.
-func runCommand(c client.Client, command string) error {
+func runCommand(c client.ControlClient, command string) error {
 	switch command {
 	case "status":
 		return getStatus(c)
@@ -187,6 +885,16 @@ func runCommand(c client.Client, command string) error {
 		return monitor(c)
 	case "relogin":
 		return relogin(c)
+	case "history":
+		return getHistory(c)
+	case "debug":
+		return debug(c)
+	case "support-bundle":
+		return supportBundle(c)
+	case "wait":
+		return wait(c)
+	case "check":
+		return check(os.Stdout, c)
 	}
 	return nil
 }
@@ -198,10 +906,37 @@ func run(args []string) error {
 		return err
 	}
 
+	// set language of user-facing messages
+	i18n.SetLocale(i18n.EnvLocale())
+
+	// run commands that do not need the agent
+	switch command {
+	case "config":
+		return convertConfig(os.Stdout, configInput, configOutput, configFormat)
+	case "completion":
+		return Command().Completion(os.Stdout, completionShell)
+	}
+
 	// create client
-	c, err := client.NewClient()
+	c, err := client.NewControlClient()
+	if command == "doctor" {
+		// doctor reports unreachable agent itself
+		if err != nil {
+			return runDoctor(os.Stdout, nil)
+		}
+		defer func() { _ = c.Close() }()
+		return runDoctor(os.Stdout, c)
+	}
 	if err != nil {
-		return fmt.Errorf("could not create client: %w", err)
+		err = fmt.Errorf("could not create client: %w", err)
+		switch command {
+		case "status", "wait":
+			return &exitError{ExitNotRunning, err}
+		case "check":
+			fmt.Printf("FW-ID %s - %v\n", checkStates[CheckUnknown], err)
+			return &exitError{code: CheckUnknown}
+		}
+		return err
 	}
 	defer func() { _ = c.Close() }()
 
@@ -209,12 +944,29 @@ func run(args []string) error {
 	return runCommand(c, command)
 }
 
-// Run is the main entry point.
-func Run() {
-	if err := run(os.Args); err != nil {
-		if err != flag.ErrHelp {
-			log.Fatal(err)
-		}
+// osExit is os.Exit for testing.
+var osExit = os.Exit
+
+// exit logs the error err returned by run and exits with its exit code.
+// Errors that are not exitErrors exit with ExitError, so they cannot be
+// mistaken for a status.
+func exit(err error) {
+	var exitErr *exitError
+	switch {
+	case err == nil, err == flag.ErrHelp:
 		return
+	case errors.As(err, &exitErr):
+		if exitErr.err != nil {
+			log.Error(exitErr.err)
+		}
+		osExit(exitErr.code)
+	default:
+		log.Error(err)
+		osExit(ExitError)
 	}
 }
+
+// Run is the main entry point.
+func Run() {
+	exit(run(os.Args))
+}
@@ -2,12 +2,20 @@ package cli
 
 import (
 	"bytes"
+	stdjson "encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/telekom-mms/fw-id-agent/internal/i18n"
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/fw-id-agent/pkg/history"
 	"github.com/telekom-mms/fw-id-agent/pkg/status"
 )
 
@@ -38,6 +46,127 @@ func TestParseCommandLine(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 
+	args = []string{"test", "history", "-since", "1h", "-json"}
+	if err := parseCommandLine(args); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if historySince != time.Hour || !json {
+		t.Errorf("unexpected history options: %v, %t", historySince, json)
+	}
+	historySince = 0
+	json = false
+
+	args = []string{"test", "config", "convert", "in.json", "out.yaml"}
+	if err := parseCommandLine(args); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if configInput != "in.json" || configOutput != "out.yaml" {
+		t.Errorf("unexpected config files: %s, %s", configInput, configOutput)
+	}
+
+	args = []string{"test", "config", "convert"}
+	if err := parseCommandLine(args); err == nil {
+		t.Errorf("should return error")
+	}
+
+	args = []string{"test", "config", "invalid-command"}
+	if err := parseCommandLine(args); err == nil {
+		t.Errorf("should return error")
+	}
+
+	args = []string{"test", "debug", "on", "--for", "5m"}
+	if err := parseCommandLine(args); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if debugMode != "on" || debugFor != 5*time.Minute {
+		t.Errorf("unexpected debug options: %s, %v", debugMode, debugFor)
+	}
+
+	args = []string{"test", "debug", "-for", "0", "off"}
+	if err := parseCommandLine(args); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if debugMode != "off" || debugFor != 0 {
+		t.Errorf("unexpected debug options: %s, %v", debugMode, debugFor)
+	}
+	debugFor = 10 * time.Minute
+
+	for _, args := range [][]string{
+		{"test", "debug"},
+		{"test", "debug", "invalid"},
+		{"test", "debug", "on", "extra"},
+	} {
+		if err := parseCommandLine(args); err == nil {
+			t.Errorf("should return error for %v", args)
+		}
+	}
+	debugMode = ""
+
+	args = []string{"test", "doctor", "-config", "/etc/fw-id-agent.json"}
+	if err := parseCommandLine(args); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if doctorConfig != "/etc/fw-id-agent.json" {
+		t.Errorf("unexpected doctor config: %s", doctorConfig)
+	}
+	doctorConfig = ""
+
+	args = []string{"test", "support-bundle", "-output", "bundle.tar.gz"}
+	if err := parseCommandLine(args); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if bundleOutput != "bundle.tar.gz" {
+		t.Errorf("unexpected support bundle output: %s", bundleOutput)
+	}
+	bundleOutput = ""
+
+	args = []string{"test", "monitor", "-json", "-fields", "LoginState"}
+	if err := parseCommandLine(args); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !json || !slices.Equal(monitorFields, []string{"LoginState"}) {
+		t.Errorf("unexpected monitor options: %v, %v", json, monitorFields)
+	}
+	args = []string{"test", "monitor", "-fields", "invalid"}
+	if err := parseCommandLine(args); err == nil {
+		t.Errorf("should return error for %v", args)
+	}
+	json = false
+	monitorFields = statusFields
+
+	args = []string{"test", "wait", "-state", "trusted", "-timeout", "1m"}
+	if err := parseCommandLine(args); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if waitState != WaitStateTrusted || waitTimeout != time.Minute {
+		t.Errorf("unexpected wait options: %s, %v", waitState, waitTimeout)
+	}
+	args = []string{"test", "wait", "-state", "invalid"}
+	if err := parseCommandLine(args); err == nil {
+		t.Errorf("should return error for %v", args)
+	}
+	waitState = WaitStateLoggedIn
+	waitTimeout = 0
+
+	args = []string{"test", "completion", "zsh"}
+	if err := parseCommandLine(args); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if command != "completion" || completionShell != "zsh" {
+		t.Errorf("unexpected completion options: %s, %s", command, completionShell)
+	}
+	completionShell = ""
+	for _, args := range [][]string{
+		{"test", "completion"},
+		{"test", "completion", "invalid"},
+		{"test", "status", "extra"},
+		{"test"},
+	} {
+		if err := parseCommandLine(args); err == nil {
+			t.Errorf("should return error for %v", args)
+		}
+	}
+
 	args = []string{"test", "invalid-command"}
 	if err := parseCommandLine(args); err == nil {
 		t.Errorf("should return error")
@@ -49,6 +178,32 @@ func TestParseCommandLine(t *testing.T) {
 	}
 }
 
+// TestCommand tests Command.
+func TestCommand(t *testing.T) {
+	c := Command()
+	if c.Name != "fw-id-cli" {
+		t.Errorf("unexpected name: %s", c.Name)
+	}
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		b := &bytes.Buffer{}
+		if err := c.Completion(b, shell); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"status", "support-bundle", "logged-in", "keepalive-warning"} {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("%s completion should contain %s", shell, want)
+			}
+		}
+	}
+	b := &bytes.Buffer{}
+	if err := c.ManPage(b, 1, "test", "2024-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `.SS "config convert"`) {
+		t.Errorf("man page should contain config convert: %s", b)
+	}
+}
+
 // TestPrintStatus tests printStatus.
 func TestPrintStatus(t *testing.T) {
 	// create status and output buffer
@@ -77,20 +232,30 @@ Login State:        unknown
 	got = b.String()
 	want = `Trusted Network:    unknown
 Login State:        unknown
+Agent State:        unknown
+Source IP:
+Last Login:
 Last Keep-Alive:
+Login Failures:     0
 Kerberos TGT:
 - Start Time:
 - End Time:
 Config:             null
+Config Signature:   unknown
+Log Level:
 `
 	if got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
 
 	// verbose, timestamps != 0
+	s.SourceIP = "192.168.1.10"
+	s.LastLogin = 4
 	s.LastKeepAlive = 3
 	s.KerberosTGT.StartTime = 1
 	s.KerberosTGT.EndTime = 2
+	s.LogLevel = "debug"
+	s.LoginFailures = 2
 
 	b.Reset()
 	if err := printStatus(b, s, true); err != nil {
@@ -100,31 +265,167 @@ Config:             null
 	got = b.String()
 	want = fmt.Sprintf(`Trusted Network:    unknown
 Login State:        unknown
+Agent State:        unknown
+Source IP:          192.168.1.10
+Last Login:         %s
 Last Keep-Alive:    %s
+Login Failures:     2
 Kerberos TGT:
 - Start Time:       %s
 - End Time:         %s
 Config:             null
-`, time.Unix(3, 0), time.Unix(1, 0), time.Unix(2, 0))
+Config Signature:   unknown
+Log Level:          debug
+`, time.Unix(4, 0), time.Unix(3, 0), time.Unix(1, 0), time.Unix(2, 0))
+
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestPrintStatusGerman tests printStatus with German messages.
+func TestPrintStatusGerman(t *testing.T) {
+	i18n.SetLanguage(i18n.German)
+	defer i18n.SetLanguage(i18n.English)
+
+	s := status.New()
+	s.TrustedNetwork = status.TrustedNetworkTrusted
+	s.LoginState = status.LoginStateLoggedIn
+	s.AgentState = status.AgentStateLoggedIn
+	s.LoginFailures = 1
+	b := &bytes.Buffer{}
+	if err := printStatus(b, s, true); err != nil {
+		t.Error(err)
+	}
 
+	got := b.String()
+	want := `Vertrauenswürdiges Netzwerk:   vertrauenswürdig
+Anmeldestatus:                 angemeldet
+Agent-Status:                  angemeldet
+Quell-IP:
+Letzte Anmeldung:
+Letztes Keep-Alive:
+Fehlgeschlagene Anmeldungen:   1
+Kerberos-TGT:
+- Beginn:
+- Ende:
+Konfiguration:                 null
+Konfigurationssignatur:        unbekannt
+Log-Level:
+`
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestPrintHistory tests printHistory.
+func TestPrintHistory(t *testing.T) {
+	// test empty
+	b := &bytes.Buffer{}
+	if err := printHistory(b, nil); err != nil || b.Len() != 0 {
+		t.Errorf("got %s, %v, want empty", b, err)
+	}
+
+	// test events
+	t1 := time.Unix(1, 0)
+	t2 := time.Unix(2, 0)
+	events := []*history.Event{
+		{Time: t1, Type: history.TypeTND, Message: "Trusted network status changed",
+			Details: map[string]string{"TrustedNetwork": "trusted"}},
+		{Time: t2, Type: history.TypeLogin, Message: "Login failed", Error: "test error",
+			Details: map[string]string{"b": "2", "a": "1"}},
+	}
+	if err := printHistory(b, events); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	want := fmt.Sprintf(`%s  tnd         Trusted network status changed (TrustedNetwork=trusted)
+%s  login       Login failed (a=1, b=2): test error
+`, t1.Format(time.DateTime), t2.Format(time.DateTime))
 	if got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
 }
 
+// TestConvertConfig tests convertConfig.
+func TestConvertConfig(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(input, []byte(`{
+		"ServiceURL": "https://myservice.mycompany.com:443",
+		"Realm": "MYKERBEROSREALM.COM",
+		"TND": {"HTTPSServers": [{"URL": "https://tnd1.mycompany.com:443", "Hash": "ABCDEF"}]}
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// test errors
+	b := &bytes.Buffer{}
+	if err := convertConfig(b, filepath.Join(dir, "does-not-exist"), "", ""); err == nil {
+		t.Error("missing input should fail")
+	}
+	if err := convertConfig(b, input, "", "invalid"); err == nil {
+		t.Error("invalid format should fail")
+	}
+	if err := convertConfig(b, input, filepath.Join(dir, "does-not-exist", "out.yaml"), ""); err == nil {
+		t.Error("invalid output should fail")
+	}
+
+	// test output to writer
+	b.Reset()
+	if err := convertConfig(b, input, "", "toml"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.NewFromTOML(b.Bytes()); err != nil {
+		t.Errorf("output should be valid toml: %v", err)
+	}
+
+	// test output to file
+	output := filepath.Join(dir, "config.yaml")
+	if err := convertConfig(b, input, output, ""); err != nil {
+		t.Fatal(err)
+	}
+	c, err := config.Load(output)
+	if err != nil || !c.Valid() {
+		t.Errorf("output should be valid yaml config: %v", err)
+	}
+}
+
 // testClient is a Client for testing.
 type testClient struct {
 	status *status.Status
 	sub    chan *status.Status
+	events []*history.Event
 	err    error
+
+	level    string
+	duration time.Duration
+	bundle   []byte
 }
 
 func (t *testClient) Ping() error                             { return t.err }
 func (t *testClient) Query() (*status.Status, error)          { return t.status, t.err }
 func (t *testClient) Subscribe() (chan *status.Status, error) { return t.sub, t.err }
 func (t *testClient) ReLogin() error                          { return t.err }
+func (t *testClient) Logout() error                           { return t.err }
+func (t *testClient) Pause() error                            { return t.err }
+func (t *testClient) Resume() error                           { return t.err }
 func (t *testClient) Close() error                            { return t.err }
 
+func (t *testClient) GetHistory(time.Time) ([]*history.Event, error) {
+	return t.events, t.err
+}
+
+func (t *testClient) CollectDiagnostics() ([]byte, error) {
+	return t.bundle, t.err
+}
+
+func (t *testClient) SetLogLevel(level string, duration time.Duration) error {
+	t.level = level
+	t.duration = duration
+	return t.err
+}
+
 // TestRunCommand tests runCommand.
 func TestRunCommand(t *testing.T) {
 	// create test client
@@ -142,6 +443,15 @@ func TestRunCommand(t *testing.T) {
 	if err := runCommand(c, "relogin"); err == nil {
 		t.Errorf("command should fail")
 	}
+	if err := runCommand(c, "history"); err == nil {
+		t.Errorf("command should fail")
+	}
+	if err := runCommand(c, "debug"); err == nil {
+		t.Errorf("command should fail")
+	}
+	if err := runCommand(c, "support-bundle"); err == nil {
+		t.Errorf("command should fail")
+	}
 
 	// test unknown command
 	if err := runCommand(c, "unknown-command"); err != nil {
@@ -153,6 +463,8 @@ func TestRunCommand(t *testing.T) {
 
 	// test query
 	c.status = status.New()
+	c.status.TrustedNetwork = status.TrustedNetworkTrusted
+	c.status.LoginState = status.LoginStateLoggedIn
 	if err := runCommand(c, "status"); err != nil {
 		t.Errorf("command should not fail")
 	}
@@ -179,4 +491,259 @@ func TestRunCommand(t *testing.T) {
 	if err := runCommand(c, "relogin"); err != nil {
 		t.Errorf("command should not fail")
 	}
+
+	// test history
+	c.events = []*history.Event{history.NewEvent(history.TypeLogin, "test")}
+	historySince = time.Hour
+	if err := runCommand(c, "history"); err != nil {
+		t.Errorf("command should not fail")
+	}
+	historySince = 0
+
+	// test history with json output
+	json = true
+	if err := runCommand(c, "history"); err != nil {
+		t.Errorf("command should not fail")
+	}
+	json = oldJSON
+
+	// test support bundle
+	c.bundle = []byte("bundle")
+	bundleOutput = filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if err := runCommand(c, "support-bundle"); err != nil {
+		t.Errorf("command should not fail")
+	}
+	if b, err := os.ReadFile(bundleOutput); err != nil || string(b) != "bundle" {
+		t.Errorf("invalid support bundle: %s, %v", b, err)
+	}
+	bundleOutput = ""
+}
+
+// TestRunDoctor tests runDoctor.
+func TestRunDoctor(t *testing.T) {
+	// test without agent
+	b := &bytes.Buffer{}
+	if err := runDoctor(b, nil); err == nil {
+		t.Error("doctor should fail without agent")
+	}
+	if !strings.Contains(b.String(), "[FAIL] agent:") {
+		t.Errorf("unexpected output: %s", b)
+	}
+
+	// test with agent error and json output
+	oldJSON := json
+	json = true
+	b.Reset()
+	if err := runDoctor(b, &testClient{err: errors.New("test error")}); err == nil {
+		t.Error("doctor should fail with agent error")
+	}
+	if !strings.Contains(b.String(), `"Result": "fail"`) {
+		t.Errorf("unexpected output: %s", b)
+	}
+	json = oldJSON
+}
+
+// TestGetStatusExitCode tests the exit codes of getStatus.
+func TestGetStatusExitCode(t *testing.T) {
+	// test agent not running
+	c := &testClient{err: errors.New("test error")}
+	var exitErr *exitError
+	if err := getStatus(c); !errors.As(err, &exitErr) || exitErr.code != ExitNotRunning {
+		t.Errorf("got %v, want exit code %d", err, ExitNotRunning)
+	}
+
+	// test states
+	c.err = nil
+	for _, test := range []struct {
+		trusted status.TrustedNetwork
+		login   status.LoginState
+		want    int
+	}{
+		{status.TrustedNetworkUnknown, status.LoginStateUnknown, ExitUntrusted},
+		{status.TrustedNetworkNotTrusted, status.LoginStateLoggedOut, ExitUntrusted},
+		{status.TrustedNetworkTrusted, status.LoginStateLoggedOut, ExitLoggedOut},
+		{status.TrustedNetworkTrusted, status.LoginStateLoggingIn, ExitLoggedOut},
+		{status.TrustedNetworkTrusted, status.LoginStateLoggedIn, ExitLoggedIn},
+	} {
+		c.status = status.New()
+		c.status.TrustedNetwork = test.trusted
+		c.status.LoginState = test.login
+		if got := statusExitCode(c.status); got != test.want {
+			t.Errorf("got %d, want %d for %v", got, test.want, test)
+		}
+
+		err := getStatus(c)
+		if test.want == ExitLoggedIn {
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			continue
+		}
+		if !errors.As(err, &exitErr) || exitErr.code != test.want || exitErr.err != nil {
+			t.Errorf("got %v, want exit code %d", err, test.want)
+		}
+	}
+}
+
+// TestWait tests wait.
+func TestWait(t *testing.T) {
+	defer func() {
+		waitState = WaitStateLoggedIn
+		waitTimeout = 0
+	}()
+	var exitErr *exitError
+
+	// test agent not running
+	c := &testClient{err: errors.New("test error")}
+	if err := wait(c); !errors.As(err, &exitErr) || exitErr.code != ExitNotRunning {
+		t.Errorf("got %v, want exit code %d", err, ExitNotRunning)
+	}
+
+	// test states reached
+	c.err = nil
+	for state, s := range map[string]*status.Status{
+		WaitStateLoggedIn:  {LoginState: status.LoginStateLoggedIn},
+		WaitStateLoggedOut: {LoginState: status.LoginStateLoggedOut},
+		WaitStateTrusted:   {TrustedNetwork: status.TrustedNetworkTrusted},
+		WaitStateUntrusted: {TrustedNetwork: status.TrustedNetworkNotTrusted},
+	} {
+		waitState = state
+		c.sub = make(chan *status.Status, 2)
+		c.sub <- status.New()
+		c.sub <- s
+		if err := wait(c); err != nil {
+			t.Errorf("unexpected error for state %s: %v", state, err)
+		}
+	}
+
+	// test closed updates
+	waitState = WaitStateLoggedIn
+	c.sub = make(chan *status.Status)
+	close(c.sub)
+	if err := wait(c); !errors.As(err, &exitErr) || exitErr.code != ExitNotRunning {
+		t.Errorf("got %v, want exit code %d", err, ExitNotRunning)
+	}
+
+	// test timeout
+	waitTimeout = time.Millisecond
+	c.sub = make(chan *status.Status)
+	if err := wait(c); !errors.As(err, &exitErr) || exitErr.code != ExitTimeout {
+		t.Errorf("got %v, want exit code %d", err, ExitTimeout)
+	}
+}
+
+// TestParseFields tests parseFields.
+func TestParseFields(t *testing.T) {
+	got, err := parseFields("loginstate, TrustedNetwork")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"LoginState", "TrustedNetwork"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, f := range []string{"", "invalid", "LoginState,"} {
+		if _, err := parseFields(f); err == nil {
+			t.Errorf("invalid fields %q should return error", f)
+		}
+	}
+}
+
+// TestPrintStatusUpdate tests printStatusUpdate.
+func TestPrintStatusUpdate(t *testing.T) {
+	defer func() {
+		monitorFields = statusFields
+		json = false
+	}()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+
+	prev := status.New()
+	s := status.New()
+	s.LoginState = status.LoginStateLoggedIn
+	s.LastLogin = now.Unix()
+
+	// test compact output of initial status
+	b := &bytes.Buffer{}
+	if err := printStatusUpdate(b, nil, prev, now); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "2024-01-02 03:04:05  TrustedNetwork=\"unknown\" LoginState=\"unknown\"") ||
+		strings.Count(b.String(), "=") != len(statusFields) {
+		t.Errorf("unexpected output: %s", b)
+	}
+
+	// test compact output of changes
+	b.Reset()
+	if err := printStatusUpdate(b, prev, s, now); err != nil {
+		t.Fatal(err)
+	}
+	want := "2024-01-02 03:04:05  LoginState=\"logged in\" LastLogin=\"2024-01-02 03:04:05\"\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+
+	// test no changes
+	b.Reset()
+	if err := printStatusUpdate(b, s, s, now); err != nil || b.Len() != 0 {
+		t.Errorf("unexpected output: %s, %v", b, err)
+	}
+
+	// test no changes in selected fields
+	monitorFields = []string{"TrustedNetwork"}
+	b.Reset()
+	if err := printStatusUpdate(b, prev, s, now); err != nil || b.Len() != 0 {
+		t.Errorf("unexpected output: %s, %v", b, err)
+	}
+
+	// test json output of changes in selected fields
+	json = true
+	monitorFields = []string{"LoginState", "SourceIP"}
+	b.Reset()
+	if err := printStatusUpdate(b, prev, s, now); err != nil {
+		t.Fatal(err)
+	}
+	got := &statusUpdate{}
+	if err := stdjson.Unmarshal(b.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Time.Equal(now) ||
+		!slices.Equal(got.Changed, []string{"LoginState"}) ||
+		len(got.Status) != 2 ||
+		string(got.Status["LoginState"]) != "3" ||
+		string(got.Status["SourceIP"]) != `""` {
+		t.Errorf("unexpected json output: %s", b)
+	}
+	if strings.Count(b.String(), "\n") != 1 {
+		t.Errorf("json output should be one line: %s", b)
+	}
+}
+
+// TestExit tests exit.
+func TestExit(t *testing.T) {
+	defer func() { osExit = os.Exit }()
+	code := -1
+	osExit = func(c int) { code = c }
+
+	// test no error and help, should not exit
+	for _, err := range []error{nil, flag.ErrHelp} {
+		exit(err)
+		if code != -1 {
+			t.Errorf("got exit code %d, want no exit", code)
+		}
+	}
+
+	// test exit errors
+	for _, want := range []int{ExitLoggedOut, ExitNotRunning} {
+		exit(&exitError{code: want})
+		if code != want {
+			t.Errorf("got exit code %d, want %d", code, want)
+		}
+	}
+
+	// test other errors, should not be mistaken for a status
+	exit(errors.New("test error"))
+	if code != ExitError {
+		t.Errorf("got exit code %d, want %d", code, ExitError)
+	}
 }
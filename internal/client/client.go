@@ -2,11 +2,15 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
@@ -23,10 +27,21 @@ import (
 type Client struct {
 	config    *config.Config
 	keepAlive time.Duration
-	results   chan status.LoginState
+	results   chan *Result
+	check     chan struct{}
 	done      chan struct{}
 	closed    chan struct{}
 
+	// restored session, see Restore
+	restoredAt time.Time
+	restoredIP string
+
+	// address of the identity service resolved at last login, local
+	// source IP used to reach it and whether the client is logged in
+	serviceAddr string
+	sourceIP    string
+	loggedIn    bool
+
 	// current kerberos ccache and config
 	// protected by mutex
 	mutex    sync.Mutex
@@ -45,19 +60,231 @@ const (
 	BackendError       Error = 101
 )
 
+// Error classes.
+const (
+	ClassUserNotSet    = "user_not_set"
+	ClassToken         = "token"
+	ClassCommunication = "communication"
+	ClassBackend       = "backend"
+	ClassUnknown       = "unknown"
+)
+
+// ErrorClasses returns all error classes.
+func ErrorClasses() []string {
+	return []string{
+		ClassUserNotSet,
+		ClassToken,
+		ClassCommunication,
+		ClassBackend,
+		ClassUnknown,
+	}
+}
+
+// ErrorCode returns the error code of the client error err or 0 if err has
+// no error code.
+func ErrorCode(err error) Error {
+	if err == nil {
+		return 0
+	}
+	var code Error
+	if _, scanErr := fmt.Sscanf(err.Error(), "%d:", &code); scanErr != nil {
+		return 0
+	}
+	return code
+}
+
+// ErrorClass returns the class of the client error err, e.g., ClassToken for
+// kerberos token errors.
+func ErrorClass(err error) string {
+	switch ErrorCode(err) {
+	case UserNotSet:
+		return ClassUserNotSet
+	case TokenError:
+		return ClassToken
+	case CommunicationError:
+		return ClassCommunication
+	case BackendError:
+		return ClassBackend
+	}
+	return ClassUnknown
+}
+
 // LoginResponse is a login response.
 type LoginResponse struct {
-	KeepAlive int `json:"keep-alive"`
+	KeepAlive int    `json:"keep-alive"`
+	SessionID string `json:"session-id"`
+}
+
+// Result is a client result.
+type Result struct {
+	// LoginState is the current login state.
+	LoginState status.LoginState
+
+	// KeepAlive is the current keep-alive time.
+	KeepAlive time.Duration
+
+	// SessionID is the session ID sent by the service at login, if any.
+	SessionID string
+
+	// Err is the error of a failed login.
+	Err error
+
+	// Restored specifies whether the login state was restored from a
+	// previous session without a login request.
+	Restored bool
+
+	// SourceIP is the local source IP used to reach the identity service.
+	SourceIP string
 }
 
 // sendResult sends a result over the results channel.
-func (c *Client) sendResult(result status.LoginState) {
+func (c *Client) sendResult(result *Result) {
 	select {
 	case c.results <- result:
 	case <-c.done:
 	}
 }
 
+// sendState sends a result with login state over the results channel.
+func (c *Client) sendState(loginState status.LoginState) {
+	c.sendResult(&Result{
+		LoginState: loginState,
+		KeepAlive:  c.keepAlive,
+		SourceIP:   c.sourceIP,
+	})
+}
+
+// sendError sends a "logged out" result with error err over the results
+// channel.
+func (c *Client) sendError(err error) {
+	c.sendResult(&Result{
+		LoginState: status.LoginStateLoggedOut,
+		KeepAlive:  c.keepAlive,
+		Err:        err,
+	})
+}
+
+// sourceIPInterval is the interval for checking the source IP used to reach
+// the identity service.
+var sourceIPInterval = 10 * time.Second
+
+// sourceIPTimeout is the timeout for resolving the host of the identity
+// service and looking up the source IP used to reach it.
+var sourceIPTimeout = 5 * time.Second
+
+// context returns a context that times out after timeout and is canceled
+// when the client is stopped.
+func (c *Client) context(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// lookupHost is net.DefaultResolver.LookupHost for testing.
+var lookupHost = func(ctx context.Context, host string) ([]string, error) {
+	return net.DefaultResolver.LookupHost(ctx, host)
+}
+
+// lookupSourceIP returns the local source IP used to reach address. It only
+// looks up the route to address and does not send any packets.
+var lookupSourceIP = func(ctx context.Context, address string) (string, error) {
+	d := net.Dialer{Timeout: sourceIPTimeout}
+	conn, err := d.DialContext(ctx, "udp", address)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return "", errors.New("invalid local address")
+	}
+	return addr.IP.String(), nil
+}
+
+// resolveService returns the address of the identity service with the IP
+// of its host and its port or an empty string if it cannot be resolved.
+func (c *Client) resolveService() string {
+	u, err := url.Parse(c.config.ServiceURL)
+	if err != nil || u.Hostname() == "" {
+		log.WithField("service_url", c.config.ServiceURL).
+			Debug("Agent could not get host of identity service")
+		return ""
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	ctx, cancel := c.context(sourceIPTimeout)
+	defer cancel()
+	hosts, err := lookupHost(ctx, u.Hostname())
+	if err != nil || len(hosts) == 0 {
+		log.WithError(err).Debug("Agent could not resolve host of identity service")
+		return ""
+	}
+	return net.JoinHostPort(hosts[0], port)
+}
+
+// getSourceIP returns the local source IP used to reach the identity service
+// or an empty string if it is unknown. The host of the identity service is
+// resolved at login, it is only resolved here if that failed.
+func (c *Client) getSourceIP() string {
+	if c.serviceAddr == "" {
+		c.serviceAddr = c.resolveService()
+		if c.serviceAddr == "" {
+			return ""
+		}
+	}
+	ctx, cancel := c.context(sourceIPTimeout)
+	defer cancel()
+	ip, err := lookupSourceIP(ctx, c.serviceAddr)
+	if err != nil {
+		log.WithError(err).Debug("Agent could not get source IP")
+		return ""
+	}
+	return ip
+}
+
+// checkSourceIP checks if the source IP used to reach the identity service
+// changed since the last login and, if so, logs out if logged in and resets
+// timer to log in immediately, so the identity service removes the old IP and
+// learns the new IP.
+func (c *Client) checkSourceIP(timer *time.Timer) {
+	ip := c.getSourceIP()
+	if ip == "" || ip == c.sourceIP {
+		return
+	}
+	if c.sourceIP == "" {
+		// source IP was unknown, e.g., because the lookup at login
+		// failed, remember it to detect later changes
+		log.WithField("source_ip", ip).Debug("Agent got source IP")
+		c.sourceIP = ip
+		return
+	}
+	log.WithFields(log.Fields{
+		"old_source_ip": c.sourceIP,
+		"source_ip":     ip,
+	}).Info("Agent got source IP change, logging in immediately")
+	if c.loggedIn {
+		if err := c.logout(); err != nil {
+			log.WithError(err).WithField("error_code", ErrorCode(err)).
+				Debug("Agent got error during logout after source IP change")
+		}
+	}
+	c.sourceIP = ip
+	timer.Reset(0)
+}
+
 // httpNewRequest is http.NewRequest for testing.
 var httpNewRequest = http.NewRequest
 
@@ -121,8 +348,10 @@ func (c *Client) doServiceRequest(api string, timeout time.Duration) (response *
 
 // login sends a login request to the identity service.
 func (c *Client) login() (err error) {
-	// signal "logging in" state
-	c.sendResult(status.LoginStateLoggingIn)
+	// signal "logging in" state with current source IP
+	c.serviceAddr = c.resolveService()
+	c.sourceIP = c.getSourceIP()
+	c.sendState(status.LoginStateLoggingIn)
 
 	// send login request
 	response, err := c.doServiceRequest("/login", c.config.GetLoginTimeout())
@@ -136,7 +365,7 @@ func (c *Client) login() (err error) {
 		}()
 	}
 	if err != nil || response.StatusCode != 200 {
-		c.sendResult(status.LoginStateLoggedOut)
+		c.sendError(err)
 		return
 	}
 
@@ -145,7 +374,7 @@ func (c *Client) login() (err error) {
 	body, err = io.ReadAll(response.Body)
 	if err != nil {
 		err = fmt.Errorf("%d: error reading login response body: %w", BackendError, err)
-		c.sendResult(status.LoginStateLoggedOut)
+		c.sendError(err)
 		return
 	}
 
@@ -155,7 +384,7 @@ func (c *Client) login() (err error) {
 	if err != nil {
 		// assume login successful but response has no parseable result
 		log.WithError(err).Error("Agent could not parse login response")
-		c.sendResult(status.LoginStateLoggedIn)
+		c.sendState(status.LoginStateLoggedIn)
 		return
 	}
 
@@ -164,27 +393,33 @@ func (c *Client) login() (err error) {
 		c.keepAlive = time.Duration(responseJSON.KeepAlive) * time.Minute
 	} else {
 		log.WithFields(log.Fields{
-			"keepAlive": responseJSON.KeepAlive,
-			"current":   c.keepAlive,
-			"default":   c.config.KeepAlive,
+			"keep_alive":         responseJSON.KeepAlive,
+			"current_keep_alive": c.keepAlive,
+			"default_keep_alive": c.config.KeepAlive,
 		}).Error("Agent received invalid keep alive time at login, using current")
 	}
 
 	// signal "logged in" state
-	c.sendResult(status.LoginStateLoggedIn)
+	c.sendResult(&Result{
+		LoginState: status.LoginStateLoggedIn,
+		KeepAlive:  c.keepAlive,
+		SessionID:  responseJSON.SessionID,
+		SourceIP:   c.sourceIP,
+	})
 	return
 }
 
 // logout sends a logout request to the identity service.
 func (c *Client) logout() (err error) {
 	// signal "logging out" state
-	c.sendResult(status.LoginStateLoggingOut)
+	c.sendState(status.LoginStateLoggingOut)
 
 	// send logout request
 	_, err = c.doServiceRequest("/logout", c.config.GetLogoutTimeout())
 
 	// signal "logged out" state
-	c.sendResult(status.LoginStateLoggedOut)
+	c.loggedIn = false
+	c.sendState(status.LoginStateLoggedOut)
 	return
 }
 
@@ -193,15 +428,57 @@ func (c *Client) start() {
 	defer close(c.closed)
 	defer close(c.results)
 
-	timer := time.NewTimer(0)
+	// check restored session, only log in immediately if there is no
+	// valid restored session or the source IP changed, e.g., because of a
+	// new DHCP lease after a reboot
+	next := time.Duration(0)
+	if !c.restoredAt.IsZero() {
+		next = time.Until(c.restoredAt.Add(c.keepAlive))
+	}
+	if next > 0 {
+		c.serviceAddr = c.resolveService()
+		c.sourceIP = c.getSourceIP()
+		if c.sourceIP == "" || c.sourceIP != c.restoredIP {
+			log.WithFields(log.Fields{
+				"restored_source_ip": c.restoredIP,
+				"source_ip":          c.sourceIP,
+			}).Info("Agent restored session with other source IP, logging in immediately")
+			next = 0
+		}
+	}
+	if next > 0 {
+		log.WithField("next", next).
+			Info("Agent restored valid session, delaying next login")
+		c.loggedIn = true
+		c.sendResult(&Result{
+			LoginState: status.LoginStateLoggedIn,
+			KeepAlive:  c.keepAlive,
+			Restored:   true,
+			SourceIP:   c.sourceIP,
+		})
+	} else {
+		next = 0
+	}
+
+	timer := time.NewTimer(next)
+	check := time.NewTicker(sourceIPInterval)
+	defer check.Stop()
 	for {
 		select {
+		case <-check.C:
+			c.checkSourceIP(timer)
+
+		case <-c.check:
+			c.checkSourceIP(timer)
+
 		case <-timer.C:
 			err := c.login()
+			c.loggedIn = err == nil
 			if err != nil {
 				// error during login attempt, log error and
 				// reset timer to retry timer value
-				log.WithError(err).Error("Agent got error during method login")
+				log.WithError(err).WithField("error_code", ErrorCode(err)).
+					Error("Agent got error during method login")
 				timer.Reset(c.config.GetRetryTimer())
 				break
 			}
@@ -214,7 +491,8 @@ func (c *Client) start() {
 				// caused by not being connected to the trusted
 				// network anymore, so only log with debug
 				// level to avoid user confusion
-				log.WithError(err).Debug("Agent got error during method logout")
+				log.WithError(err).WithField("error_code", ErrorCode(err)).
+					Debug("Agent got error during method logout")
 			}
 			if !timer.Stop() {
 				<-timer.C
@@ -235,11 +513,34 @@ func (c *Client) Stop() {
 	<-c.closed
 }
 
+// CheckSourceIP triggers an immediate check of the source IP used to reach
+// the identity service, e.g., after a network change. If it changed, the
+// client logs out and logs in again immediately. Checks that are not handled yet are merged.
+func (c *Client) CheckSourceIP() {
+	select {
+	case c.check <- struct{}{}:
+	default:
+	}
+}
+
 // Results returns the result channel.
-func (c *Client) Results() chan status.LoginState {
+func (c *Client) Results() chan *Result {
 	return c.results
 }
 
+// Restore restores a session of a previous client with the last keep-alive at
+// time lastKeepAlive, keep-alive time keepAlive and source IP sourceIP. If the
+// session is still valid and the source IP did not change when the client
+// starts, the client does not log in immediately but only when the next
+// keep-alive is due. It must be called before Start.
+func (c *Client) Restore(lastKeepAlive time.Time, keepAlive time.Duration, sourceIP string) {
+	c.restoredAt = lastKeepAlive
+	c.restoredIP = sourceIP
+	if keepAlive > 0 {
+		c.keepAlive = keepAlive
+	}
+}
+
 // SetCCache sets the kerberos CCache in the client.
 func (c *Client) SetCCache(ccache *credentials.CCache) {
 	c.mutex.Lock()
@@ -275,7 +576,8 @@ func (c *Client) GetKrb5Conf() *krbConfig.Config {
 // NewClient returns a new Client.
 func NewClient(config *config.Config, ccache *credentials.CCache, krb5conf *krbConfig.Config) *Client {
 	return &Client{
-		results:   make(chan status.LoginState),
+		results:   make(chan *Result),
+		check:     make(chan struct{}, 1),
 		done:      make(chan struct{}),
 		closed:    make(chan struct{}),
 		keepAlive: config.GetKeepAlive(),
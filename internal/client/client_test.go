@@ -2,8 +2,10 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -44,6 +46,43 @@ func getTestCCache(t *testing.T) *credentials.CCache {
 	return ccache
 }
 
+// TestErrorCode tests ErrorCode.
+func TestErrorCode(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		want Error
+	}{
+		{nil, 0},
+		{errors.New("test error"), 0},
+		{fmt.Errorf("%d: test error", TokenError), TokenError},
+		{fmt.Errorf("%d: test error", BackendError), BackendError},
+	} {
+		if got := ErrorCode(test.err); got != test.want {
+			t.Errorf("%v: got %d, want %d", test.err, got, test.want)
+		}
+	}
+}
+
+// TestErrorClass tests ErrorClass.
+func TestErrorClass(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		want string
+	}{
+		{nil, ClassUnknown},
+		{errors.New("test error"), ClassUnknown},
+		{fmt.Errorf("%d: test error", 42), ClassUnknown},
+		{fmt.Errorf("%d: test error", UserNotSet), ClassUserNotSet},
+		{fmt.Errorf("%d: test error", TokenError), ClassToken},
+		{fmt.Errorf("%d: test error", CommunicationError), ClassCommunication},
+		{fmt.Errorf("%d: test error", BackendError), ClassBackend},
+	} {
+		if got := ErrorClass(test.err); got != test.want {
+			t.Errorf("%v: got %s, want %s", test.err, got, test.want)
+		}
+	}
+}
+
 // TestClientDoServiceRequestErrors tests doServiceRequest of Client, errors.
 func TestClientDoServiceRequestErrors(t *testing.T) {
 	t.Run("invalid ccache", func(t *testing.T) {
@@ -109,6 +148,214 @@ func TestClientDoServiceRequestErrors(t *testing.T) {
 	})
 }
 
+// TestClientContext tests context of Client.
+func TestClientContext(t *testing.T) {
+	c := NewClient(config.Default(), nil, nil)
+
+	// test timeout
+	ctx, cancel := c.context(time.Millisecond)
+	<-ctx.Done()
+	cancel()
+
+	// test client stop, should cancel context
+	ctx, cancel = c.context(time.Hour)
+	defer cancel()
+	close(c.done)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Error("context should be canceled when client is stopped")
+	}
+}
+
+// TestClientResolveService tests resolveService of Client.
+func TestClientResolveService(t *testing.T) {
+	oldLookupHost := lookupHost
+	defer func() { lookupHost = oldLookupHost }()
+
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		return []string{host}, nil
+	}
+
+	// test invalid service urls
+	c := NewClient(config.Default(), nil, nil)
+	for _, u := range []string{"", "::invalid", "/path/only"} {
+		c.config.ServiceURL = u
+		if got := c.resolveService(); got != "" {
+			t.Errorf("got %s, want empty string", got)
+		}
+	}
+
+	// test valid service urls
+	for u, want := range map[string]string{
+		"https://service.example.com":      "service.example.com:443",
+		"http://service.example.com":       "service.example.com:80",
+		"https://service.example.com:8443": "service.example.com:8443",
+		"https://[2001:db8::1]/identity":   "[2001:db8::1]:443",
+	} {
+		c.config.ServiceURL = u
+		if got := c.resolveService(); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+
+	// test lookup error
+	lookupHost = func(context.Context, string) ([]string, error) {
+		return nil, errors.New("test error")
+	}
+	if got := c.resolveService(); got != "" {
+		t.Errorf("got %s, want empty string", got)
+	}
+
+	// test default lookup with loopback address
+	lookupHost = oldLookupHost
+	c.config.ServiceURL = "https://127.0.0.1"
+	if got := c.resolveService(); got != "127.0.0.1:443" {
+		t.Errorf("got %s, want 127.0.0.1:443", got)
+	}
+}
+
+// TestClientGetSourceIP tests getSourceIP of Client.
+func TestClientGetSourceIP(t *testing.T) {
+	oldLookupHost := lookupHost
+	oldLookupSourceIP := lookupSourceIP
+	defer func() {
+		lookupHost = oldLookupHost
+		lookupSourceIP = oldLookupSourceIP
+	}()
+
+	resolved := 0
+	lookupHost = func(context.Context, string) ([]string, error) {
+		resolved++
+		return []string{"192.0.2.1"}, nil
+	}
+	address := ""
+	lookupSourceIP = func(_ context.Context, a string) (string, error) {
+		address = a
+		return "192.168.1.10", nil
+	}
+
+	// test invalid service url
+	c := NewClient(config.Default(), nil, nil)
+	if got := c.getSourceIP(); got != "" {
+		t.Errorf("got %s, want empty string", got)
+	}
+
+	// test unresolved service, should resolve it once
+	c.config.ServiceURL = "https://service.example.com"
+	for i := 0; i < 2; i++ {
+		if got := c.getSourceIP(); got != "192.168.1.10" {
+			t.Errorf("got %s, want 192.168.1.10", got)
+		}
+	}
+	if resolved != 1 || address != "192.0.2.1:443" {
+		t.Errorf("got %d, %s, want 1, 192.0.2.1:443", resolved, address)
+	}
+
+	// test lookup error
+	lookupSourceIP = func(context.Context, string) (string, error) {
+		return "", errors.New("test error")
+	}
+	if got := c.getSourceIP(); got != "" {
+		t.Errorf("got %s, want empty string", got)
+	}
+
+	// test default lookup with loopback address
+	lookupSourceIP = oldLookupSourceIP
+	c.serviceAddr = "127.0.0.1:443"
+	if got := c.getSourceIP(); got != "127.0.0.1" {
+		t.Errorf("got %s, want 127.0.0.1", got)
+	}
+}
+
+// TestClientCheckSourceIP tests checkSourceIP of Client.
+func TestClientCheckSourceIP(t *testing.T) {
+	oldLookupSourceIP := lookupSourceIP
+	defer func() { lookupSourceIP = oldLookupSourceIP }()
+
+	sourceIP := "192.168.1.10"
+	lookupSourceIP = func(context.Context, string) (string, error) {
+		return sourceIP, nil
+	}
+
+	c := NewClient(config.Default(), nil, nil)
+	c.serviceAddr = "192.0.2.1:443"
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	fired := func() bool {
+		select {
+		case <-timer.C:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}
+
+	// test unknown source ip, should store it and not reset timer
+	c.checkSourceIP(timer)
+	if fired() || c.sourceIP != "192.168.1.10" {
+		t.Error("source ip should be set and timer should not be reset")
+	}
+
+	// test unchanged source ip, should not reset timer
+	c.checkSourceIP(timer)
+	if fired() {
+		t.Error("timer should not be reset")
+	}
+
+	// test lookup error, should not reset timer
+	sourceIP = ""
+	c.checkSourceIP(timer)
+	if fired() || c.sourceIP != "192.168.1.10" {
+		t.Error("timer and source ip should not be reset")
+	}
+
+	// test changed source ip, should reset timer
+	sourceIP = "10.0.0.2"
+	c.checkSourceIP(timer)
+	if !fired() || c.sourceIP != "10.0.0.2" {
+		t.Error("timer and source ip should be reset")
+	}
+
+	// test changed source ip while logged in, should log out and reset
+	// timer
+	c.loggedIn = true
+	results := make(chan *Result, 2)
+	go func() {
+		results <- <-c.results
+		results <- <-c.results
+	}()
+	sourceIP = "10.0.0.3"
+	c.checkSourceIP(timer)
+	if !fired() || c.sourceIP != "10.0.0.3" || c.loggedIn {
+		t.Error("timer and source ip should be reset and client logged out")
+	}
+	for _, want := range []status.LoginState{
+		status.LoginStateLoggingOut,
+		status.LoginStateLoggedOut,
+	} {
+		if r := <-results; r.LoginState != want || r.SourceIP != "10.0.0.2" {
+			t.Errorf("got %v, want %s with old source ip", r, want)
+		}
+	}
+}
+
+// TestClientCheckSourceIPTrigger tests CheckSourceIP of Client.
+func TestClientCheckSourceIPTrigger(t *testing.T) {
+	c := NewClient(config.Default(), nil, nil)
+
+	// multiple checks should not block and be merged
+	c.CheckSourceIP()
+	c.CheckSourceIP()
+	<-c.check
+	select {
+	case <-c.check:
+		t.Error("checks should be merged")
+	default:
+	}
+}
+
 // TestClientLogin tests login of Client, successful login.
 func TestClientLogin(t *testing.T) {
 	// create server
@@ -135,13 +382,13 @@ func TestClientLogin(t *testing.T) {
 
 	// check "logging in"
 	r := <-client.Results()
-	if r != status.LoginStateLoggingIn {
+	if r.LoginState != status.LoginStateLoggingIn {
 		t.Errorf("client not logging in")
 	}
 
 	// check "logged in" and keep-alive time
 	r = <-client.Results()
-	if r != status.LoginStateLoggedIn {
+	if r.LoginState != status.LoginStateLoggedIn {
 		t.Errorf("client not logged in")
 	}
 	if client.keepAlive != 42*time.Minute {
@@ -176,13 +423,13 @@ func TestClientLoginNoResult(t *testing.T) {
 
 		// check "logging in"
 		r := <-client.Results()
-		if r != status.LoginStateLoggingIn {
+		if r.LoginState != status.LoginStateLoggingIn {
 			t.Errorf("client not logging in")
 		}
 
 		// check "logged in" and keep-alive
 		r = <-client.Results()
-		if r != status.LoginStateLoggedIn {
+		if r.LoginState != status.LoginStateLoggedIn {
 			t.Errorf("client not logged in")
 		}
 		if client.keepAlive != 5*time.Minute {
@@ -227,13 +474,13 @@ func TestClientLoginFailed(t *testing.T) {
 
 		// check "logging in"
 		r := <-client.Results()
-		if r != status.LoginStateLoggingIn {
+		if r.LoginState != status.LoginStateLoggingIn {
 			t.Errorf("client not logging in")
 		}
 
 		// check "logged out" and keep-alive
 		r = <-client.Results()
-		if r != status.LoginStateLoggedOut {
+		if r.LoginState != status.LoginStateLoggedOut {
 			t.Errorf("client not logged out")
 		}
 		if client.keepAlive != 5*time.Minute {
@@ -265,13 +512,13 @@ func TestClientLogout(t *testing.T) {
 
 	// check "logging out"
 	r := <-client.Results()
-	if r != status.LoginStateLoggingOut {
+	if r.LoginState != status.LoginStateLoggingOut {
 		t.Errorf("client not logging out")
 	}
 
 	// check "logged out"
 	r = <-client.Results()
-	if r != status.LoginStateLoggedOut {
+	if r.LoginState != status.LoginStateLoggedOut {
 		t.Errorf("client not logged out")
 	}
 }
@@ -297,13 +544,13 @@ func TestClientStartStop(t *testing.T) {
 
 		// check "logging in"
 		r := <-client.Results()
-		if r != status.LoginStateLoggingIn {
+		if r.LoginState != status.LoginStateLoggingIn {
 			t.Errorf("client not logging in")
 		}
 
 		// check "logged out"
 		r = <-client.Results()
-		if r != status.LoginStateLoggedOut {
+		if r.LoginState != status.LoginStateLoggedOut {
 			t.Errorf("client not logged out")
 		}
 
@@ -328,19 +575,81 @@ func TestClientStartStop(t *testing.T) {
 
 		// check "logging in"
 		r := <-client.Results()
-		if r != status.LoginStateLoggingIn {
+		if r.LoginState != status.LoginStateLoggingIn {
 			t.Errorf("client not logging in")
 		}
 
 		// check "logged in"
 		r = <-client.Results()
-		if r != status.LoginStateLoggedIn {
+		if r.LoginState != status.LoginStateLoggedIn {
 			t.Errorf("client not logged in")
 		}
 
 		client.Stop()
 	})
 
+	t.Run("source ip change", func(t *testing.T) {
+		oldLookupSourceIP := lookupSourceIP
+		oldSourceIPInterval := sourceIPInterval
+		defer func() {
+			lookupSourceIP = oldLookupSourceIP
+			sourceIPInterval = oldSourceIPInterval
+		}()
+		sourceIPs := make(chan string, 1)
+		sourceIPs <- "192.168.1.10"
+		sourceIP := ""
+		lookupSourceIP = func(context.Context, string) (string, error) {
+			select {
+			case sourceIP = <-sourceIPs:
+			default:
+			}
+			return sourceIP, nil
+		}
+		sourceIPInterval = time.Millisecond
+
+		// create server
+		server := initTestServer(`{ "keep-alive": 42 }`)
+		defer server.Close()
+
+		// create config
+		config := config.Default()
+		config.ServiceURL = server.URL
+
+		// create and run client
+		ccache := getTestCCache(t)
+		krb5conf := krbConfig.New()
+		client := NewClient(config, ccache, krb5conf)
+		client.Start()
+
+		// check "logging in" and "logged in" with source ip
+		<-client.Results()
+		r := <-client.Results()
+		if r.LoginState != status.LoginStateLoggedIn ||
+			r.SourceIP != "192.168.1.10" {
+			t.Errorf("client not logged in: %v", r)
+		}
+
+		// change source ip, check logout and immediate login with new
+		// source ip
+		sourceIPs <- "10.0.0.2"
+		for _, want := range []status.LoginState{
+			status.LoginStateLoggingOut,
+			status.LoginStateLoggedOut,
+			status.LoginStateLoggingIn,
+		} {
+			if r := <-client.Results(); r.LoginState != want {
+				t.Errorf("got %s, want %s", r.LoginState, want)
+			}
+		}
+		r = <-client.Results()
+		if r.LoginState != status.LoginStateLoggedIn ||
+			r.SourceIP != "10.0.0.2" {
+			t.Errorf("client not logged in again: %v", r)
+		}
+
+		client.Stop()
+	})
+
 	t.Run("immediate stop without consumer", func(t *testing.T) {
 		// create config
 		config := config.Default()
@@ -354,6 +663,93 @@ func TestClientStartStop(t *testing.T) {
 	})
 }
 
+// TestClientRestore tests Restore of Client.
+func TestClientRestore(t *testing.T) {
+	oldLookupSourceIP := lookupSourceIP
+	defer func() { lookupSourceIP = oldLookupSourceIP }()
+	lookupSourceIP = func(context.Context, string) (string, error) {
+		return "192.168.1.10", nil
+	}
+
+	t.Run("valid session", func(t *testing.T) {
+		// create server that fails all requests
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(404)
+		}))
+		defer server.Close()
+
+		config := config.Default()
+		config.ServiceURL = server.URL
+		client := NewClient(config, getTestCCache(t), krbConfig.New())
+		client.Restore(time.Now(), 10*time.Minute, "192.168.1.10")
+		client.Start()
+
+		// check restored "logged in" without login request
+		r := <-client.Results()
+		if r.LoginState != status.LoginStateLoggedIn || !r.Restored {
+			t.Errorf("client not restored: %v", r)
+		}
+		if r.KeepAlive != 10*time.Minute || r.SourceIP != "192.168.1.10" {
+			t.Errorf("keep-alive time or source ip not restored: %v", r)
+		}
+
+		client.Stop()
+	})
+
+	t.Run("changed source ip", func(t *testing.T) {
+		// create server
+		server := initTestServer(`{ "keep-alive": 42 }`)
+		defer server.Close()
+
+		config := config.Default()
+		config.ServiceURL = server.URL
+		client := NewClient(config, getTestCCache(t), krbConfig.New())
+		client.Restore(time.Now(), 10*time.Minute, "10.0.0.2")
+		client.Start()
+
+		// check immediate login
+		r := <-client.Results()
+		if r.LoginState != status.LoginStateLoggingIn {
+			t.Errorf("client not logging in")
+		}
+		r = <-client.Results()
+		if r.LoginState != status.LoginStateLoggedIn ||
+			r.Restored ||
+			r.SourceIP != "192.168.1.10" {
+			t.Errorf("client not logged in: %v", r)
+		}
+
+		client.Stop()
+	})
+
+	t.Run("expired session", func(t *testing.T) {
+		// create server
+		server := initTestServer(`{ "keep-alive": 42, "session-id": "session" }`)
+		defer server.Close()
+
+		config := config.Default()
+		config.ServiceURL = server.URL
+		client := NewClient(config, getTestCCache(t), krbConfig.New())
+		client.Restore(time.Now().Add(-time.Hour), 10*time.Minute, "192.168.1.10")
+		client.Start()
+
+		// check immediate login
+		r := <-client.Results()
+		if r.LoginState != status.LoginStateLoggingIn {
+			t.Errorf("client not logging in")
+		}
+		r = <-client.Results()
+		if r.LoginState != status.LoginStateLoggedIn ||
+			r.Restored ||
+			r.SessionID != "session" ||
+			r.KeepAlive != 42*time.Minute {
+			t.Errorf("client not logged in: %v", r)
+		}
+
+		client.Stop()
+	})
+}
+
 // TestClientSetGetCCache tests SetCCache and GetCCache of Client.
 func TestClientSetGetCCache(t *testing.T) {
 	client := NewClient(config.Default(), nil, nil)
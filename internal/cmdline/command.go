@@ -0,0 +1,184 @@
+// Package cmdline contains a small command line framework based on the flag
+// package. Commands are defined with their flags and subcommands, usage
+// output, shell completion scripts and man pages are generated from these
+// definitions.
+package cmdline
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Command is a command line command with flags and subcommands.
+type Command struct {
+	// Name is the name of the command.
+	Name string
+
+	// Args is the usage of the positional arguments, e.g., "on|off".
+	Args string
+
+	// Short is a short one-line description of the command.
+	Short string
+
+	// Long is the long description of the command in the man page,
+	// paragraphs are separated by empty lines. If Long is empty, Short is
+	// used.
+	Long string
+
+	// Flags are the flags of the command. If Flags is nil, an empty flag
+	// set is created.
+	Flags *flag.FlagSet
+
+	// FlagValues are the completion values of flags by flag name.
+	FlagValues map[string][]string
+
+	// ArgValues are the completion values of the positional arguments,
+	// ArgFiles specifies whether the positional arguments are files.
+	ArgValues []string
+	ArgFiles  bool
+
+	// Check checks the flags and the positional arguments args after the
+	// flags are parsed. Commands without subcommands and Args do not accept
+	// positional arguments.
+	Check func(args []string) error
+
+	// Commands are the subcommands of the command.
+	Commands []*Command
+
+	// parent is the parent command.
+	parent *Command
+}
+
+// setup sets the parents, flag sets and usage functions of the command and
+// its subcommands.
+func (c *Command) setup() {
+	if c.Flags == nil {
+		name := c.Name
+		if c.parent != nil {
+			name = strings.Join(c.Path()[1:], " ")
+		}
+		c.Flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	}
+	if len(c.Commands) > 0 || c.Args != "" {
+		c.Flags.Usage = c.usage
+	}
+	for _, sub := range c.Commands {
+		sub.parent = c
+		sub.setup()
+	}
+}
+
+// Path returns the names of the command and its parent commands starting
+// with the root command.
+func (c *Command) Path() []string {
+	if c.parent == nil {
+		return []string{c.Name}
+	}
+	return append(c.parent.Path(), c.Name)
+}
+
+// hasFlags returns whether the command has flags.
+func (c *Command) hasFlags() bool {
+	has := false
+	c.Flags.VisitAll(func(*flag.Flag) { has = true })
+	return has
+}
+
+// leaves returns all commands without subcommands below the command.
+func (c *Command) leaves() []*Command {
+	leaves := []*Command{}
+	for _, sub := range c.Commands {
+		if len(sub.Commands) == 0 {
+			leaves = append(leaves, sub)
+			continue
+		}
+		leaves = append(leaves, sub.leaves()...)
+	}
+	return leaves
+}
+
+// all returns the command and all commands below it.
+func (c *Command) all() []*Command {
+	all := []*Command{c}
+	for _, sub := range c.Commands {
+		all = append(all, sub.all()...)
+	}
+	return all
+}
+
+// usage prints the usage of the command with positional arguments or
+// subcommands.
+func (c *Command) usage() {
+	w := c.Flags.Output()
+	usage := func(f string, args ...any) {
+		_, _ = fmt.Fprintf(w, f, args...)
+	}
+	synopsis := []string{c.Flags.Name()}
+	if c.hasFlags() {
+		synopsis = append(synopsis, "[options]")
+	}
+	if len(c.Commands) > 0 {
+		synopsis = append(synopsis, "[command]")
+	} else if c.Args != "" {
+		synopsis = append(synopsis, c.Args)
+	}
+	usage("Usage:\n")
+	usage("  %s\n", strings.Join(synopsis, " "))
+	if c.hasFlags() {
+		usage("\nOptions:\n")
+		c.Flags.PrintDefaults()
+	}
+	if len(c.Commands) > 0 {
+		usage("\nCommands:\n")
+		n := len(c.Path())
+		for _, l := range c.leaves() {
+			name := strings.Join(append(l.Path()[n:], l.Args), " ")
+			usage("  %s\n", strings.TrimSpace(name))
+			usage("        %s\n", l.Short)
+		}
+	}
+}
+
+// Parse parses the command line arguments args of the command and its
+// subcommands and returns the selected command without subcommands.
+func (c *Command) Parse(args []string) (*Command, error) {
+	c.setup()
+	return c.parse(args)
+}
+
+// parse parses the command line arguments args of the command.
+func (c *Command) parse(args []string) (*Command, error) {
+	if err := c.Flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if len(c.Commands) == 0 && c.Args == "" && c.Flags.NArg() > 0 {
+		c.Flags.Usage()
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(c.Flags.Args(), " "))
+	}
+	if c.Check != nil {
+		if err := c.Check(c.Flags.Args()); err != nil {
+			return nil, err
+		}
+	}
+
+	// command without subcommands
+	if len(c.Commands) == 0 {
+		return c, nil
+	}
+
+	// parse subcommand
+	name := c.Flags.Arg(0)
+	if name == "" {
+		c.Flags.Usage()
+		return nil, errors.New("missing command")
+	}
+	for _, sub := range c.Commands {
+		if sub.Name == name {
+			return sub.parse(c.Flags.Args()[1:])
+		}
+	}
+	c.Flags.Usage()
+	return nil, fmt.Errorf("unknown command: %s", name)
+}
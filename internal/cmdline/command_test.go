@@ -0,0 +1,131 @@
+package cmdline
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testCommand returns a command for testing that writes usage output to out.
+func testCommand(out *bytes.Buffer) (*Command, *string) {
+	value := ""
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Bool("version", false, "print version")
+	flags.SetOutput(out)
+	showFlags := flag.NewFlagSet("show", flag.ContinueOnError)
+	showFlags.StringVar(&value, "value", "", "show `value`")
+	showFlags.String("file", "", "read `file`")
+	showFlags.SetOutput(out)
+	mode := flag.NewFlagSet("set mode", flag.ContinueOnError)
+	mode.SetOutput(out)
+	return &Command{
+		Name:  "test",
+		Short: "test command",
+		Flags: flags,
+		Commands: []*Command{
+			{
+				Name:       "show",
+				Short:      "show something",
+				Long:       "Show something.\n\nShow more.",
+				Flags:      showFlags,
+				FlagValues: map[string][]string{"value": {"a", "b"}},
+			},
+			{
+				Name:  "set",
+				Short: "set something",
+				Commands: []*Command{
+					{
+						Name:      "mode",
+						Args:      "on|off",
+						Short:     "set mode",
+						Flags:     mode,
+						ArgValues: []string{"on", "off"},
+						Check: func(args []string) error {
+							if len(args) != 1 {
+								return errors.New("invalid mode")
+							}
+							return nil
+						},
+					},
+				},
+			},
+		},
+	}, &value
+}
+
+// TestCommandParse tests Parse of Command.
+func TestCommandParse(t *testing.T) {
+	out := &bytes.Buffer{}
+	c, value := testCommand(out)
+
+	// test valid
+	cmd, err := c.Parse([]string{"show", "-value", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Name != "show" || *value != "a" {
+		t.Errorf("got %s, %s", cmd.Name, *value)
+	}
+	cmd, err = c.Parse([]string{"set", "mode", "on"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cmd.Path(), []string{"test", "set", "mode"}) {
+		t.Errorf("got path %v", cmd.Path())
+	}
+
+	// test invalid
+	for _, args := range [][]string{
+		{},
+		{"invalid"},
+		{"show", "extra"},
+		{"show", "-invalid"},
+		{"set"},
+		{"set", "mode"},
+	} {
+		if _, err := c.Parse(args); err == nil {
+			t.Errorf("should return error for %v", args)
+		}
+	}
+
+	// test help
+	if _, err := c.Parse([]string{"-help"}); err != flag.ErrHelp {
+		t.Errorf("got %v, want %v", err, flag.ErrHelp)
+	}
+}
+
+// TestCommandUsage tests usage of Command.
+func TestCommandUsage(t *testing.T) {
+	out := &bytes.Buffer{}
+	c, _ := testCommand(out)
+	if _, err := c.Parse([]string{"-help"}); err != flag.ErrHelp {
+		t.Fatal(err)
+	}
+	want := `Usage:
+  test [options] [command]
+
+Options:
+  -version
+    	print version
+
+Commands:
+  show
+        show something
+  set mode on|off
+        set mode
+`
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if _, err := c.Parse([]string{"set", "mode", "-help"}); err != flag.ErrHelp {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Usage:\n  set mode on|off\n") {
+		t.Errorf("got %q", out.String())
+	}
+}
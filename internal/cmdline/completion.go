@@ -0,0 +1,324 @@
+package cmdline
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Shells are the shells supported by Completion.
+var Shells = []string{"bash", "zsh", "fish"}
+
+// flagInfo is the completion info of a flag.
+type flagInfo struct {
+	name   string
+	usage  string
+	isBool bool
+	kind   string
+	values []string
+}
+
+// flagInfos returns the completion info of the flags of the command. The kind
+// of a flag is the name of its value in the usage, e.g., "file" or
+// "directory".
+func (c *Command) flagInfos() []*flagInfo {
+	infos := []*flagInfo{}
+	c.Flags.VisitAll(func(f *flag.Flag) {
+		kind, usage := flag.UnquoteUsage(f)
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		infos = append(infos, &flagInfo{
+			name:   f.Name,
+			usage:  usage,
+			isBool: ok && b.IsBoolFlag(),
+			kind:   kind,
+			values: c.FlagValues[f.Name],
+		})
+	})
+	return infos
+}
+
+// completionPath returns the path of the command used in completion scripts,
+// e.g., "/config/convert". The path of the root command is empty.
+func (c *Command) completionPath() string {
+	p := ""
+	for _, name := range c.Path()[1:] {
+		p += "/" + name
+	}
+	return p
+}
+
+// funcName returns the name of the completion function of the command.
+func (c *Command) funcName() string {
+	return "_" + strings.NewReplacer("-", "_", ".", "_").Replace(c.Name)
+}
+
+// subPaths returns the completion paths of all subcommands.
+func (c *Command) subPaths() []string {
+	paths := []string{}
+	for _, cmd := range c.all()[1:] {
+		paths = append(paths, cmd.completionPath())
+	}
+	return paths
+}
+
+// shellQuote quotes s in single quotes for bash and zsh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s in single quotes for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// bash writes the bash completion script of the command to w.
+func (c *Command) bash(w io.Writer) error {
+	b := &strings.Builder{}
+	p := func(f string, args ...any) {
+		_, _ = fmt.Fprintf(b, f, args...)
+	}
+	words := func(values []string) string {
+		return shellQuote(strings.Join(values, " "))
+	}
+
+	p("# bash completion for %s\n\n", c.Name)
+	p("%s() {\n", c.funcName())
+	p("\tlocal cur prev cmdpath i\n")
+	p("\tcur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	p("\tprev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	p("\tcmdpath=\"\"\n")
+	if len(c.Commands) > 0 {
+		p("\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+		p("\t\tcase \"$cmdpath/${COMP_WORDS[i]}\" in\n")
+		p("\t\t%s) cmdpath=\"$cmdpath/${COMP_WORDS[i]}\" ;;\n", strings.Join(c.subPaths(), "|"))
+		p("\t\tesac\n")
+		p("\tdone\n")
+	}
+	p("\tcase \"$cmdpath\" in\n")
+	for _, cmd := range c.all() {
+		p("\t%s)\n", shellQuote(cmd.completionPath()))
+
+		// flag values
+		flags := cmd.flagInfos()
+		p("\t\tcase \"$prev\" in\n")
+		for _, f := range flags {
+			if f.isBool {
+				continue
+			}
+			p("\t\t-%s|--%s)\n", f.name, f.name)
+			switch {
+			case len(f.values) > 0:
+				p("\t\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", words(f.values))
+			case f.kind == "file":
+				p("\t\t\tcompopt -o filenames 2>/dev/null\n")
+				p("\t\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n")
+			case f.kind == "directory":
+				p("\t\t\tcompopt -o filenames 2>/dev/null\n")
+				p("\t\t\tCOMPREPLY=($(compgen -d -- \"$cur\"))\n")
+			}
+			p("\t\t\treturn\n")
+			p("\t\t\t;;\n")
+		}
+		p("\t\tesac\n")
+
+		// flags
+		names := []string{}
+		for _, f := range flags {
+			names = append(names, "-"+f.name)
+		}
+		p("\t\tif [[ \"$cur\" == -* ]]; then\n")
+		p("\t\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", words(names))
+		p("\t\t\treturn\n")
+		p("\t\tfi\n")
+
+		// subcommands and positional arguments
+		switch {
+		case len(cmd.Commands) > 0:
+			subs := []string{}
+			for _, sub := range cmd.Commands {
+				subs = append(subs, sub.Name)
+			}
+			p("\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", words(subs))
+		case len(cmd.ArgValues) > 0:
+			p("\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", words(cmd.ArgValues))
+		case cmd.ArgFiles:
+			p("\t\tcompopt -o filenames 2>/dev/null\n")
+			p("\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n")
+		}
+		p("\t\t;;\n")
+	}
+	p("\tesac\n")
+	p("}\n\n")
+	p("complete -F %s %s\n", c.funcName(), c.Name)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// zsh writes the zsh completion script of the command to w.
+func (c *Command) zsh(w io.Writer) error {
+	b := &strings.Builder{}
+	p := func(f string, args ...any) {
+		_, _ = fmt.Fprintf(b, f, args...)
+	}
+	words := func(values []string) string {
+		quoted := []string{}
+		for _, v := range values {
+			quoted = append(quoted, shellQuote(v))
+		}
+		return strings.Join(quoted, " ")
+	}
+	describe := func(name, desc string) string {
+		return shellQuote(strings.ReplaceAll(name, ":", `\:`) + ":" + desc)
+	}
+
+	p("#compdef %s\n\n", c.Name)
+	p("# zsh completion for %s\n\n", c.Name)
+	p("%s() {\n", c.funcName())
+	p("\tlocal cur=\"${words[CURRENT]}\" prev=\"${words[CURRENT-1]}\" cmdpath=\"\" i\n")
+	if len(c.Commands) > 0 {
+		p("\tfor ((i = 2; i < CURRENT; i++)); do\n")
+		p("\t\tcase \"$cmdpath/${words[i]}\" in\n")
+		p("\t\t(%s) cmdpath=\"$cmdpath/${words[i]}\" ;;\n", strings.Join(c.subPaths(), "|"))
+		p("\t\tesac\n")
+		p("\tdone\n")
+	}
+	p("\tcase \"$cmdpath\" in\n")
+	for _, cmd := range c.all() {
+		p("\t(%s)\n", shellQuote(cmd.completionPath()))
+
+		// flag values
+		flags := cmd.flagInfos()
+		p("\t\tcase \"$prev\" in\n")
+		for _, f := range flags {
+			if f.isBool {
+				continue
+			}
+			p("\t\t(-%s|--%s)\n", f.name, f.name)
+			switch {
+			case len(f.values) > 0:
+				p("\t\t\tcompadd -- %s\n", words(f.values))
+			case f.kind == "file":
+				p("\t\t\t_files\n")
+			case f.kind == "directory":
+				p("\t\t\t_files -/\n")
+			}
+			p("\t\t\treturn\n")
+			p("\t\t\t;;\n")
+		}
+		p("\t\tesac\n")
+
+		// flags
+		opts := []string{}
+		for _, f := range flags {
+			opts = append(opts, describe("-"+f.name, f.usage))
+		}
+		p("\t\tif [[ \"$cur\" == -* ]]; then\n")
+		p("\t\t\tlocal -a opts=(%s)\n", strings.Join(opts, " "))
+		p("\t\t\t_describe option opts\n")
+		p("\t\t\treturn\n")
+		p("\t\tfi\n")
+
+		// subcommands and positional arguments
+		switch {
+		case len(cmd.Commands) > 0:
+			subs := []string{}
+			for _, sub := range cmd.Commands {
+				subs = append(subs, describe(sub.Name, sub.Short))
+			}
+			p("\t\tlocal -a cmds=(%s)\n", strings.Join(subs, " "))
+			p("\t\t_describe command cmds\n")
+		case len(cmd.ArgValues) > 0:
+			p("\t\tcompadd -- %s\n", words(cmd.ArgValues))
+		case cmd.ArgFiles:
+			p("\t\t_files\n")
+		}
+		p("\t\t;;\n")
+	}
+	p("\tesac\n")
+	p("}\n\n")
+	p("%s \"$@\"\n", c.funcName())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fish writes the fish completion script of the command to w.
+func (c *Command) fish(w io.Writer) error {
+	b := &strings.Builder{}
+	p := func(f string, args ...any) {
+		_, _ = fmt.Fprintf(b, f, args...)
+	}
+
+	is := c.funcName() + "_is"
+	p("# fish completion for %s\n\n", c.Name)
+	p("function %s\n", is)
+	p("\tset -l words (commandline -opc)\n")
+	p("\tset -e words[1]\n")
+	p("\tset -l cmdpath \"\"\n")
+	if len(c.Commands) > 0 {
+		p("\tfor w in $words\n")
+		p("\t\tswitch \"$cmdpath/$w\"\n")
+		p("\t\t\tcase %s\n", strings.Join(c.subPaths(), " "))
+		p("\t\t\t\tset cmdpath \"$cmdpath/$w\"\n")
+		p("\t\tend\n")
+		p("\tend\n")
+	}
+	p("\ttest \"$cmdpath\" = \"$argv[1]\"\n")
+	p("end\n\n")
+	p("complete -c %s -f\n", c.Name)
+
+	for _, cmd := range c.all() {
+		cond := fishQuote(is + " " + shellQuote(cmd.completionPath()))
+		complete := func(f string, args ...any) {
+			p("complete -c %s -n %s %s\n", c.Name, cond, fmt.Sprintf(f, args...))
+		}
+
+		// flags
+		for _, f := range cmd.flagInfos() {
+			desc := fishQuote(f.usage)
+			switch {
+			case f.isBool:
+				complete("-o %s -d %s", f.name, desc)
+			case len(f.values) > 0:
+				complete("-o %s -x -a %s -d %s", f.name, fishQuote(strings.Join(f.values, " ")), desc)
+			case f.kind == "file":
+				complete("-o %s -r -F -d %s", f.name, desc)
+			case f.kind == "directory":
+				complete("-o %s -x -a '(__fish_complete_directories)' -d %s", f.name, desc)
+			default:
+				complete("-o %s -x -d %s", f.name, desc)
+			}
+		}
+
+		// subcommands and positional arguments
+		switch {
+		case len(cmd.Commands) > 0:
+			for _, sub := range cmd.Commands {
+				complete("-a %s -d %s", sub.Name, fishQuote(sub.Short))
+			}
+		case len(cmd.ArgValues) > 0:
+			complete("-a %s", fishQuote(strings.Join(cmd.ArgValues, " ")))
+		case cmd.ArgFiles:
+			complete("-F")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Completion writes the completion script of the command for shell to w.
+func (c *Command) Completion(w io.Writer, shell string) error {
+	c.setup()
+	switch shell {
+	case "bash":
+		return c.bash(w)
+	case "zsh":
+		return c.zsh(w)
+	case "fish":
+		return c.fish(w)
+	}
+	return fmt.Errorf("unsupported shell: %s", shell)
+}
@@ -0,0 +1,100 @@
+package cmdline
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCommandCompletion tests Completion of Command.
+func TestCommandCompletion(t *testing.T) {
+	c, _ := testCommand(&bytes.Buffer{})
+
+	for _, test := range []struct {
+		shell string
+		want  []string
+	}{
+		{"bash", []string{
+			"complete -F _test test",
+			"/show|/set|/set/mode)",
+			"-value|--value)",
+			"compgen -W 'a b'",
+			"compgen -f",
+			"compgen -W 'show set'",
+			"compgen -W 'on off'",
+		}},
+		{"zsh", []string{
+			"#compdef test",
+			"(/show|/set|/set/mode)",
+			"compadd -- 'a' 'b'",
+			"_files",
+			"'-value:show value'",
+			"'show:show something'",
+			"compadd -- 'on' 'off'",
+		}},
+		{"fish", []string{
+			"function _test_is",
+			"case /show /set /set/mode",
+			"complete -c test -n '_test_is \\'\\'' -o version -d 'print version'",
+			"complete -c test -n '_test_is \\'/show\\'' -o value -x -a 'a b' -d 'show value'",
+			"complete -c test -n '_test_is \\'/show\\'' -o file -r -F -d 'read file'",
+			"complete -c test -n '_test_is \\'/set/mode\\'' -a 'on off'",
+		}},
+	} {
+		b := &bytes.Buffer{}
+		if err := c.Completion(b, test.shell); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("%s completion should contain %q:\n%s", test.shell, want, b)
+			}
+		}
+	}
+
+	// test invalid shell
+	if err := c.Completion(&bytes.Buffer{}, "invalid"); err == nil {
+		t.Error("invalid shell should return error")
+	}
+}
+
+// TestCommandCompletionBash tests the bash completion script of Command.
+func TestCommandCompletionBash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+
+	c, _ := testCommand(&bytes.Buffer{})
+	script := filepath.Join(t.TempDir(), "test.bash")
+	b := &bytes.Buffer{}
+	if err := c.Completion(b, "bash"); err != nil {
+		t.Fatal(err)
+	}
+	b.WriteString(`COMP_WORDS=("$@"); COMP_CWORD=$(($# - 1)); _test; echo "${COMPREPLY[*]}"` + "\n")
+	if err := os.WriteFile(script, b.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for words, want := range map[string]string{
+		"test ":             "show set",
+		"test s":            "show set",
+		"test -":            "-version",
+		"test show -v":      "-value",
+		"test show -value ": "a b",
+		"test set ":         "mode",
+		"test set mode ":    "on off",
+	} {
+		args := append([]string{script}, strings.Split(words, " ")...)
+		out, err := exec.Command(bash, args...).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(out)); got != want {
+			t.Errorf("%q: got %q, want %q", words, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package cmdline
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// roff escapes s for roff.
+func roff(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = `\&` + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// description returns the long description of the command in roff format.
+func (c *Command) description() string {
+	desc := c.Long
+	if desc == "" {
+		desc = c.Short
+	}
+	paragraphs := []string{}
+	for p := range strings.SplitSeq(strings.TrimSpace(desc), "\n\n") {
+		paragraphs = append(paragraphs, roff(strings.TrimSpace(p)))
+	}
+	return strings.Join(paragraphs, "\n.PP\n")
+}
+
+// manFlags writes the flags of the command in roff format to b.
+func (c *Command) manFlags(b *strings.Builder) {
+	c.Flags.VisitAll(func(f *flag.Flag) {
+		name, usage := flag.UnquoteUsage(f)
+		b.WriteString(".TP\n")
+		b.WriteString(`\fB` + roff("-"+f.Name) + `\fR`)
+		if name != "" {
+			b.WriteString(` \fI` + roff(name) + `\fR`)
+		}
+		b.WriteString("\n" + roff(usage))
+		switch f.DefValue {
+		case "", "0", "0s", "false", "[]":
+		default:
+			b.WriteString(" (default: " + roff(f.DefValue) + ")")
+		}
+		b.WriteString("\n")
+	})
+}
+
+// synopsis returns the synopsis of the command in roff format.
+func (c *Command) synopsis() string {
+	s := `\fB` + roff(strings.Join(c.Path(), " ")) + `\fR`
+	if c.hasFlags() {
+		s += ` [\fIoptions\fR]`
+	}
+	switch {
+	case len(c.Commands) > 0:
+		s += ` [\fIcommand\fR]`
+	case c.Args != "":
+		s += ` \fI` + roff(c.Args) + `\fR`
+	}
+	return s
+}
+
+// ManPage writes the man page of the command in man section to w. Source is
+// the source of the command, e.g., the package name and version, date is the
+// date of the man page.
+func (c *Command) ManPage(w io.Writer, section int, source, date string) error {
+	c.setup()
+	b := &strings.Builder{}
+	p := func(f string, args ...any) {
+		_, _ = fmt.Fprintf(b, f, args...)
+	}
+
+	p(".TH %q \"%d\" %q %q \"User Commands\"\n", strings.ToUpper(c.Name), section, date, source)
+	p(".SH NAME\n")
+	p("%s \\- %s\n", roff(c.Name), roff(c.Short))
+	p(".SH SYNOPSIS\n")
+	p("%s\n", c.synopsis())
+	p(".SH DESCRIPTION\n")
+	p("%s\n", c.description())
+	if c.hasFlags() {
+		p(".SH OPTIONS\n")
+		c.manFlags(b)
+	}
+	if len(c.Commands) > 0 {
+		p(".SH COMMANDS\n")
+		for _, l := range c.leaves() {
+			p(".SS %q\n", strings.Join(l.Path()[1:], " "))
+			p("%s\n", l.synopsis())
+			p(".PP\n")
+			p("%s\n", l.description())
+			if l.hasFlags() {
+				p(".PP\n")
+				p("Options:\n")
+				l.manFlags(b)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,44 @@
+package cmdline
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestRoff tests roff.
+func TestRoff(t *testing.T) {
+	for s, want := range map[string]string{
+		"text":            "text",
+		`a-b\c`:           `a\-b\ec`,
+		".start\n'quote":  "\\&.start\n\\&'quote",
+		"in.the 'middle'": "in.the 'middle'",
+	} {
+		if got := roff(s); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+// TestCommandManPage tests ManPage of Command.
+func TestCommandManPage(t *testing.T) {
+	c, _ := testCommand(&bytes.Buffer{})
+	b := &bytes.Buffer{}
+	if err := c.ManPage(b, 1, "test 1.0", "2024-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		".TH \"TEST\" \"1\" \"2024-01-01\" \"test 1.0\" \"User Commands\"\n",
+		".SH NAME\ntest \\- test command\n",
+		".SH SYNOPSIS\n\\fBtest\\fR [\\fIoptions\\fR] [\\fIcommand\\fR]\n",
+		".SH OPTIONS\n.TP\n\\fB\\-version\\fR\nprint version\n",
+		".SS \"show\"\n",
+		"Show something.\n.PP\nShow more.\n",
+		"\\fB\\-value\\fR \\fIvalue\\fR\nshow value\n",
+		".SS \"set mode\"\n\\fBtest set mode\\fR \\fIon|off\\fR\n.PP\nset mode\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("man page should contain %q:\n%s", want, b)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package dbusapi
 import (
 	"errors"
 	"fmt"
+	"os"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/godbus/dbus/v5/introspect"
@@ -20,11 +21,17 @@ const (
 // Properties.
 const (
 	PropertyConfig               = "Config"
+	PropertyConfigSignature      = "ConfigSignature"
+	PropertyAgentState           = "AgentState"
 	PropertyTrustedNetwork       = "TrustedNetwork"
 	PropertyLoginState           = "LoginState"
+	PropertySourceIP             = "SourceIP"
+	PropertyLastLoginAt          = "LastLoginAt"
 	PropertyLastKeepAliveAt      = "LastKeepAliveAt"
 	PropertyKerberosTGTStartTime = "KerberosTGTStartTime"
 	PropertyKerberosTGTEndTime   = "KerberosTGTEndTime"
+	PropertyLogLevel             = "LogLevel"
+	PropertyLoginFailures        = "LoginFailures"
 )
 
 // Property "Config" values.
@@ -32,6 +39,24 @@ const (
 	ConfigInvalid = ""
 )
 
+// Property "Config Signature" states.
+const (
+	ConfigSignatureUnknown uint32 = iota
+	ConfigSignatureNotVerified
+	ConfigSignatureValid
+)
+
+// Property "Agent State" states.
+const (
+	AgentStateUnknown uint32 = iota
+	AgentStateWaitingForTicket
+	AgentStateUntrusted
+	AgentStateTrustedLoggingIn
+	AgentStateLoggedIn
+	AgentStatePaused
+	AgentStateSleeping
+)
+
 // Property "Trusted Network" states.
 const (
 	TrustedNetworkUnknown uint32 = iota
@@ -48,6 +73,16 @@ const (
 	LoginStateLoggingOut
 )
 
+// Property "Source IP" values.
+const (
+	SourceIPInvalid = ""
+)
+
+// Property "Last Login At" values.
+const (
+	LastLoginAtInvalid int64 = -1
+)
+
 // Property "Last Keep Alive At" values.
 const (
 	LastKeepAliveAtInvalid int64 = -1
@@ -63,19 +98,42 @@ const (
 	KerberosTGTEndTimeInvalid int64 = -1
 )
 
+// Property "Log Level" values.
+const (
+	LogLevelInvalid = ""
+)
+
+// Property "Login Failures" values.
+const (
+	LoginFailuresInvalid uint32 = 0
+)
+
 // Methods.
 const (
-	MethodReLogin = Interface + ".ReLogin"
+	MethodReLogin            = Interface + ".ReLogin"
+	MethodGetHistory         = Interface + ".GetHistory"
+	MethodSetLogLevel        = Interface + ".SetLogLevel"
+	MethodCollectDiagnostics = Interface + ".CollectDiagnostics"
+	MethodLogout             = Interface + ".Logout"
+	MethodPause              = Interface + ".Pause"
+	MethodResume             = Interface + ".Resume"
 )
 
 // Request Names.
 const (
-	RequestReLogin = "ReLogin"
+	RequestReLogin            = "ReLogin"
+	RequestGetHistory         = "GetHistory"
+	RequestSetLogLevel        = "SetLogLevel"
+	RequestCollectDiagnostics = "CollectDiagnostics"
+	RequestLogout             = "Logout"
+	RequestPause              = "Pause"
+	RequestResume             = "Resume"
 )
 
 // Request is a D-Bus client request.
 type Request struct {
 	Name       string
+	Sender     string
 	Parameters []any
 	Results    []any
 	Error      error
@@ -109,26 +167,142 @@ func NewRequest(name string, done chan struct{}) *Request {
 
 // agent defines agent interface methods.
 type agent struct {
+	conn     dbusConn
 	requests chan *Request
 	done     chan struct{}
 }
 
+// getConnectionUnixUser returns the unix user ID of the D-Bus connection
+// sender.
+var getConnectionUnixUser = func(conn dbusConn, sender string) (uint32, error) {
+	uid := uint32(0)
+	err := conn.(*dbus.Conn).BusObject().
+		Call("org.freedesktop.DBus.GetConnectionUnixUser", 0, sender).
+		Store(&uid)
+	return uid, err
+}
+
+// isOwner returns whether sender belongs to the user running the agent.
+func (a agent) isOwner(sender dbus.Sender) bool {
+	uid, err := getConnectionUnixUser(a.conn, string(sender))
+	if err != nil {
+		log.WithError(err).WithField("sender", sender).
+			Error("D-Bus could not get unix user of sender")
+		return false
+	}
+	return int(uid) == os.Getuid()
+}
+
 // ReLogin is the "ReLogin" method of the Agent D-Bus interface.
 func (a agent) ReLogin(sender dbus.Sender) *dbus.Error {
 	log.WithField("sender", sender).Debug("Received D-Bus ReLogin() call")
-	request := NewRequest(RequestReLogin, a.done)
+	_, err := a.sendRequest(RequestReLogin, sender)
+	return err
+}
+
+// sendRequest sends the request with name and parameters from sender to the
+// agent, waits for its completion and returns its results. The requests
+// change the agent's state or return details of the user's session, so they
+// are only allowed for the agent's user.
+func (a agent) sendRequest(name string, sender dbus.Sender, parameters ...any) ([]any, *dbus.Error) {
+	if !a.isOwner(sender) {
+		return nil, dbus.NewError("org.freedesktop.DBus.Error.AccessDenied",
+			[]any{name + " is only allowed for the agent's user"})
+	}
+	request := NewRequest(name, a.done)
+	request.Sender = string(sender)
+	request.Parameters = parameters
 
 	select {
 	case a.requests <- request:
 	case <-a.done:
-		return dbus.NewError(Interface+".ReLoginAborted", []any{"ReLogin aborted"})
+		return nil, dbus.NewError(Interface+"."+name+"Aborted", []any{name + " aborted"})
 	}
 
 	request.Wait()
 	if request.Error != nil {
-		return dbus.NewError(Interface+".ReLoginAborted", []any{request.Error.Error()})
+		return nil, dbus.NewError(Interface+"."+name+"Aborted", []any{request.Error.Error()})
 	}
-	return nil
+	return request.Results, nil
+}
+
+// requestResult returns the single result in results of the request with
+// name as T.
+func requestResult[T any](name string, results []any) (T, *dbus.Error) {
+	var result T
+	if len(results) != 1 {
+		return result, dbus.NewError(Interface+"."+name+"Aborted", []any{"invalid results"})
+	}
+	result, ok := results[0].(T)
+	if !ok {
+		return result, dbus.NewError(Interface+"."+name+"Aborted", []any{"invalid results"})
+	}
+	return result, nil
+}
+
+// Logout is the "Logout" method of the Agent D-Bus interface. It logs out
+// the client until ReLogin or Resume is called.
+func (a agent) Logout(sender dbus.Sender) *dbus.Error {
+	log.WithField("sender", sender).Debug("Received D-Bus Logout() call")
+	_, err := a.sendRequest(RequestLogout, sender)
+	return err
+}
+
+// Pause is the "Pause" method of the Agent D-Bus interface. It pauses the
+// agent and logs out the client until Resume is called.
+func (a agent) Pause(sender dbus.Sender) *dbus.Error {
+	log.WithField("sender", sender).Debug("Received D-Bus Pause() call")
+	_, err := a.sendRequest(RequestPause, sender)
+	return err
+}
+
+// Resume is the "Resume" method of the Agent D-Bus interface. It resumes the
+// paused agent.
+func (a agent) Resume(sender dbus.Sender) *dbus.Error {
+	log.WithField("sender", sender).Debug("Received D-Bus Resume() call")
+	_, err := a.sendRequest(RequestResume, sender)
+	return err
+}
+
+// GetHistory is the "GetHistory" method of the Agent D-Bus interface. It
+// returns the events that occurred after the unix timestamp since as JSON.
+// Only the user running the agent is allowed to call it.
+func (a agent) GetHistory(sender dbus.Sender, since int64) (string, *dbus.Error) {
+	log.WithFields(log.Fields{
+		"sender": sender,
+		"since":  since,
+	}).Debug("Received D-Bus GetHistory() call")
+	results, err := a.sendRequest(RequestGetHistory, sender, since)
+	if err != nil {
+		return "", err
+	}
+	return requestResult[string](RequestGetHistory, results)
+}
+
+// SetLogLevel is the "SetLogLevel" method of the Agent D-Bus interface. It
+// sets the log level to level for duration seconds, or until it is reset if
+// duration is 0. An empty level resets the log level to the configured log
+// level. Only the user running the agent is allowed to call it.
+func (a agent) SetLogLevel(sender dbus.Sender, level string, duration int64) *dbus.Error {
+	log.WithFields(log.Fields{
+		"sender":   sender,
+		"level":    level,
+		"duration": duration,
+	}).Debug("Received D-Bus SetLogLevel() call")
+	_, err := a.sendRequest(RequestSetLogLevel, sender, level, duration)
+	return err
+}
+
+// CollectDiagnostics is the "CollectDiagnostics" method of the Agent D-Bus
+// interface. It returns the support bundle as tar.gz archive. Only the user
+// running the agent is allowed to call it.
+func (a agent) CollectDiagnostics(sender dbus.Sender) ([]byte, *dbus.Error) {
+	log.WithField("sender", sender).Debug("Received D-Bus CollectDiagnostics() call")
+	results, err := a.sendRequest(RequestCollectDiagnostics, sender)
+	if err != nil {
+		return nil, err
+	}
+	return requestResult[[]byte](RequestCollectDiagnostics, results)
 }
 
 // propertyUpdate is an update of a property.
@@ -199,11 +373,17 @@ func (s *Service) start() {
 			// set properties values to unknown/invalid to emit
 			// properties changed signal and inform clients
 			s.props.SetMust(Interface, PropertyConfig, ConfigInvalid)
+			s.props.SetMust(Interface, PropertyConfigSignature, ConfigSignatureUnknown)
+			s.props.SetMust(Interface, PropertyAgentState, AgentStateUnknown)
 			s.props.SetMust(Interface, PropertyTrustedNetwork, TrustedNetworkUnknown)
 			s.props.SetMust(Interface, PropertyLoginState, LoginStateUnknown)
+			s.props.SetMust(Interface, PropertySourceIP, SourceIPInvalid)
+			s.props.SetMust(Interface, PropertyLastLoginAt, LastLoginAtInvalid)
 			s.props.SetMust(Interface, PropertyLastKeepAliveAt, LastKeepAliveAtInvalid)
 			s.props.SetMust(Interface, PropertyKerberosTGTStartTime, KerberosTGTStartTimeInvalid)
 			s.props.SetMust(Interface, PropertyKerberosTGTEndTime, KerberosTGTEndTimeInvalid)
+			s.props.SetMust(Interface, PropertyLogLevel, LogLevelInvalid)
+			s.props.SetMust(Interface, PropertyLoginFailures, LoginFailuresInvalid)
 			return
 		}
 	}
@@ -228,7 +408,7 @@ func (s *Service) Start() error {
 	}
 
 	// methods
-	meths := agent{s.requests, s.done}
+	meths := agent{conn, s.requests, s.done}
 	err = conn.Export(meths, Path, Interface)
 	if err != nil {
 		return fmt.Errorf("could not export D-Bus methods: %w", err)
@@ -243,6 +423,18 @@ func (s *Service) Start() error {
 				Emit:     prop.EmitTrue,
 				Callback: nil,
 			},
+			PropertyConfigSignature: {
+				Value:    ConfigSignatureUnknown,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyAgentState: {
+				Value:    AgentStateUnknown,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
 			PropertyTrustedNetwork: {
 				Value:    TrustedNetworkUnknown,
 				Writable: false,
@@ -255,6 +447,18 @@ func (s *Service) Start() error {
 				Emit:     prop.EmitTrue,
 				Callback: nil,
 			},
+			PropertySourceIP: {
+				Value:    SourceIPInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyLastLoginAt: {
+				Value:    LastLoginAtInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
 			PropertyLastKeepAliveAt: {
 				Value:    LastKeepAliveAtInvalid,
 				Writable: false,
@@ -273,6 +477,18 @@ func (s *Service) Start() error {
 				Emit:     prop.EmitTrue,
 				Callback: nil,
 			},
+			PropertyLogLevel: {
+				Value:    LogLevelInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyLoginFailures: {
+				Value:    LoginFailuresInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
 		},
 	}
 	props, err := propExport(conn, Path, propsSpec)
@@ -303,11 +519,17 @@ func (s *Service) Start() error {
 	// set properties values to emit properties changed signal and make
 	// sure existing clients get updated values after restart
 	props.SetMust(Interface, PropertyConfig, ConfigInvalid)
+	props.SetMust(Interface, PropertyConfigSignature, ConfigSignatureUnknown)
+	props.SetMust(Interface, PropertyAgentState, AgentStateWaitingForTicket)
 	props.SetMust(Interface, PropertyTrustedNetwork, TrustedNetworkNotTrusted)
 	props.SetMust(Interface, PropertyLoginState, LoginStateLoggedOut)
+	props.SetMust(Interface, PropertySourceIP, SourceIPInvalid)
+	props.SetMust(Interface, PropertyLastLoginAt, LastLoginAtInvalid)
 	props.SetMust(Interface, PropertyLastKeepAliveAt, LastKeepAliveAtInvalid)
 	props.SetMust(Interface, PropertyKerberosTGTStartTime, KerberosTGTStartTimeInvalid)
 	props.SetMust(Interface, PropertyKerberosTGTEndTime, KerberosTGTEndTimeInvalid)
+	props.SetMust(Interface, PropertyLogLevel, LogLevelInvalid)
+	props.SetMust(Interface, PropertyLoginFailures, LoginFailuresInvalid)
 
 	go s.start()
 	return nil
@@ -2,6 +2,7 @@ package dbusapi
 
 import (
 	"errors"
+	"os"
 	"reflect"
 	"testing"
 
@@ -38,6 +39,10 @@ func TestRequestWaitClose(_ *testing.T) {
 
 // TestAgentReLogin tests ReLogin of agent.
 func TestAgentReLogin(t *testing.T) {
+	defer func(f func(dbusConn, string) (uint32, error)) {
+		getConnectionUnixUser = f
+	}(getConnectionUnixUser)
+
 	// create agent
 	requests := make(chan *Request)
 	done := make(chan struct{})
@@ -46,6 +51,19 @@ func TestAgentReLogin(t *testing.T) {
 		done:     done,
 	}
 
+	// test with other user
+	getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+		return uint32(os.Getuid() + 1), nil
+	}
+	if err := a.ReLogin("sender"); err == nil {
+		t.Error("relogin should fail for other user")
+	}
+
+	// test with owner
+	getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+		return uint32(os.Getuid()), nil
+	}
+
 	// run relogin and get results
 	want := &Request{
 		Name: RequestReLogin,
@@ -92,6 +110,277 @@ func TestAgentReLogin(t *testing.T) {
 	}
 }
 
+// TestAgentLogoutPauseResume tests Logout, Pause and Resume of agent.
+func TestAgentLogoutPauseResume(t *testing.T) {
+	defer func(f func(dbusConn, string) (uint32, error)) {
+		getConnectionUnixUser = f
+	}(getConnectionUnixUser)
+
+	for name, method := range map[string]func(agent, dbus.Sender) *dbus.Error{
+		RequestLogout: agent.Logout,
+		RequestPause:  agent.Pause,
+		RequestResume: agent.Resume,
+	} {
+		// create agent
+		requests := make(chan *Request)
+		done := make(chan struct{})
+		a := agent{
+			requests: requests,
+			done:     done,
+		}
+
+		// test with other user
+		getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+			return uint32(os.Getuid() + 1), nil
+		}
+		if err := method(a, "sender"); err == nil {
+			t.Errorf("%s should fail for other user", name)
+		}
+
+		// test request with owner
+		getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+			return uint32(os.Getuid()), nil
+		}
+		go func() {
+			r := <-requests
+			if r.Name != name || r.Sender != "sender" {
+				t.Errorf("unexpected request: %v", r)
+			}
+			r.Close()
+		}()
+		if err := method(a, "sender"); err != nil {
+			t.Error(err)
+		}
+
+		// test with request error
+		go func() {
+			r := <-requests
+			r.Error = errors.New("test error")
+			r.Close()
+		}()
+		if err := method(a, "sender"); err == nil ||
+			err.Name != Interface+"."+name+"Aborted" {
+			t.Errorf("%s should return error, got %v", name, err)
+		}
+
+		// test with stopped agent
+		close(done)
+		if err := method(a, "sender"); err == nil {
+			t.Errorf("%s should return error", name)
+		}
+	}
+}
+
+// TestAgentGetHistory tests GetHistory of agent.
+func TestAgentGetHistory(t *testing.T) {
+	defer func(f func(dbusConn, string) (uint32, error)) {
+		getConnectionUnixUser = f
+	}(getConnectionUnixUser)
+
+	// create agent
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	a := agent{
+		requests: requests,
+		done:     done,
+	}
+
+	// test with other user
+	getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+		return uint32(os.Getuid() + 1), nil
+	}
+	if _, err := a.GetHistory("sender", 0); err == nil {
+		t.Error("get history should fail for other user")
+	}
+
+	// test with owner and valid results
+	getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+		return uint32(os.Getuid()), nil
+	}
+	want := "[]"
+	go func() {
+		r := <-requests
+		if r.Name != RequestGetHistory ||
+			r.Sender != "sender" ||
+			!reflect.DeepEqual(r.Parameters, []any{int64(42)}) {
+			t.Errorf("invalid request: %v", r)
+		}
+		r.Results = []any{want}
+		r.Close()
+	}()
+	got, err := a.GetHistory("sender", 42)
+	if err != nil || got != want {
+		t.Errorf("got %s, %v, want %s", got, err, want)
+	}
+
+	// test with invalid results
+	for _, results := range [][]any{
+		nil,
+		{1},
+	} {
+		go func() {
+			r := <-requests
+			r.Results = results
+			r.Close()
+		}()
+		if _, err := a.GetHistory("sender", 0); err == nil {
+			t.Errorf("invalid results %v should return error", results)
+		}
+	}
+
+	// test with request error
+	go func() {
+		r := <-requests
+		r.Error = errors.New("test error")
+		r.Close()
+	}()
+	if _, err := a.GetHistory("sender", 0); err == nil {
+		t.Error("get history should return error")
+	}
+
+	// test with stopped agent
+	close(done)
+	if _, err := a.GetHistory("sender", 0); err == nil {
+		t.Error("get history should return error")
+	}
+}
+
+// TestAgentSetLogLevel tests SetLogLevel of agent.
+func TestAgentSetLogLevel(t *testing.T) {
+	defer func(f func(dbusConn, string) (uint32, error)) {
+		getConnectionUnixUser = f
+	}(getConnectionUnixUser)
+
+	// create agent
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	a := agent{
+		requests: requests,
+		done:     done,
+	}
+
+	// test with other user
+	getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+		return uint32(os.Getuid() + 1), nil
+	}
+	if err := a.SetLogLevel("sender", "debug", 600); err == nil {
+		t.Error("set log level should fail for other user")
+	}
+
+	// test with unknown user
+	getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+		return 0, errors.New("test error")
+	}
+	if err := a.SetLogLevel("sender", "debug", 600); err == nil {
+		t.Error("set log level should fail for unknown user")
+	}
+
+	// test with owner
+	getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+		return uint32(os.Getuid()), nil
+	}
+	go func() {
+		r := <-requests
+		if r.Name != RequestSetLogLevel ||
+			r.Sender != "sender" ||
+			!reflect.DeepEqual(r.Parameters, []any{"debug", int64(600)}) {
+			t.Errorf("invalid request: %v", r)
+		}
+		r.Close()
+	}()
+	if err := a.SetLogLevel("sender", "debug", 600); err != nil {
+		t.Error(err)
+	}
+
+	// test with request error
+	go func() {
+		r := <-requests
+		r.Error = errors.New("test error")
+		r.Close()
+	}()
+	if err := a.SetLogLevel("sender", "invalid", 0); err == nil {
+		t.Error("set log level should return error")
+	}
+
+	// test with stopped agent
+	close(done)
+	if err := a.SetLogLevel("sender", "debug", 0); err == nil {
+		t.Error("set log level should return error")
+	}
+}
+
+// TestAgentCollectDiagnostics tests CollectDiagnostics of agent.
+func TestAgentCollectDiagnostics(t *testing.T) {
+	defer func(f func(dbusConn, string) (uint32, error)) {
+		getConnectionUnixUser = f
+	}(getConnectionUnixUser)
+
+	// create agent
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	a := agent{
+		requests: requests,
+		done:     done,
+	}
+
+	// test with other user
+	getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+		return uint32(os.Getuid() + 1), nil
+	}
+	if _, err := a.CollectDiagnostics("sender"); err == nil {
+		t.Error("collect diagnostics should fail for other user")
+	}
+
+	// test with owner and valid results
+	getConnectionUnixUser = func(dbusConn, string) (uint32, error) {
+		return uint32(os.Getuid()), nil
+	}
+	want := []byte("bundle")
+	go func() {
+		r := <-requests
+		if r.Name != RequestCollectDiagnostics || r.Sender != "sender" {
+			t.Errorf("invalid request: %v", r)
+		}
+		r.Results = []any{want}
+		r.Close()
+	}()
+	got, err := a.CollectDiagnostics("sender")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %s, %v, want %s", got, err, want)
+	}
+
+	// test with invalid results
+	for _, results := range [][]any{
+		nil,
+		{"bundle"},
+	} {
+		go func() {
+			r := <-requests
+			r.Results = results
+			r.Close()
+		}()
+		if _, err := a.CollectDiagnostics("sender"); err == nil {
+			t.Errorf("invalid results %v should return error", results)
+		}
+	}
+
+	// test with request error
+	go func() {
+		r := <-requests
+		r.Error = errors.New("test error")
+		r.Close()
+	}()
+	if _, err := a.CollectDiagnostics("sender"); err == nil {
+		t.Error("collect diagnostics should return error")
+	}
+
+	// test with stopped agent
+	close(done)
+	if _, err := a.CollectDiagnostics("sender"); err == nil {
+		t.Error("collect diagnostics should return error")
+	}
+}
+
 // testConn implements the dbusConn interface for testing.
 type testConn struct{}
 
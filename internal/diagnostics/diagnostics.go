@@ -0,0 +1,291 @@
+// Package diagnostics contains the support bundle of the agent.
+package diagnostics
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/telekom-mms/fw-id-agent/internal/doctor"
+	"github.com/telekom-mms/fw-id-agent/internal/krbmon"
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/fw-id-agent/pkg/history"
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// bundleDir is the directory of all files in the support bundle.
+const bundleDir = "fw-id-agent-support"
+
+// redacted replaces secrets in the support bundle.
+const redacted = "REDACTED"
+
+var (
+	// krb5conf is the file path of the krb5.conf file.
+	krb5conf = "/etc/krb5.conf"
+
+	// now is time.Now for testing.
+	now = time.Now
+
+	// getCCacheFile is krbmon.CCacheFile for testing.
+	getCCacheFile = krbmon.CCacheFile
+
+	// runTND is doctor.RunTND for testing.
+	runTND = doctor.RunTND
+)
+
+// Diagnostics is a snapshot of the agent state for the support bundle.
+type Diagnostics struct {
+	Version string
+	Config  *config.Config
+	Status  *status.Status
+	History []*history.Event
+	Logs    []byte
+}
+
+// Credential is the metadata of a ticket in the ccache without key
+// material.
+type Credential struct {
+	Client         string
+	Server         string
+	AuthTime       time.Time
+	StartTime      time.Time
+	EndTime        time.Time
+	RenewTill      time.Time
+	EncryptionType int32
+}
+
+// CCache is the metadata of the ccache without key material.
+type CCache struct {
+	File        string
+	Principal   string
+	Credentials []*Credential
+}
+
+// redactURL removes user info, query and fragment from URL u.
+func redactURL(u string) string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return redacted
+	}
+	if p.User != nil {
+		p.User = url.User(redacted)
+	}
+	if p.RawQuery != "" {
+		p.RawQuery = redacted
+	}
+	p.Fragment = ""
+	return p.String()
+}
+
+// urlPattern matches URLs in log lines.
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>\\{}]+`)
+
+// redactLogs returns log lines b with redacted URLs, e.g., of config log
+// messages.
+func redactLogs(b []byte) []byte {
+	return urlPattern.ReplaceAllFunc(b, func(u []byte) []byte {
+		return []byte(redactURL(string(u)))
+	})
+}
+
+// redactHistory returns a copy of events with redacted URLs in errors and
+// details, e.g., of failed login requests.
+func redactHistory(events []*history.Event) []*history.Event {
+	if events == nil {
+		return nil
+	}
+	redact := func(s string) string {
+		return string(redactLogs([]byte(s)))
+	}
+	h := make([]*history.Event, 0, len(events))
+	for _, e := range events {
+		c := *e
+		c.Error = redact(e.Error)
+		if e.Details != nil {
+			c.Details = make(map[string]string, len(e.Details))
+			for k, v := range e.Details {
+				c.Details[k] = redact(v)
+			}
+		}
+		h = append(h, &c)
+	}
+	return h
+}
+
+// redactConfig returns a copy of cfg with redacted URLs.
+func redactConfig(cfg *config.Config) *config.Config {
+	if cfg == nil {
+		return nil
+	}
+	c := cfg.Copy()
+	c.ServiceURL = redactURL(c.ServiceURL)
+	for i := range c.TND.HTTPSServers {
+		c.TND.HTTPSServers[i].URL = redactURL(c.TND.HTTPSServers[i].URL)
+	}
+	return c
+}
+
+// stripKrb5Conf returns krb5.conf b with redacted values of settings that
+// may contain secrets, e.g., passwords.
+func stripKrb5Conf(b []byte) []byte {
+	out := &bytes.Buffer{}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := s.Text()
+		key, _, found := strings.Cut(line, "=")
+		k := strings.ToLower(key)
+		if found && (strings.Contains(k, "password") ||
+			strings.Contains(k, "passwd") ||
+			strings.Contains(k, "secret")) {
+			line = key + "= " + redacted
+		}
+		out.WriteString(line + "\n")
+	}
+	return out.Bytes()
+}
+
+// loadCCache returns the metadata of the ccache.
+func loadCCache() (*CCache, error) {
+	file, err := getCCacheFile()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cCache := new(credentials.CCache)
+	if err := cCache.Unmarshal(b); err != nil {
+		return nil, err
+	}
+
+	c := &CCache{
+		File:      file,
+		Principal: cCache.GetClientPrincipalName().PrincipalNameString() + "@" + cCache.GetClientRealm(),
+	}
+	for _, e := range cCache.GetEntries() {
+		c.Credentials = append(c.Credentials, &Credential{
+			Client:         e.Client.PrincipalName.PrincipalNameString() + "@" + e.Client.Realm,
+			Server:         e.Server.PrincipalName.PrincipalNameString() + "@" + e.Server.Realm,
+			AuthTime:       e.AuthTime,
+			StartTime:      e.StartTime,
+			EndTime:        e.EndTime,
+			RenewTill:      e.RenewTill,
+			EncryptionType: e.Key.KeyType,
+		})
+	}
+	return c, nil
+}
+
+// bundle is a tar.gz support bundle.
+type bundle struct {
+	tw     *tar.Writer
+	errors []string
+}
+
+// add adds file name with contents b to the bundle.
+func (b *bundle) add(name string, contents []byte) error {
+	if err := b.tw.WriteHeader(&tar.Header{
+		Name:    bundleDir + "/" + name,
+		Mode:    0600,
+		Size:    int64(len(contents)),
+		ModTime: now(),
+	}); err != nil {
+		return err
+	}
+	_, err := b.tw.Write(contents)
+	return err
+}
+
+// addJSON adds file name with v as JSON to the bundle.
+func (b *bundle) addJSON(name string, v any) error {
+	j, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		b.errors = append(b.errors, fmt.Sprintf("%s: %v", name, err))
+		return nil
+	}
+	return b.add(name, j)
+}
+
+// addError records the error err for file name.
+func (b *bundle) addError(name string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %v", name, err))
+}
+
+// Bundle collects the remaining diagnostics, i.e., krb5.conf, ccache
+// metadata and TND probe results, and returns them with the snapshot in d
+// as tar.gz archive. Errors collecting single files are stored in the file
+// errors.txt in the archive.
+func (d *Diagnostics) Bundle() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	b := &bundle{tw: tar.NewWriter(gw)}
+
+	// snapshot of the agent
+	var s *status.Status
+	if d.Status != nil {
+		s = d.Status.Copy()
+		s.Config = redactConfig(s.Config)
+	}
+	if err := errors.Join(
+		b.add("version.txt", []byte(d.Version+"\n")),
+		b.addJSON("config.json", redactConfig(d.Config)),
+		b.addJSON("status.json", s),
+		b.addJSON("history.json", redactHistory(d.History)),
+		b.add("agent.log", redactLogs(d.Logs)),
+	); err != nil {
+		return nil, err
+	}
+
+	// krb5.conf
+	if conf, err := os.ReadFile(krb5conf); err != nil {
+		b.addError("krb5.conf", err)
+	} else if err := b.add("krb5.conf", stripKrb5Conf(conf)); err != nil {
+		return nil, err
+	}
+
+	// ccache metadata
+	if c, err := loadCCache(); err != nil {
+		b.addError("ccache.json", err)
+	} else if err := b.addJSON("ccache.json", c); err != nil {
+		return nil, err
+	}
+
+	// TND probe results
+	if d.Config == nil {
+		b.addError("tnd.json", errors.New("config is not available"))
+	} else {
+		r := runTND(d.Config)
+		for _, c := range r.Checks {
+			c.Name = redactURL(strings.TrimPrefix(c.Name, "tnd "))
+		}
+		if err := b.addJSON("tnd.json", r); err != nil {
+			return nil, err
+		}
+	}
+
+	// collection errors
+	if len(b.errors) > 0 {
+		if err := b.add("errors.txt", []byte(strings.Join(b.errors, "\n")+"\n")); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,327 @@
+// Package doctor contains end-to-end diagnostics of the agent setup.
+package doctor
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	krbConfig "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/telekom-mms/fw-id-agent/internal/krbmon"
+	"github.com/telekom-mms/fw-id-agent/pkg/client"
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/tnd/pkg/tnd"
+)
+
+// Result is the result of a check.
+type Result string
+
+// Check results.
+const (
+	ResultPass Result = "pass"
+	ResultFail Result = "fail"
+	ResultSkip Result = "skip"
+)
+
+// Check is a diagnostic check and its result.
+type Check struct {
+	Name    string
+	Result  Result
+	Message string
+	Hint    string `json:",omitempty"`
+}
+
+// Report is a diagnostics report.
+type Report struct {
+	Checks []*Check
+}
+
+// pass adds a passed check to the report.
+func (r *Report) pass(name, message string) {
+	r.Checks = append(r.Checks, &Check{
+		Name:    name,
+		Result:  ResultPass,
+		Message: message,
+	})
+}
+
+// fail adds a failed check with remediation hint to the report.
+func (r *Report) fail(name, message, hint string) {
+	r.Checks = append(r.Checks, &Check{
+		Name:    name,
+		Result:  ResultFail,
+		Message: message,
+		Hint:    hint,
+	})
+}
+
+// skip adds a skipped check to the report.
+func (r *Report) skip(name, message string) {
+	r.Checks = append(r.Checks, &Check{
+		Name:    name,
+		Result:  ResultSkip,
+		Message: message,
+	})
+}
+
+// Passed returns whether no check in the report failed.
+func (r *Report) Passed() bool {
+	for _, c := range r.Checks {
+		if c.Result == ResultFail {
+			return false
+		}
+	}
+	return true
+}
+
+// JSONIndent returns the report as indented JSON.
+func (r *Report) JSONIndent() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// Write writes the report as text to w.
+func (r *Report) Write(w io.Writer) error {
+	for _, c := range r.Checks {
+		line := fmt.Sprintf("[%s] %s: %s\n", strings.ToUpper(string(c.Result)), c.Name, c.Message)
+		if c.Hint != "" {
+			line += fmt.Sprintf("       hint: %s\n", c.Hint)
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	// krb5conf is the file path of the krb5.conf file.
+	krb5conf = "/etc/krb5.conf"
+
+	// now is time.Now for testing.
+	now = time.Now
+
+	// lookupHost is net.LookupHost for testing.
+	lookupHost = net.LookupHost
+
+	// getCCacheFile is krbmon.CCacheFile for testing.
+	getCCacheFile = krbmon.CCacheFile
+
+	// tlsConfig is the TLS config for the service TLS handshake,
+	// nil uses the system defaults.
+	tlsConfig *tls.Config
+)
+
+// checkAgent checks if the agent is reachable with c and returns its
+// config.
+func checkAgent(r *Report, c client.Client) *config.Config {
+	hint := "start the agent, e.g., with: systemctl --user start fw-id-agent.service"
+	if c == nil {
+		r.fail("agent", "could not connect to D-Bus", hint)
+		return nil
+	}
+	s, err := c.Query()
+	if err != nil {
+		r.fail("agent", fmt.Sprintf("agent is not reachable on D-Bus: %v", err), hint)
+		return nil
+	}
+	r.pass("agent", fmt.Sprintf("agent is reachable on D-Bus, login state: %s", s.LoginState))
+	return s.Config
+}
+
+// checkConfig checks the config of the agent or, if not available, loads
+// the config from configFile.
+func checkConfig(r *Report, cfg *config.Config, configFile string) *config.Config {
+	if configFile != "" {
+		c, err := config.Load(configFile)
+		if err != nil {
+			r.fail("config", fmt.Sprintf("could not load config file %s: %v", configFile, err),
+				"check the config file and its signature")
+			return nil
+		}
+		cfg = c
+	}
+	if cfg == nil {
+		r.fail("config", "config is not available",
+			"start the agent or specify the config file with -config")
+		return nil
+	}
+	if !cfg.Valid() {
+		r.fail("config", "config is not valid",
+			"set ServiceURL, Realm and TND servers in the config file")
+		return nil
+	}
+	r.pass("config", fmt.Sprintf("config is valid, service: %s, realm: %s", cfg.ServiceURL, cfg.Realm))
+	return cfg
+}
+
+// checkCCache checks if the ccache contains a valid TGT for realm.
+func checkCCache(r *Report, realm string) {
+	hint := "get a kerberos ticket, e.g., with: kinit"
+	file, err := getCCacheFile()
+	if err != nil {
+		r.fail("ccache", fmt.Sprintf("could not get credential cache: %v", err), hint)
+		return
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		r.fail("ccache", fmt.Sprintf("could not read credential cache: %v", err), hint)
+		return
+	}
+	cCache := new(credentials.CCache)
+	if err := cCache.Unmarshal(b); err != nil {
+		r.fail("ccache", fmt.Sprintf("could not load credential cache %s: %v", file, err), hint)
+		return
+	}
+	tgt := (&krbmon.CCacheUpdate{CCache: cCache}).GetTGT(realm)
+	if tgt == nil {
+		r.fail("ccache", fmt.Sprintf("no TGT for realm %s in credential cache %s", realm, file), hint)
+		return
+	}
+	remaining := tgt.EndTime.Sub(now()).Truncate(time.Second)
+	if remaining <= 0 {
+		r.fail("ccache", fmt.Sprintf("TGT for realm %s expired at %s", realm, tgt.EndTime), hint)
+		return
+	}
+	r.pass("ccache", fmt.Sprintf("TGT for realm %s is valid for %s", realm, remaining))
+}
+
+// checkKrb5Conf checks if the krb5.conf file can be parsed and contains realm.
+func checkKrb5Conf(r *Report, realm string) {
+	conf, err := krbConfig.Load(krb5conf)
+	if err != nil {
+		r.fail("krb5.conf", fmt.Sprintf("could not parse %s: %v", krb5conf, err),
+			fmt.Sprintf("check the syntax of %s", krb5conf))
+		return
+	}
+	for _, rlm := range conf.Realms {
+		if rlm.Realm == realm {
+			r.pass("krb5.conf", fmt.Sprintf("%s contains realm %s", krb5conf, realm))
+			return
+		}
+	}
+	if conf.LibDefaults.DNSLookupKDC {
+		r.pass("krb5.conf", fmt.Sprintf("%s uses DNS lookup of KDCs for realm %s", krb5conf, realm))
+		return
+	}
+	r.fail("krb5.conf", fmt.Sprintf("%s does not contain realm %s", krb5conf, realm),
+		fmt.Sprintf("add realm %s to %s or enable dns_lookup_kdc", realm, krb5conf))
+}
+
+// getCertHash returns the hex encoded SHA-256 hash of the certificate of
+// the https server at url.
+func getCertHash(url string, timeout time.Duration) (string, error) {
+	c := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		// do not follow redirects, the hash must be of the configured
+		// server's certificate
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: timeout,
+	}
+	resp, err := c.Head(url)
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return "", errors.New("no tls connection")
+	}
+	hash := sha256.Sum256(resp.TLS.PeerCertificates[0].Raw)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// checkTND checks if the TND servers are reachable and if their
+// certificate hashes match.
+func checkTND(r *Report, cfg *config.Config) {
+	timeout := tnd.HTTPSTimeout
+	if cfg.TND.Config != nil {
+		timeout = cfg.TND.Config.HTTPSTimeout
+	}
+	for _, s := range cfg.TND.HTTPSServers {
+		name := "tnd " + s.URL
+		hash, err := getCertHash(s.URL, timeout)
+		if err != nil {
+			r.fail(name, fmt.Sprintf("server is not reachable: %v", err),
+				"check if you are connected to a trusted network")
+			continue
+		}
+		if hash != strings.ToLower(s.Hash) {
+			r.fail(name, fmt.Sprintf("certificate hash mismatch, got %s", hash),
+				"check the TND server hash in the config file")
+			continue
+		}
+		r.pass(name, "server is reachable and certificate hash matches")
+	}
+}
+
+// checkService checks if the service URL resolves and if the TLS handshake
+// with the service succeeds.
+func checkService(r *Report, cfg *config.Config) {
+	u, err := url.Parse(cfg.ServiceURL)
+	if err != nil || u.Hostname() == "" {
+		r.fail("service", fmt.Sprintf("invalid service URL %s", cfg.ServiceURL),
+			"check the ServiceURL in the config file")
+		return
+	}
+	addrs, err := lookupHost(u.Hostname())
+	if err != nil {
+		r.fail("service", fmt.Sprintf("could not resolve %s: %v", u.Hostname(), err),
+			"check the DNS settings and if you are connected to the network")
+		return
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	dialer := &net.Dialer{Timeout: cfg.GetLoginTimeout()}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(u.Hostname(), port), tlsConfig)
+	if err != nil {
+		r.fail("service", fmt.Sprintf("TLS handshake with %s failed: %v", u.Host, err),
+			"check the network connection, firewall rules and CA certificates")
+		return
+	}
+	_ = conn.Close()
+	r.pass("service", fmt.Sprintf("%s resolves to %s and TLS handshake succeeds",
+		u.Hostname(), strings.Join(addrs, ", ")))
+}
+
+// RunTND runs the TND server checks with config cfg and returns the report.
+func RunTND(cfg *config.Config) *Report {
+	r := &Report{}
+	checkTND(r, cfg)
+	return r
+}
+
+// Run runs all diagnostic checks with client c and config file configFile
+// and returns the report. If c is nil, the agent is not reachable. If
+// configFile is empty, the config of the running agent is checked.
+func Run(c client.Client, configFile string) *Report {
+	r := &Report{}
+	cfg := checkAgent(r, c)
+	cfg = checkConfig(r, cfg, configFile)
+	if cfg == nil {
+		for _, name := range []string{"ccache", "krb5.conf", "tnd", "service"} {
+			r.skip(name, "config is not available")
+		}
+		return r
+	}
+	checkCCache(r, cfg.Realm)
+	checkKrb5Conf(r, cfg.Realm)
+	checkTND(r, cfg)
+	checkService(r, cfg)
+	return r
+}
@@ -0,0 +1,378 @@
+package doctor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/telekom-mms/fw-id-agent/pkg/client"
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// testClient is a Client for testing.
+type testClient struct {
+	client.Client
+	status *status.Status
+	err    error
+}
+
+func (t *testClient) Query() (*status.Status, error) { return t.status, t.err }
+
+// testConfig returns a valid config for testing.
+func testConfig() *config.Config {
+	c := config.Default()
+	c.ServiceURL = "https://127.0.0.1:1"
+	c.Realm = "TEST.GOKRB5"
+	c.TND.HTTPSServers = []config.TNDHTTPSConfig{{URL: "https://127.0.0.1:1", Hash: "abcdef"}}
+	return c
+}
+
+// getResult returns the result of the check name in r.
+func getResult(r *Report, name string) Result {
+	for _, c := range r.Checks {
+		if c.Name == name {
+			return c.Result
+		}
+	}
+	return ""
+}
+
+// TestReport tests Passed, JSONIndent and Write of Report.
+func TestReport(t *testing.T) {
+	r := &Report{}
+	r.pass("pass", "passed")
+	r.skip("skip", "skipped")
+	if !r.Passed() {
+		t.Error("report should pass")
+	}
+	r.fail("fail", "failed", "fix it")
+	if r.Passed() {
+		t.Error("report should not pass")
+	}
+
+	// text
+	b := &bytes.Buffer{}
+	if err := r.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	want := `[PASS] pass: passed
+[SKIP] skip: skipped
+[FAIL] fail: failed
+       hint: fix it
+`
+	if got := b.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// json
+	j, err := r.JSONIndent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(j), `"Hint": "fix it"`) ||
+		strings.Count(string(j), `"Hint"`) != 1 {
+		t.Errorf("invalid json: %s", j)
+	}
+}
+
+// TestCheckAgent tests checkAgent.
+func TestCheckAgent(t *testing.T) {
+	// test without client
+	r := &Report{}
+	if checkAgent(r, nil) != nil || r.Passed() {
+		t.Error("check should fail without client")
+	}
+
+	// test with error
+	r = &Report{}
+	if checkAgent(r, &testClient{err: errors.New("test error")}) != nil || r.Passed() {
+		t.Error("check should fail with error")
+	}
+
+	// test with agent
+	r = &Report{}
+	s := status.New()
+	s.Config = testConfig()
+	if checkAgent(r, &testClient{status: s}) != s.Config || !r.Passed() {
+		t.Error("check should pass with agent")
+	}
+}
+
+// TestCheckConfig tests checkConfig.
+func TestCheckConfig(t *testing.T) {
+	// test without config
+	r := &Report{}
+	if checkConfig(r, nil, "") != nil || r.Passed() {
+		t.Error("check should fail without config")
+	}
+
+	// test with invalid config
+	r = &Report{}
+	if checkConfig(r, config.Default(), "") != nil || r.Passed() {
+		t.Error("check should fail with invalid config")
+	}
+
+	// test with not existing config file
+	r = &Report{}
+	file := filepath.Join(t.TempDir(), "config.json")
+	if checkConfig(r, testConfig(), file) != nil || r.Passed() {
+		t.Error("check should fail with not existing config file")
+	}
+
+	// test with valid config
+	r = &Report{}
+	cfg := testConfig()
+	if checkConfig(r, cfg, "") != cfg || !r.Passed() {
+		t.Error("check should pass with valid config")
+	}
+
+	// test with valid config file
+	r = &Report{}
+	b, err := cfg.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if c := checkConfig(r, nil, file); c == nil || c.ServiceURL != cfg.ServiceURL || !r.Passed() {
+		t.Error("check should pass with valid config file")
+	}
+}
+
+// TestCheckCCache tests checkCCache.
+func TestCheckCCache(t *testing.T) {
+	defer func(f func() (string, error)) { getCCacheFile = f }(getCCacheFile)
+	defer func() { now = time.Now }()
+
+	// test unknown ccache file
+	getCCacheFile = func() (string, error) { return "", errors.New("test error") }
+	r := &Report{}
+	checkCCache(r, "TEST.GOKRB5")
+	if r.Passed() {
+		t.Error("check should fail with unknown ccache file")
+	}
+
+	file := filepath.Join(t.TempDir(), "ccache")
+	getCCacheFile = func() (string, error) { return file, nil }
+
+	// test not existing ccache
+	r = &Report{}
+	checkCCache(r, "TEST.GOKRB5")
+	if r.Passed() {
+		t.Error("check should fail with not existing ccache")
+	}
+
+	// test invalid ccache
+	if err := os.WriteFile(file, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r = &Report{}
+	checkCCache(r, "TEST.GOKRB5")
+	if r.Passed() {
+		t.Error("check should fail with invalid ccache")
+	}
+
+	// write valid ccache
+	b, err := hex.DecodeString(testdata.CCACHE_TEST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// test wrong realm
+	r = &Report{}
+	checkCCache(r, "OTHER.REALM")
+	if r.Passed() {
+		t.Error("check should fail with wrong realm")
+	}
+
+	// test expired TGT
+	r = &Report{}
+	checkCCache(r, "TEST.GOKRB5")
+	if r.Passed() {
+		t.Error("check should fail with expired TGT")
+	}
+
+	// test valid TGT
+	now = func() time.Time { return time.Unix(0, 0) }
+	r = &Report{}
+	checkCCache(r, "TEST.GOKRB5")
+	if !r.Passed() {
+		t.Errorf("check should pass with valid TGT: %v", r.Checks[0])
+	}
+}
+
+// TestCheckKrb5Conf tests checkKrb5Conf.
+func TestCheckKrb5Conf(t *testing.T) {
+	defer func(s string) { krb5conf = s }(krb5conf)
+	krb5conf = filepath.Join(t.TempDir(), "krb5.conf")
+
+	// test not existing file
+	r := &Report{}
+	checkKrb5Conf(r, "TEST.GOKRB5")
+	if r.Passed() {
+		t.Error("check should fail with not existing file")
+	}
+
+	// test valid file
+	if err := os.WriteFile(krb5conf, []byte(testdata.KRB5_CONF), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r = &Report{}
+	checkKrb5Conf(r, "TEST.GOKRB5")
+	if !r.Passed() {
+		t.Error("check should pass with realm in file")
+	}
+
+	// test missing realm
+	r = &Report{}
+	checkKrb5Conf(r, "OTHER.REALM")
+	if r.Passed() {
+		t.Error("check should fail with missing realm")
+	}
+
+	// test dns lookup of kdc
+	conf := strings.Replace(testdata.KRB5_CONF, "dns_lookup_kdc = false", "dns_lookup_kdc = true", 1)
+	if err := os.WriteFile(krb5conf, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r = &Report{}
+	checkKrb5Conf(r, "OTHER.REALM")
+	if !r.Passed() {
+		t.Error("check should pass with dns lookup of kdc")
+	}
+}
+
+// TestCheckTND tests checkTND.
+func TestCheckTND(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer ts.Close()
+	hash := sha256.Sum256(ts.Certificate().Raw)
+
+	cfg := testConfig()
+	cfg.TND.HTTPSServers = []config.TNDHTTPSConfig{
+		{URL: ts.URL, Hash: strings.ToUpper(hex.EncodeToString(hash[:]))},
+		{URL: ts.URL, Hash: "abcdef"},
+		{URL: "https://127.0.0.1:1", Hash: "abcdef"},
+	}
+	r := &Report{}
+	checkTND(r, cfg)
+
+	want := []Result{ResultPass, ResultFail, ResultFail}
+	if len(r.Checks) != len(want) {
+		t.Fatalf("got %d checks, want %d", len(r.Checks), len(want))
+	}
+	for i, c := range r.Checks {
+		if c.Result != want[i] {
+			t.Errorf("got %s, want %s for %v", c.Result, want[i], c)
+		}
+	}
+}
+
+// TestGetCertHashRedirect tests getCertHash with a redirect, the redirect
+// should not be followed.
+func TestGetCertHashRedirect(t *testing.T) {
+	followed := make(chan struct{}, 1)
+	target := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		followed <- struct{}{}
+	}))
+	defer target.Close()
+	ts := httptest.NewTLSServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer ts.Close()
+
+	hash := sha256.Sum256(ts.Certificate().Raw)
+	got, err := getCertHash(ts.URL, time.Second)
+	if err != nil || got != hex.EncodeToString(hash[:]) {
+		t.Errorf("got %s, %v, want %x", got, err, hash)
+	}
+	select {
+	case <-followed:
+		t.Error("redirect should not be followed")
+	default:
+	}
+}
+
+// TestCheckService tests checkService.
+func TestCheckService(t *testing.T) {
+	defer func() {
+		tlsConfig = nil
+		lookupHost = net.LookupHost
+	}()
+	lookupHost = func(host string) ([]string, error) {
+		if host == "unknown" {
+			return nil, errors.New("test error")
+		}
+		return []string{host}, nil
+	}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer ts.Close()
+	tlsConfig = &tls.Config{RootCAs: ts.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+
+	for url, want := range map[string]Result{
+		"":                    ResultFail,
+		"https://unknown":     ResultFail,
+		"https://127.0.0.1:1": ResultFail,
+		ts.URL:                ResultPass,
+	} {
+		cfg := testConfig()
+		cfg.ServiceURL = url
+		r := &Report{}
+		checkService(r, cfg)
+		if got := getResult(r, "service"); got != want {
+			t.Errorf("got %s, want %s for %s", got, want, url)
+		}
+	}
+}
+
+// TestRun tests Run.
+func TestRun(t *testing.T) {
+	// test without agent and config
+	r := Run(nil, "")
+	if r.Passed() {
+		t.Error("report should fail")
+	}
+	for _, name := range []string{"ccache", "krb5.conf", "tnd", "service"} {
+		if got := getResult(r, name); got != ResultSkip {
+			t.Errorf("got %s, want %s for %s", got, ResultSkip, name)
+		}
+	}
+
+	// test with agent
+	s := status.New()
+	s.Config = testConfig()
+	r = Run(&testClient{status: s}, "")
+	for _, name := range []string{"agent", "config"} {
+		if got := getResult(r, name); got != ResultPass {
+			t.Errorf("got %s, want %s for %s", got, ResultPass, name)
+		}
+	}
+	for _, name := range []string{"ccache", "krb5.conf", "service"} {
+		if got := getResult(r, name); got == ResultSkip || got == "" {
+			t.Errorf("check %s should run", name)
+		}
+	}
+}
+
+// TestRunTND tests RunTND.
+func TestRunTND(t *testing.T) {
+	r := RunTND(testConfig())
+	if len(r.Checks) != 1 || r.Passed() {
+		t.Errorf("unexpected report: %v", r.Checks)
+	}
+}
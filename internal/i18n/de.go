@@ -0,0 +1,72 @@
+package i18n
+
+// german is the German message catalog.
+var german = map[Message]string{
+	// desktop notifications
+	NotifyTrustedNetworkTitle:   "Vertrauenswürdiges Netzwerk",
+	NotifyTrustedNetworkBody:    "Vertrauenswürdiges Netzwerk erkannt",
+	NotifyUntrustedNetworkTitle: "Kein vertrauenswürdiges Netzwerk",
+	NotifyUntrustedNetworkBody:  "Kein vertrauenswürdiges Netzwerk erkannt",
+	NotifyLoginTitle:            "Identity Agent Anmeldung",
+	NotifyLoginBody:             "Identity Agent erfolgreich angemeldet",
+	NotifyLogoutTitle:           "Identity Agent Abmeldung",
+	NotifyLogoutBody:            "Identity Agent abgemeldet",
+	NotifyLoginFailedTitle:      "Identity Agent Anmeldung fehlgeschlagen",
+	NotifyLoginFailedBody:       "Kerberos-Ticket ist ungültig oder abgelaufen",
+	NotifyStatusTitle:           "Identity Agent Status",
+	NotifyAgentNotRunning:       "Agent läuft nicht",
+	NotifyRequestFailed:         "Anfrage fehlgeschlagen: %v",
+
+	// notification actions and tray menu items
+	ActionReLogin: "Erneut anmelden",
+	ActionKinit:   "kinit ausführen",
+	ActionDetails: "Details",
+	ActionLogout:  "Abmelden",
+	ActionPause:   "Pausieren",
+	ActionResume:  "Fortsetzen",
+	ActionStatus:  "Status",
+
+	// status labels
+	LabelTrustedNetwork:     "Vertrauenswürdiges Netzwerk",
+	LabelLoginState:         "Anmeldestatus",
+	LabelAgentState:         "Agent-Status",
+	LabelSourceIP:           "Quell-IP",
+	LabelLastLogin:          "Letzte Anmeldung",
+	LabelLastKeepAlive:      "Letztes Keep-Alive",
+	LabelLoginFailures:      "Fehlgeschlagene Anmeldungen",
+	LabelKerberosTGT:        "Kerberos-TGT",
+	LabelKerberosTGTExpires: "Kerberos-TGT läuft ab",
+	LabelStartTime:          "Beginn",
+	LabelEndTime:            "Ende",
+	LabelConfig:             "Konfiguration",
+	LabelConfigSignature:    "Konfigurationssignatur",
+	LabelLogLevel:           "Log-Level",
+	LabelLastError:          "Letzter Fehler",
+	LabelNone:               "keins",
+
+	// trusted network states
+	TrustedNetworkUnknown:    "unbekannt",
+	TrustedNetworkNotTrusted: "nicht vertrauenswürdig",
+	TrustedNetworkTrusted:    "vertrauenswürdig",
+
+	// login states
+	LoginStateUnknown:    "unbekannt",
+	LoginStateLoggedOut:  "abgemeldet",
+	LoginStateLoggingIn:  "wird angemeldet",
+	LoginStateLoggedIn:   "angemeldet",
+	LoginStateLoggingOut: "wird abgemeldet",
+
+	// agent states
+	AgentStateUnknown:          "unbekannt",
+	AgentStateWaitingForTicket: "wartet auf Ticket",
+	AgentStateUntrusted:        "nicht vertrauenswürdig",
+	AgentStateTrustedLoggingIn: "vertrauenswürdig, wird angemeldet",
+	AgentStateLoggedIn:         "angemeldet",
+	AgentStatePaused:           "pausiert",
+	AgentStateSleeping:         "Ruhezustand",
+
+	// config signature states
+	SignatureUnknown:     "unbekannt",
+	SignatureNotVerified: "nicht geprüft",
+	SignatureValid:       "gültig",
+}
@@ -0,0 +1,72 @@
+package i18n
+
+// english is the English message catalog.
+var english = map[Message]string{
+	// desktop notifications
+	NotifyTrustedNetworkTitle:   "Trusted Network",
+	NotifyTrustedNetworkBody:    "Trusted network detected",
+	NotifyUntrustedNetworkTitle: "No Trusted Network",
+	NotifyUntrustedNetworkBody:  "No trusted network detected",
+	NotifyLoginTitle:            "Identity Agent Login",
+	NotifyLoginBody:             "Identity Agent logged in successfully",
+	NotifyLogoutTitle:           "Identity Agent Logout",
+	NotifyLogoutBody:            "Identity Agent logged out",
+	NotifyLoginFailedTitle:      "Identity Agent Login Failed",
+	NotifyLoginFailedBody:       "Kerberos ticket is invalid or expired",
+	NotifyStatusTitle:           "Identity Agent Status",
+	NotifyAgentNotRunning:       "Agent not running",
+	NotifyRequestFailed:         "Request failed: %v",
+
+	// notification actions and tray menu items
+	ActionReLogin: "Re-login",
+	ActionKinit:   "Run kinit",
+	ActionDetails: "Details",
+	ActionLogout:  "Logout",
+	ActionPause:   "Pause",
+	ActionResume:  "Resume",
+	ActionStatus:  "Status",
+
+	// status labels
+	LabelTrustedNetwork:     "Trusted Network",
+	LabelLoginState:         "Login State",
+	LabelAgentState:         "Agent State",
+	LabelSourceIP:           "Source IP",
+	LabelLastLogin:          "Last Login",
+	LabelLastKeepAlive:      "Last Keep-Alive",
+	LabelLoginFailures:      "Login Failures",
+	LabelKerberosTGT:        "Kerberos TGT",
+	LabelKerberosTGTExpires: "Kerberos TGT expires",
+	LabelStartTime:          "Start Time",
+	LabelEndTime:            "End Time",
+	LabelConfig:             "Config",
+	LabelConfigSignature:    "Config Signature",
+	LabelLogLevel:           "Log Level",
+	LabelLastError:          "Last Error",
+	LabelNone:               "none",
+
+	// trusted network states
+	TrustedNetworkUnknown:    "unknown",
+	TrustedNetworkNotTrusted: "not trusted",
+	TrustedNetworkTrusted:    "trusted",
+
+	// login states
+	LoginStateUnknown:    "unknown",
+	LoginStateLoggedOut:  "logged out",
+	LoginStateLoggingIn:  "logging in",
+	LoginStateLoggedIn:   "logged in",
+	LoginStateLoggingOut: "logging out",
+
+	// agent states
+	AgentStateUnknown:          "unknown",
+	AgentStateWaitingForTicket: "waiting for ticket",
+	AgentStateUntrusted:        "untrusted",
+	AgentStateTrustedLoggingIn: "trusted, logging in",
+	AgentStateLoggedIn:         "logged in",
+	AgentStatePaused:           "paused",
+	AgentStateSleeping:         "sleeping",
+
+	// config signature states
+	SignatureUnknown:     "unknown",
+	SignatureNotVerified: "not verified",
+	SignatureValid:       "valid",
+}
@@ -0,0 +1,233 @@
+// Package i18n contains the message catalogs for localized user-facing
+// messages, e.g., in desktop notifications and the status output of
+// fw-id-cli.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// Message is the ID of a localized message.
+type Message int
+
+// Messages.
+const (
+	// desktop notifications
+	NotifyTrustedNetworkTitle Message = iota
+	NotifyTrustedNetworkBody
+	NotifyUntrustedNetworkTitle
+	NotifyUntrustedNetworkBody
+	NotifyLoginTitle
+	NotifyLoginBody
+	NotifyLogoutTitle
+	NotifyLogoutBody
+	NotifyLoginFailedTitle
+	NotifyLoginFailedBody
+	NotifyStatusTitle
+	NotifyAgentNotRunning
+	NotifyRequestFailed
+
+	// notification actions and tray menu items
+	ActionReLogin
+	ActionKinit
+	ActionDetails
+	ActionLogout
+	ActionPause
+	ActionResume
+	ActionStatus
+
+	// status labels
+	LabelTrustedNetwork
+	LabelLoginState
+	LabelAgentState
+	LabelSourceIP
+	LabelLastLogin
+	LabelLastKeepAlive
+	LabelLoginFailures
+	LabelKerberosTGT
+	LabelKerberosTGTExpires
+	LabelStartTime
+	LabelEndTime
+	LabelConfig
+	LabelConfigSignature
+	LabelLogLevel
+	LabelLastError
+	LabelNone
+
+	// trusted network states
+	TrustedNetworkUnknown
+	TrustedNetworkNotTrusted
+	TrustedNetworkTrusted
+
+	// login states
+	LoginStateUnknown
+	LoginStateLoggedOut
+	LoginStateLoggingIn
+	LoginStateLoggedIn
+	LoginStateLoggingOut
+
+	// agent states
+	AgentStateUnknown
+	AgentStateWaitingForTicket
+	AgentStateUntrusted
+	AgentStateTrustedLoggingIn
+	AgentStateLoggedIn
+	AgentStatePaused
+	AgentStateSleeping
+
+	// config signature states
+	SignatureUnknown
+	SignatureNotVerified
+	SignatureValid
+
+	// numMessages is the number of messages, it must be the last
+	numMessages
+)
+
+// Languages.
+const (
+	English = "en"
+	German  = "de"
+)
+
+// catalogs are the message catalogs of the languages.
+var catalogs = map[string]map[Message]string{
+	English: english,
+	German:  german,
+}
+
+// current is the message catalog of the current language.
+var current atomic.Pointer[map[Message]string]
+
+func init() {
+	SetLanguage(English)
+}
+
+// EnvLocale returns the locale for messages from the environment variables
+// LC_ALL, LC_MESSAGES and LANG in this order.
+func EnvLocale() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if l := os.Getenv(env); l != "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// Language returns the language of locale, e.g., "de" for "de_DE.UTF-8". It
+// returns English if there is no catalog for the language.
+func Language(locale string) string {
+	lang, _, _ := strings.Cut(locale, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	lang, _, _ = strings.Cut(lang, "_")
+	lang = strings.ToLower(lang)
+	if _, ok := catalogs[lang]; !ok {
+		return English
+	}
+	return lang
+}
+
+// SetLanguage sets the language of the messages. It uses English if there
+// is no catalog for the language.
+func SetLanguage(lang string) {
+	c, ok := catalogs[lang]
+	if !ok {
+		c = catalogs[English]
+	}
+	current.Store(&c)
+}
+
+// SetLocale sets the language of the messages from locale, see Language.
+func SetLocale(locale string) {
+	SetLanguage(Language(locale))
+}
+
+// String returns the message m in the current language. It falls back to
+// English if the message is missing in the current language.
+func String(m Message) string {
+	if s, ok := (*current.Load())[m]; ok {
+		return s
+	}
+	if s, ok := english[m]; ok {
+		return s
+	}
+	return fmt.Sprintf("!(Message=%d)", m)
+}
+
+// Sprintf formats the message m in the current language with the arguments
+// a, see fmt.Sprintf.
+func Sprintf(m Message, a ...any) string {
+	return fmt.Sprintf(String(m), a...)
+}
+
+// TrustedNetwork returns the trusted network state t in the current
+// language.
+func TrustedNetwork(t status.TrustedNetwork) string {
+	switch t {
+	case status.TrustedNetworkUnknown:
+		return String(TrustedNetworkUnknown)
+	case status.TrustedNetworkNotTrusted:
+		return String(TrustedNetworkNotTrusted)
+	case status.TrustedNetworkTrusted:
+		return String(TrustedNetworkTrusted)
+	}
+	return t.String()
+}
+
+// LoginState returns the login state l in the current language.
+func LoginState(l status.LoginState) string {
+	switch l {
+	case status.LoginStateUnknown:
+		return String(LoginStateUnknown)
+	case status.LoginStateLoggedOut:
+		return String(LoginStateLoggedOut)
+	case status.LoginStateLoggingIn:
+		return String(LoginStateLoggingIn)
+	case status.LoginStateLoggedIn:
+		return String(LoginStateLoggedIn)
+	case status.LoginStateLoggingOut:
+		return String(LoginStateLoggingOut)
+	}
+	return l.String()
+}
+
+// AgentState returns the agent state a in the current language.
+func AgentState(a status.AgentState) string {
+	switch a {
+	case status.AgentStateUnknown:
+		return String(AgentStateUnknown)
+	case status.AgentStateWaitingForTicket:
+		return String(AgentStateWaitingForTicket)
+	case status.AgentStateUntrusted:
+		return String(AgentStateUntrusted)
+	case status.AgentStateTrustedLoggingIn:
+		return String(AgentStateTrustedLoggingIn)
+	case status.AgentStateLoggedIn:
+		return String(AgentStateLoggedIn)
+	case status.AgentStatePaused:
+		return String(AgentStatePaused)
+	case status.AgentStateSleeping:
+		return String(AgentStateSleeping)
+	}
+	return a.String()
+}
+
+// SignatureStatus returns the config signature status s in the current
+// language.
+func SignatureStatus(s config.SignatureStatus) string {
+	switch s {
+	case config.SignatureUnknown:
+		return String(SignatureUnknown)
+	case config.SignatureNotVerified:
+		return String(SignatureNotVerified)
+	case config.SignatureValid:
+		return String(SignatureValid)
+	}
+	return s.String()
+}
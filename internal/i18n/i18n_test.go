@@ -0,0 +1,168 @@
+package i18n
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// verbs matches the formatting verbs in a message.
+var verbs = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+// TestCatalogsComplete tests that all catalogs contain all messages with the
+// same formatting verbs as the English catalog.
+func TestCatalogsComplete(t *testing.T) {
+	for lang, catalog := range catalogs {
+		if len(catalog) != int(numMessages) {
+			t.Errorf("%s: got %d messages, want %d", lang, len(catalog), numMessages)
+		}
+		for m := Message(0); m < numMessages; m++ {
+			s, ok := catalog[m]
+			if !ok || s == "" {
+				t.Errorf("%s: message %d is missing", lang, m)
+				continue
+			}
+			got := verbs.FindAllString(s, -1)
+			want := verbs.FindAllString(english[m], -1)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: message %d has verbs %v, want %v", lang, m, got, want)
+			}
+		}
+	}
+}
+
+// TestEnglishStates tests that the English states match the strings of the
+// status package.
+func TestEnglishStates(t *testing.T) {
+	SetLanguage(English)
+	for _, tn := range []status.TrustedNetwork{
+		status.TrustedNetworkUnknown,
+		status.TrustedNetworkNotTrusted,
+		status.TrustedNetworkTrusted,
+	} {
+		if got, want := TrustedNetwork(tn), tn.String(); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+	for _, l := range []status.LoginState{
+		status.LoginStateUnknown,
+		status.LoginStateLoggedOut,
+		status.LoginStateLoggingIn,
+		status.LoginStateLoggedIn,
+		status.LoginStateLoggingOut,
+	} {
+		if got, want := LoginState(l), l.String(); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+	for _, a := range []status.AgentState{
+		status.AgentStateUnknown,
+		status.AgentStateWaitingForTicket,
+		status.AgentStateUntrusted,
+		status.AgentStateTrustedLoggingIn,
+		status.AgentStateLoggedIn,
+		status.AgentStatePaused,
+		status.AgentStateSleeping,
+	} {
+		if got, want := AgentState(a), a.String(); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+	for _, s := range []config.SignatureStatus{
+		config.SignatureUnknown,
+		config.SignatureNotVerified,
+		config.SignatureValid,
+	} {
+		if got, want := SignatureStatus(s), s.String(); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
+// TestEnvLocale tests EnvLocale.
+func TestEnvLocale(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "")
+	if got := EnvLocale(); got != "" {
+		t.Errorf("got %s, want empty", got)
+	}
+
+	t.Setenv("LANG", "en_US.UTF-8")
+	if got := EnvLocale(); got != "en_US.UTF-8" {
+		t.Errorf("got %s, want en_US.UTF-8", got)
+	}
+
+	t.Setenv("LC_MESSAGES", "de_DE.UTF-8")
+	if got := EnvLocale(); got != "de_DE.UTF-8" {
+		t.Errorf("got %s, want de_DE.UTF-8", got)
+	}
+
+	t.Setenv("LC_ALL", "C")
+	if got := EnvLocale(); got != "C" {
+		t.Errorf("got %s, want C", got)
+	}
+}
+
+// TestLanguage tests Language.
+func TestLanguage(t *testing.T) {
+	for locale, want := range map[string]string{
+		"":                 English,
+		"C":                English,
+		"POSIX":            English,
+		"fr_FR.UTF-8":      English,
+		"en_GB.UTF-8":      English,
+		"de":               German,
+		"de_DE.UTF-8":      German,
+		"de_AT@euro":       German,
+		"DE_CH.ISO-8859-1": German,
+	} {
+		if got := Language(locale); got != want {
+			t.Errorf("%s: got %s, want %s", locale, got, want)
+		}
+	}
+}
+
+// TestString tests String and Sprintf in different languages.
+func TestString(t *testing.T) {
+	defer SetLanguage(English)
+
+	// test English
+	SetLocale("C")
+	if got := String(LabelLoginState); got != "Login State" {
+		t.Errorf("got %s, want Login State", got)
+	}
+	if got := LoginState(status.LoginStateLoggedIn); got != "logged in" {
+		t.Errorf("got %s, want logged in", got)
+	}
+
+	// test German
+	SetLocale("de_DE.UTF-8")
+	if got := String(LabelLoginState); got != "Anmeldestatus" {
+		t.Errorf("got %s, want Anmeldestatus", got)
+	}
+	if got := Sprintf(NotifyRequestFailed, "test"); got != "Anfrage fehlgeschlagen: test" {
+		t.Errorf("got %s, want Anfrage fehlgeschlagen: test", got)
+	}
+	if got := AgentState(status.AgentStatePaused); got != "pausiert" {
+		t.Errorf("got %s, want pausiert", got)
+	}
+	if got := TrustedNetwork(status.TrustedNetworkTrusted); got != "vertrauenswürdig" {
+		t.Errorf("got %s, want vertrauenswürdig", got)
+	}
+	if got := SignatureStatus(config.SignatureValid); got != "gültig" {
+		t.Errorf("got %s, want gültig", got)
+	}
+
+	// test unknown language and message
+	SetLanguage("xx")
+	if got := String(LabelLoginState); got != "Login State" {
+		t.Errorf("got %s, want Login State", got)
+	}
+	if got := String(numMessages); got == "" {
+		t.Error("unknown message should not be empty")
+	}
+}
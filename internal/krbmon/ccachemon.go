@@ -70,15 +70,15 @@ var getCredentialCacheFilename = func() (string, error) {
 	envVar := os.Getenv("KRB5CCNAME")
 	if envVar == "" {
 		newEnv := createCredentialCacheEnvVar()
-		log.WithField("new", newEnv).
+		log.WithField("krb5ccname", newEnv).
 			Debug("Kerberos CCache Monitor could not get environment variable KRB5CCNAME, setting it")
 		envVar = newEnv
 	}
 	if !strings.HasPrefix(envVar, "FILE:") {
 		newEnv := createCredentialCacheEnvVar()
 		log.WithFields(log.Fields{
-			"old": envVar,
-			"new": newEnv,
+			"old_krb5ccname": envVar,
+			"krb5ccname":     newEnv,
 		}).Error("Kerberos CCache Monitor got invalid environment variable KRB5CCNAME, resetting it")
 		envVar = newEnv
 	}
@@ -89,6 +89,11 @@ var getCredentialCacheFilename = func() (string, error) {
 	return strings.TrimPrefix(envVar, "FILE:"), nil
 }
 
+// CCacheFile returns the ccache file name of the current user.
+func CCacheFile() (string, error) {
+	return getCredentialCacheFilename()
+}
+
 // isCCacheFileEvent checks if event is a ccache file event.
 func (c *CCacheMon) isCCacheFileEvent(event fsnotify.Event) bool {
 	return event.Name == c.cCacheFile
@@ -101,8 +106,8 @@ func (c *CCacheMon) handleCCacheFileEvent(event fsnotify.Event) {
 		return
 	}
 	log.WithFields(log.Fields{
-		"name": event.Name,
-		"op":   event.Op,
+		"file":    event.Name,
+		"file_op": event.Op,
 	}).Debug("Kerberos CCache Monitor handling file event")
 
 	// read ccache file
@@ -51,8 +51,8 @@ func (c *ConfMon) handleConfigFileEvent(event fsnotify.Event) {
 		return
 	}
 	log.WithFields(log.Fields{
-		"name": event.Name,
-		"op":   event.Op,
+		"file":    event.Name,
+		"file_op": event.Op,
 	}).Debug("Kerberos Config Monitor handling file event")
 
 	// load config file
@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// journalSocket is the socket of the native systemd journal protocol.
+	journalSocket = "/run/systemd/journal/socket"
+
+	// syslogIdentifier is the syslog identifier of the agent in the journal.
+	syslogIdentifier = "fw-id-agent"
+
+	// fieldPrefix is the prefix of the agent's journal fields.
+	fieldPrefix = "FW_ID_"
+)
+
+// journaldHook is a logrus hook that sends log entries to the systemd journal
+// with the native journal protocol.
+type journaldHook struct {
+	conn *net.UnixConn
+}
+
+// Levels returns the log levels of the hook.
+func (j *journaldHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// priority returns the syslog priority of level.
+func priority(level log.Level) int {
+	switch level {
+	case log.PanicLevel:
+		return 0
+	case log.FatalLevel:
+		return 2
+	case log.ErrorLevel:
+		return 3
+	case log.WarnLevel:
+		return 4
+	case log.InfoLevel:
+		return 6
+	}
+	return 7
+}
+
+// fieldName returns the journal field name of the log field key, e.g.,
+// "FW_ID_LOGIN_STATE" for "login_state".
+func fieldName(key string) string {
+	name := []byte(fieldPrefix + strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// writeField writes the journal field name with value to b. Values that
+// contain newlines are written in the binary format.
+func writeField(b *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(b, "%s=%s\n", name, value)
+		return
+	}
+	b.WriteString(name)
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// message returns the journal message of the log entry.
+func message(entry *log.Entry) []byte {
+	b := &bytes.Buffer{}
+	writeField(b, "MESSAGE", entry.Message)
+	writeField(b, "PRIORITY", fmt.Sprint(priority(entry.Level)))
+	writeField(b, "SYSLOG_IDENTIFIER", syslogIdentifier)
+
+	// sort fields for reproducible messages
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		v := entry.Data[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		writeField(b, fieldName(k), fmt.Sprint(v))
+	}
+	return b.Bytes()
+}
+
+// Fire sends the log entry to the journal.
+func (j *journaldHook) Fire(entry *log.Entry) error {
+	_, err := j.conn.Write(message(entry))
+	return err
+}
+
+// Close closes the connection to the journal.
+func (j *journaldHook) Close() {
+	_ = j.conn.Close()
+}
+
+// newJournaldHookSocket returns a new journald hook that sends log entries
+// to the journal socket.
+func newJournaldHookSocket(socket string) (*journaldHook, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{
+		Name: socket,
+		Net:  "unixgram",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &journaldHook{conn: conn}, nil
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TestFieldName tests fieldName.
+func TestFieldName(t *testing.T) {
+	for key, want := range map[string]string{
+		"login_state": "FW_ID_LOGIN_STATE",
+		"error_code":  "FW_ID_ERROR_CODE",
+		"error":       "FW_ID_ERROR",
+		"source-ip":   "FW_ID_SOURCE_IP",
+	} {
+		if got := fieldName(key); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
+// TestPriority tests priority.
+func TestPriority(t *testing.T) {
+	for level, want := range map[log.Level]int{
+		log.PanicLevel: 0,
+		log.FatalLevel: 2,
+		log.ErrorLevel: 3,
+		log.WarnLevel:  4,
+		log.InfoLevel:  6,
+		log.DebugLevel: 7,
+		log.TraceLevel: 7,
+	} {
+		if got := priority(level); got != want {
+			t.Errorf("got %d, want %d", got, want)
+		}
+	}
+}
+
+// TestMessage tests message.
+func TestMessage(t *testing.T) {
+	entry := log.WithFields(log.Fields{
+		"login_state": "logged in",
+		"error":       errors.New("test\nerror"),
+	})
+	entry.Level = log.ErrorLevel
+	entry.Message = "test message"
+
+	want := []byte("MESSAGE=test message\n" +
+		"PRIORITY=3\n" +
+		"SYSLOG_IDENTIFIER=fw-id-agent\n" +
+		"FW_ID_ERROR\n\x0a\x00\x00\x00\x00\x00\x00\x00test\nerror\n" +
+		"FW_ID_LOGIN_STATE=logged in\n")
+	if got := message(entry); !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestJournaldHook tests journaldHook.
+func TestJournaldHook(t *testing.T) {
+	// test invalid socket
+	if _, err := newJournaldHookSocket(filepath.Join(t.TempDir(), "does-not-exist")); err == nil {
+		t.Error("hook should fail with invalid socket")
+	}
+
+	// test with socket
+	socket := filepath.Join(t.TempDir(), "journal")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{
+		Name: socket,
+		Net:  "unixgram",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	hook, err := newJournaldHookSocket(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close()
+
+	if len(hook.Levels()) != len(log.AllLevels) {
+		t.Error("hook should fire on all levels")
+	}
+
+	entry := log.WithField("source_ip", "192.168.1.10")
+	entry.Level = log.InfoLevel
+	entry.Message = "test"
+	if err := hook.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1024)
+	n, err := conn.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "MESSAGE=test\nPRIORITY=6\nSYSLOG_IDENTIFIER=fw-id-agent\n" +
+		"FW_ID_SOURCE_IP=192.168.1.10\n"
+	if got := string(b[:n]); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+// Package logging contains the log format and level setup of the agent.
+//
+// Log fields use lowercase snake_case names, e.g., "login_state",
+// "trusted_network", "source_ip" or "error_code", so they can be aggregated
+// across packages. With the journald log format, fields are sent as journal
+// fields with the prefix "FW_ID_" in upper case, e.g., "FW_ID_LOGIN_STATE".
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+)
+
+// current journald hook, only set with journald log format.
+var journald *journaldHook
+
+// newJournaldHook is the journald hook constructor for testing.
+var newJournaldHook = func() (*journaldHook, error) {
+	return newJournaldHookSocket(journalSocket)
+}
+
+// setText sets the text log format.
+func setText() {
+	log.SetFormatter(&log.TextFormatter{})
+	log.SetOutput(os.Stderr)
+}
+
+// Setup sets the log format and log level of the standard logger. If the
+// journald log format is not available, e.g., because the agent is not
+// running under systemd, it falls back to the text log format and returns an
+// error.
+func Setup(format, level string) error {
+	// reset previous journald hook, keep recent log entries
+	log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+	log.AddHook(recent)
+	if journald != nil {
+		journald.Close()
+		journald = nil
+	}
+
+	// set log level
+	l, err := log.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	log.SetLevel(l)
+
+	// set log format
+	switch format {
+	case "", config.LogFormatText:
+		setText()
+	case config.LogFormatJSON:
+		log.SetFormatter(&log.JSONFormatter{})
+		log.SetOutput(os.Stderr)
+	case config.LogFormatJournald:
+		hook, err := newJournaldHook()
+		if err != nil {
+			setText()
+			return fmt.Errorf("journald not available: %w", err)
+		}
+		journald = hook
+		log.AddHook(hook)
+		log.SetOutput(io.Discard)
+	default:
+		setText()
+		return fmt.Errorf("invalid log format: %s", format)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+)
+
+// TestSetup tests Setup.
+func TestSetup(t *testing.T) {
+	defer func(f func() (*journaldHook, error)) { newJournaldHook = f }(newJournaldHook)
+	defer func() { _ = Setup(config.LogFormatText, "info") }()
+
+	// test text and json
+	for _, format := range []string{"", config.LogFormatText, config.LogFormatJSON} {
+		if err := Setup(format, "warn"); err != nil {
+			t.Fatal(err)
+		}
+		if log.GetLevel() != log.WarnLevel {
+			t.Errorf("got %v, want %v", log.GetLevel(), log.WarnLevel)
+		}
+	}
+	if _, ok := log.StandardLogger().Formatter.(*log.JSONFormatter); !ok {
+		t.Error("formatter should be json")
+	}
+
+	// test invalid level and format
+	if err := Setup(config.LogFormatText, "invalid"); err == nil {
+		t.Error("invalid level should fail")
+	}
+	if err := Setup("invalid", "info"); err == nil {
+		t.Error("invalid format should fail")
+	}
+
+	// test journald not available, should fall back to text
+	newJournaldHook = func() (*journaldHook, error) {
+		return nil, errors.New("test error")
+	}
+	if err := Setup(config.LogFormatJournald, "debug"); err == nil {
+		t.Error("unavailable journald should fail")
+	}
+	if _, ok := log.StandardLogger().Formatter.(*log.TextFormatter); !ok {
+		t.Error("formatter should be text")
+	}
+
+	// test journald
+	socket := filepath.Join(t.TempDir(), "journal")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{
+		Name: socket,
+		Net:  "unixgram",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	newJournaldHook = func() (*journaldHook, error) {
+		return newJournaldHookSocket(socket)
+	}
+	if err := Setup(config.LogFormatJournald, "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if journald == nil || log.StandardLogger().Out != io.Discard {
+		t.Error("journald hook should be set")
+	}
+
+	// test reset of journald
+	if err := Setup(config.LogFormatText, "info"); err != nil {
+		t.Fatal(err)
+	}
+	if journald != nil || len(log.StandardLogger().Hooks[log.InfoLevel]) != 1 {
+		t.Error("journald hook should be reset")
+	}
+	if log.StandardLogger().Hooks[log.InfoLevel][0] != recent {
+		t.Error("recent hook should be set")
+	}
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// recentSize is the number of recent log entries kept in memory.
+const recentSize = 1000
+
+// recentHook is a logrus hook that keeps the recent log entries in text
+// format in memory, e.g., for diagnostics.
+type recentHook struct {
+	mutex     sync.Mutex
+	formatter log.Formatter
+	entries   [][]byte
+	next      int
+}
+
+// Levels returns the log levels of the hook.
+func (r *recentHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire adds entry to the recent log entries.
+func (r *recentHook) Fire(entry *log.Entry) error {
+	b, err := r.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	// the formatter may reuse the entry's buffer, copy it
+	b = bytes.Clone(b)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.entries) < recentSize {
+		r.entries = append(r.entries, b)
+		return nil
+	}
+	r.entries[r.next] = b
+	r.next = (r.next + 1) % recentSize
+	return nil
+}
+
+// Bytes returns the recent log entries, oldest first.
+func (r *recentHook) Bytes() []byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	b := &bytes.Buffer{}
+	for i := range r.entries {
+		b.Write(r.entries[(r.next+i)%len(r.entries)])
+	}
+	return b.Bytes()
+}
+
+// newRecentHook returns a new recent log entries hook.
+func newRecentHook() *recentHook {
+	return &recentHook{
+		formatter: &log.TextFormatter{
+			DisableColors: true,
+			FullTimestamp: true,
+		},
+	}
+}
+
+// recent keeps the recent log entries of the standard logger.
+var recent = newRecentHook()
+
+// Recent returns the recent log entries of the standard logger in text
+// format, oldest first. Log entries are only kept after Setup.
+func Recent() []byte {
+	return recent.Bytes()
+}
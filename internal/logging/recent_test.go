@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TestRecentHook tests the recent log entries hook.
+func TestRecentHook(t *testing.T) {
+	r := newRecentHook()
+	if len(r.Levels()) != len(log.AllLevels) {
+		t.Errorf("got %v, want all levels", r.Levels())
+	}
+
+	// test empty
+	if b := r.Bytes(); len(b) != 0 {
+		t.Errorf("got %q, want empty", b)
+	}
+
+	// test with entries
+	logger := log.New()
+	logger.SetOutput(&bytes.Buffer{})
+	logger.AddHook(r)
+	logger.WithField("test_field", 42).Info("first entry")
+	logger.Debug("not logged")
+	logger.Warn("second entry")
+
+	got := string(r.Bytes())
+	if strings.Count(got, "\n") != 2 ||
+		!strings.Contains(got, `msg="first entry" test_field=42`) ||
+		strings.Index(got, "first entry") > strings.Index(got, "second entry") {
+		t.Errorf("unexpected entries: %q", got)
+	}
+
+	// test overflow, oldest entries are dropped
+	for i := range recentSize + 10 {
+		logger.Infof("entry %d", i)
+	}
+	got = string(r.Bytes())
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	if len(lines) != recentSize ||
+		!strings.Contains(lines[0], "entry 10\"") ||
+		!strings.Contains(lines[recentSize-1], fmt.Sprintf("entry %d\"", recentSize+9)) {
+		t.Errorf("unexpected entries after overflow: %q ... %q", lines[0], lines[len(lines)-1])
+	}
+}
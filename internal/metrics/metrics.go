@@ -0,0 +1,198 @@
+// Package metrics contains the agent metrics in the Prometheus text format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// ContentType is the content type of the Prometheus text format.
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	// prefix is the prefix of all metric names.
+	prefix = "fw_id_agent_"
+)
+
+// durationBuckets are the upper bounds of the login duration histogram
+// buckets in seconds.
+var durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics are the agent metrics.
+type Metrics struct {
+	mutex sync.Mutex
+
+	// uid label of all metrics, so metrics of multiple users on the same
+	// host can be told apart
+	uid string
+
+	// login counters, failures by error class
+	loginAttempts  uint64
+	loginSuccesses uint64
+	loginFailures  map[string]uint64
+
+	// login duration histogram, counts per bucket are not cumulative
+	durationCounts []uint64
+	durationSum    float64
+	durationCount  uint64
+
+	// current state
+	trustedNetwork bool
+	loggedIn       bool
+	tgtEndTime     int64
+	lastKeepAlive  int64
+}
+
+// ObserveLogin records a login attempt that took duration. Class is the error
+// class of a failed login or empty for a successful login.
+func (m *Metrics) ObserveLogin(duration time.Duration, class string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.loginAttempts++
+	if class == "" {
+		m.loginSuccesses++
+	} else {
+		m.loginFailures[class]++
+	}
+
+	seconds := duration.Seconds()
+	for i, b := range durationBuckets {
+		if seconds <= b {
+			m.durationCounts[i]++
+			break
+		}
+	}
+	m.durationSum += seconds
+	m.durationCount++
+}
+
+// SetTrustedNetwork sets whether the agent is connected to a trusted network.
+func (m *Metrics) SetTrustedNetwork(trusted bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.trustedNetwork = trusted
+}
+
+// SetLoggedIn sets whether the agent is logged in.
+func (m *Metrics) SetLoggedIn(loggedIn bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.loggedIn = loggedIn
+}
+
+// SetKerberosTGTEndTime sets the end time of the kerberos TGT as unix
+// timestamp.
+func (m *Metrics) SetKerberosTGTEndTime(endTime int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.tgtEndTime = endTime
+}
+
+// SetLastKeepAlive sets the time of the last successful keep-alive as unix
+// timestamp.
+func (m *Metrics) SetLastKeepAlive(lastKeepAlive int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lastKeepAlive = lastKeepAlive
+}
+
+// formatFloat formats f as Prometheus sample value.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// formatBool formats b as Prometheus sample value.
+func formatBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// Write writes the metrics at time now in the Prometheus text format to w.
+func (m *Metrics) Write(w io.Writer, now time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	b := &bytes.Buffer{}
+	labels := fmt.Sprintf("uid=%q", m.uid)
+	header := func(name, typ, help string) {
+		fmt.Fprintf(b, "# HELP %s%s %s\n", prefix, name, help)
+		fmt.Fprintf(b, "# TYPE %s%s %s\n", prefix, name, typ)
+	}
+	sample := func(name, extraLabels, value string) {
+		fmt.Fprintf(b, "%s%s{%s%s} %s\n", prefix, name, labels, extraLabels, value)
+	}
+
+	// login counters
+	header("login_attempts_total", "counter", "Total number of login attempts.")
+	sample("login_attempts_total", "", strconv.FormatUint(m.loginAttempts, 10))
+	header("login_successes_total", "counter", "Total number of successful logins.")
+	sample("login_successes_total", "", strconv.FormatUint(m.loginSuccesses, 10))
+	header("login_failures_total", "counter", "Total number of failed logins by error class.")
+	classes := make([]string, 0, len(m.loginFailures))
+	for c := range m.loginFailures {
+		classes = append(classes, c)
+	}
+	slices.Sort(classes)
+	for _, c := range classes {
+		sample("login_failures_total", fmt.Sprintf(",class=%q", c),
+			strconv.FormatUint(m.loginFailures[c], 10))
+	}
+
+	// login duration histogram
+	header("login_duration_seconds", "histogram", "Duration of login requests in seconds.")
+	cumulative := uint64(0)
+	for i, bucket := range durationBuckets {
+		cumulative += m.durationCounts[i]
+		sample("login_duration_seconds_bucket", fmt.Sprintf(",le=%q", formatFloat(bucket)),
+			strconv.FormatUint(cumulative, 10))
+	}
+	sample("login_duration_seconds_bucket", `,le="+Inf"`, strconv.FormatUint(m.durationCount, 10))
+	sample("login_duration_seconds_sum", "", formatFloat(m.durationSum))
+	sample("login_duration_seconds_count", "", strconv.FormatUint(m.durationCount, 10))
+
+	// current state
+	header("trusted_network", "gauge", "Whether the agent is connected to a trusted network.")
+	sample("trusted_network", "", formatBool(m.trustedNetwork))
+	header("logged_in", "gauge", "Whether the agent is logged in.")
+	sample("logged_in", "", formatBool(m.loggedIn))
+	if m.tgtEndTime > 0 {
+		remaining := max(0, m.tgtEndTime-now.Unix())
+		header("kerberos_tgt_remaining_seconds", "gauge", "Remaining lifetime of the kerberos TGT in seconds.")
+		sample("kerberos_tgt_remaining_seconds", "", strconv.FormatInt(remaining, 10))
+	}
+	if m.lastKeepAlive > 0 {
+		age := max(0, now.Unix()-m.lastKeepAlive)
+		header("keep_alive_age_seconds", "gauge", "Time since the last successful keep-alive in seconds.")
+		sample("keep_alive_age_seconds", "", strconv.FormatInt(age, 10))
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// NewMetrics returns new metrics with failure counters for the error classes
+// in classes.
+func NewMetrics(classes []string) *Metrics {
+	failures := make(map[string]uint64)
+	for _, c := range classes {
+		failures[c] = 0
+	}
+	return &Metrics{
+		uid:            strconv.Itoa(os.Getuid()),
+		loginFailures:  failures,
+		durationCounts: make([]uint64, len(durationBuckets)),
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMetricsWrite tests Write of Metrics.
+func TestMetricsWrite(t *testing.T) {
+	m := NewMetrics([]string{"token", "backend"})
+	m.uid = "1000"
+	now := time.Unix(1000, 0)
+
+	// test initial metrics, tgt and keep-alive not known
+	b := &bytes.Buffer{}
+	if err := m.Write(b, now); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# TYPE fw_id_agent_login_attempts_total counter\n",
+		`fw_id_agent_login_attempts_total{uid="1000"} 0` + "\n",
+		`fw_id_agent_login_failures_total{uid="1000",class="backend"} 0` + "\n",
+		`fw_id_agent_login_failures_total{uid="1000",class="token"} 0` + "\n",
+		`fw_id_agent_login_duration_seconds_bucket{uid="1000",le="+Inf"} 0` + "\n",
+		`fw_id_agent_trusted_network{uid="1000"} 0` + "\n",
+		`fw_id_agent_logged_in{uid="1000"} 0` + "\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics should contain %q:\n%s", want, b.String())
+		}
+	}
+	for _, notWant := range []string{
+		"kerberos_tgt_remaining_seconds",
+		"keep_alive_age_seconds",
+	} {
+		if strings.Contains(b.String(), notWant) {
+			t.Errorf("metrics should not contain %q", notWant)
+		}
+	}
+
+	// test after logins and state changes
+	m.ObserveLogin(200*time.Millisecond, "")
+	m.ObserveLogin(3*time.Second, "token")
+	m.ObserveLogin(time.Minute, "communication")
+	m.SetTrustedNetwork(true)
+	m.SetLoggedIn(true)
+	m.SetKerberosTGTEndTime(1600)
+	m.SetLastKeepAlive(970)
+
+	b.Reset()
+	if err := m.Write(b, now); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`fw_id_agent_login_attempts_total{uid="1000"} 3` + "\n",
+		`fw_id_agent_login_successes_total{uid="1000"} 1` + "\n",
+		`fw_id_agent_login_failures_total{uid="1000",class="backend"} 0` + "\n",
+		`fw_id_agent_login_failures_total{uid="1000",class="communication"} 1` + "\n",
+		`fw_id_agent_login_failures_total{uid="1000",class="token"} 1` + "\n",
+		`fw_id_agent_login_duration_seconds_bucket{uid="1000",le="0.1"} 0` + "\n",
+		`fw_id_agent_login_duration_seconds_bucket{uid="1000",le="0.25"} 1` + "\n",
+		`fw_id_agent_login_duration_seconds_bucket{uid="1000",le="5"} 2` + "\n",
+		`fw_id_agent_login_duration_seconds_bucket{uid="1000",le="30"} 2` + "\n",
+		`fw_id_agent_login_duration_seconds_bucket{uid="1000",le="+Inf"} 3` + "\n",
+		`fw_id_agent_login_duration_seconds_sum{uid="1000"} 63.2` + "\n",
+		`fw_id_agent_login_duration_seconds_count{uid="1000"} 3` + "\n",
+		`fw_id_agent_trusted_network{uid="1000"} 1` + "\n",
+		`fw_id_agent_logged_in{uid="1000"} 1` + "\n",
+		`fw_id_agent_kerberos_tgt_remaining_seconds{uid="1000"} 600` + "\n",
+		`fw_id_agent_keep_alive_age_seconds{uid="1000"} 30` + "\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics should contain %q:\n%s", want, b.String())
+		}
+	}
+
+	// test expired tgt
+	m.SetKerberosTGTEndTime(500)
+	b.Reset()
+	if err := m.Write(b, now); err != nil {
+		t.Fatal(err)
+	}
+	want := `fw_id_agent_kerberos_tgt_remaining_seconds{uid="1000"} 0` + "\n"
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("metrics should contain %q", want)
+	}
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Server serves the metrics over HTTP on a unix socket or loopback address.
+type Server struct {
+	metrics *Metrics
+	address string
+	server  *http.Server
+	closed  chan struct{}
+}
+
+// ServeHTTP serves the metrics.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	if err := s.metrics.Write(w, time.Now()); err != nil {
+		log.WithError(err).Debug("Metrics Server could not write metrics")
+	}
+}
+
+// listen returns a listener for the server address.
+func (s *Server) listen() (net.Listener, error) {
+	path, ok := strings.CutPrefix(s.address, "unix:")
+	if !ok {
+		return net.Listen("tcp", s.address)
+	}
+
+	// create socket directory and remove stale socket of a previous agent
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode().Type() == os.ModeSocket {
+		_ = os.Remove(path)
+	}
+
+	// only allow the user to access the socket
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Start starts the metrics server.
+func (s *Server) Start() error {
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+	log.WithField("address", s.address).Info("Metrics Server listening")
+
+	go func() {
+		defer close(s.closed)
+		if err := s.server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Error("Metrics Server stopped")
+		}
+	}()
+	return nil
+}
+
+// Stop stops the metrics server.
+func (s *Server) Stop() {
+	_ = s.server.Close()
+	<-s.closed
+}
+
+// NewServer returns a new metrics server for metrics on address. Address is
+// either a unix socket path with prefix "unix:" or host:port.
+func NewServer(metrics *Metrics, address string) *Server {
+	s := &Server{
+		metrics: metrics,
+		address: address,
+		closed:  make(chan struct{}),
+	}
+	s.server = &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
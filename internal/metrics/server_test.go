@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// get gets url with client c and returns the status code and body.
+func get(t *testing.T, c *http.Client, url string) (int, string) {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(b)
+}
+
+// TestServerTCP tests Server on a loopback address.
+func TestServerTCP(t *testing.T) {
+	m := NewMetrics(nil)
+	m.SetLoggedIn(true)
+
+	// get free port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	_ = l.Close()
+
+	s := NewServer(m, address)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	code, body := get(t, http.DefaultClient, "http://"+address+"/metrics")
+	if code != http.StatusOK {
+		t.Errorf("got %d, want %d", code, http.StatusOK)
+	}
+	if !strings.Contains(body, "fw_id_agent_logged_in{") {
+		t.Errorf("unexpected metrics: %s", body)
+	}
+
+	code, _ = get(t, http.DefaultClient, "http://"+address+"/other")
+	if code != http.StatusNotFound {
+		t.Errorf("got %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+// TestServerUnix tests Server on a unix socket.
+func TestServerUnix(t *testing.T) {
+	m := NewMetrics(nil)
+	path := filepath.Join(t.TempDir(), "sub", "metrics.sock")
+
+	// create stale socket
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = l.Close()
+
+	s := NewServer(m, "unix:"+path)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// check socket permissions
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("got %v, want %v", fi.Mode().Perm(), os.FileMode(0600))
+	}
+
+	c := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	code, body := get(t, c, "http://localhost/metrics")
+	if code != http.StatusOK {
+		t.Errorf("got %d, want %d", code, http.StatusOK)
+	}
+	if !strings.Contains(body, "fw_id_agent_login_attempts_total{") {
+		t.Errorf("unexpected metrics: %s", body)
+	}
+
+	// stop should remove the socket
+	s.Stop()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket should be removed: %v", err)
+	}
+}
+
+// TestServerStartError tests Start of Server with an invalid address.
+func TestServerStartError(t *testing.T) {
+	s := NewServer(NewMetrics(nil), "invalid address")
+	if err := s.Start(); err == nil {
+		t.Error("start should fail with invalid address")
+	}
+}
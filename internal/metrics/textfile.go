@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// textfileInterval is the interval for writing the metrics to the textfile.
+var textfileInterval = 15 * time.Second
+
+// Textfile periodically writes the metrics to a file in a node_exporter
+// textfile collector directory.
+type Textfile struct {
+	metrics *Metrics
+	path    string
+	done    chan struct{}
+	closed  chan struct{}
+}
+
+// write writes the metrics to the textfile. It writes to a temporary file
+// first and renames it, so node_exporter never reads a partial file.
+func (t *Textfile) write() error {
+	f, err := os.CreateTemp(filepath.Dir(t.path), ".fw-id-agent-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+
+	if err := t.metrics.Write(f, time.Now()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), t.path)
+}
+
+// writeLog writes the metrics to the textfile and logs errors.
+func (t *Textfile) writeLog() {
+	if err := t.write(); err != nil {
+		log.WithError(err).WithField("path", t.path).
+			Error("Metrics Textfile could not write metrics")
+	}
+}
+
+// start starts the textfile writer.
+func (t *Textfile) start() {
+	defer close(t.closed)
+
+	t.writeLog()
+	ticker := time.NewTicker(textfileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.writeLog()
+		case <-t.done:
+			// write final metrics, e.g., after logout
+			t.writeLog()
+			return
+		}
+	}
+}
+
+// Start starts the textfile writer.
+func (t *Textfile) Start() {
+	go t.start()
+}
+
+// Stop stops the textfile writer.
+func (t *Textfile) Stop() {
+	close(t.done)
+	<-t.closed
+}
+
+// NewTextfile returns a new textfile writer for metrics in the textfile
+// collector directory dir. The file name contains the user ID, so multiple
+// users on the same host do not overwrite each other's metrics.
+func NewTextfile(metrics *Metrics, dir string) *Textfile {
+	return &Textfile{
+		metrics: metrics,
+		path:    filepath.Join(dir, fmt.Sprintf("fw-id-agent-%d.prom", os.Getuid())),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
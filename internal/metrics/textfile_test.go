@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTextfileStartStop tests Start and Stop of Textfile.
+func TestTextfileStartStop(t *testing.T) {
+	defer func(interval time.Duration) { textfileInterval = interval }(textfileInterval)
+	textfileInterval = time.Millisecond
+
+	m := NewMetrics(nil)
+	dir := t.TempDir()
+	tf := NewTextfile(m, dir)
+	tf.Start()
+	time.Sleep(10 * time.Millisecond)
+
+	// final write on stop should contain the latest state
+	m.SetTrustedNetwork(true)
+	tf.Stop()
+
+	b, err := os.ReadFile(tf.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "fw_id_agent_trusted_network{uid=") ||
+		!strings.Contains(string(b), "} 1\n") {
+		t.Errorf("unexpected metrics: %s", b)
+	}
+
+	// there should be no temporary files left
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d files, want 1", len(entries))
+	}
+}
+
+// TestTextfileWriteError tests write of Textfile with an invalid directory.
+func TestTextfileWriteError(t *testing.T) {
+	tf := NewTextfile(NewMetrics(nil), "/does/not/exist")
+	if err := tf.write(); err == nil {
+		t.Error("write should fail with invalid directory")
+	}
+}
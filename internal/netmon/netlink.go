@@ -0,0 +1,86 @@
+package netmon
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// netlinkRouteWatcher watches netlink for address and route changes.
+type netlinkRouteWatcher struct {
+	addrs   chan netlink.AddrUpdate
+	routes  chan netlink.RouteUpdate
+	changes chan struct{}
+	done    chan struct{}
+	closed  chan struct{}
+}
+
+// start starts the netlink watcher.
+func (w *netlinkRouteWatcher) start() {
+	defer close(w.closed)
+
+	for {
+		select {
+		case u, ok := <-w.addrs:
+			if !ok {
+				log.Error("Network Monitor got unexpected close of address updates")
+				return
+			}
+			log.WithField("address", u.LinkAddress.String()).
+				Debug("Network Monitor got netlink address update")
+			notifyChange(w.changes)
+
+		case u, ok := <-w.routes:
+			if !ok {
+				log.Error("Network Monitor got unexpected close of route updates")
+				return
+			}
+			log.WithField("route", u.Route.String()).
+				Debug("Network Monitor got netlink route update")
+			notifyChange(w.changes)
+
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// netlinkAddrSubscribe and netlinkRouteSubscribe are netlink.AddrSubscribe
+// and netlink.RouteSubscribe for testing.
+var (
+	netlinkAddrSubscribe  = netlink.AddrSubscribe
+	netlinkRouteSubscribe = netlink.RouteSubscribe
+)
+
+// Start starts the netlink watcher.
+func (w *netlinkRouteWatcher) Start() error {
+	// subscribe to address and route updates, subscriptions are closed
+	// when done is closed
+	if err := netlinkAddrSubscribe(w.addrs, w.done); err != nil {
+		return err
+	}
+	if err := netlinkRouteSubscribe(w.routes, w.done); err != nil {
+		close(w.done)
+		return err
+	}
+
+	go w.start()
+	return nil
+}
+
+// Stop stops the netlink watcher.
+func (w *netlinkRouteWatcher) Stop() {
+	close(w.done)
+	<-w.closed
+}
+
+// newNetlinkRouteWatcher returns a new netlink watcher that signals network
+// changes over changes.
+func newNetlinkRouteWatcher(changes chan struct{}) *netlinkRouteWatcher {
+	return &netlinkRouteWatcher{
+		addrs:   make(chan netlink.AddrUpdate),
+		routes:  make(chan netlink.RouteUpdate),
+		changes: changes,
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
@@ -0,0 +1,96 @@
+package netmon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+// TestNetlinkRouteWatcherStart tests start of netlinkRouteWatcher.
+func TestNetlinkRouteWatcherStart(t *testing.T) {
+	changes := make(chan struct{}, 1)
+
+	// test updates
+	w := newNetlinkRouteWatcher(changes)
+	go w.start()
+	w.addrs <- netlink.AddrUpdate{}
+	<-changes
+	w.routes <- netlink.RouteUpdate{}
+	<-changes
+	w.Stop()
+
+	// unexpected close of address updates should result in stop
+	w = newNetlinkRouteWatcher(changes)
+	go w.start()
+	close(w.addrs)
+	<-w.closed
+
+	// unexpected close of route updates should result in stop
+	w = newNetlinkRouteWatcher(changes)
+	go w.start()
+	close(w.routes)
+	<-w.closed
+}
+
+// TestNetlinkRouteWatcherStartStop tests Start and Stop of
+// netlinkRouteWatcher.
+func TestNetlinkRouteWatcherStartStop(t *testing.T) {
+	oldAddrSubscribe := netlinkAddrSubscribe
+	oldRouteSubscribe := netlinkRouteSubscribe
+	defer func() {
+		netlinkAddrSubscribe = oldAddrSubscribe
+		netlinkRouteSubscribe = oldRouteSubscribe
+	}()
+	netlinkAddrSubscribe = func(chan<- netlink.AddrUpdate, <-chan struct{}) error {
+		return nil
+	}
+
+	// test errors
+	netlinkRouteSubscribe = func(chan<- netlink.RouteUpdate, <-chan struct{}) error {
+		return errors.New("test error")
+	}
+	w := newNetlinkRouteWatcher(nil)
+	if err := w.Start(); err == nil {
+		t.Error("start should fail with route subscribe error")
+	}
+	select {
+	case <-w.done:
+	default:
+		t.Error("address subscription should be closed")
+	}
+	netlinkAddrSubscribe = func(chan<- netlink.AddrUpdate, <-chan struct{}) error {
+		return errors.New("test error")
+	}
+	if err := newNetlinkRouteWatcher(nil).Start(); err == nil {
+		t.Error("start should fail with address subscribe error")
+	}
+
+	// test without errors
+	netlinkAddrSubscribe = func(chan<- netlink.AddrUpdate, <-chan struct{}) error {
+		return nil
+	}
+	netlinkRouteSubscribe = func(chan<- netlink.RouteUpdate, <-chan struct{}) error {
+		return nil
+	}
+	w = newNetlinkRouteWatcher(nil)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	w.Stop()
+}
+
+// TestNewNetlinkRouteWatcher tests newNetlinkRouteWatcher.
+func TestNewNetlinkRouteWatcher(t *testing.T) {
+	changes := make(chan struct{})
+	w := newNetlinkRouteWatcher(changes)
+	if w == nil ||
+		w.addrs == nil ||
+		w.routes == nil ||
+		w.changes != changes ||
+		w.done == nil ||
+		w.closed == nil {
+
+		t.Errorf("invalid netlink watcher: %v", w)
+	}
+}
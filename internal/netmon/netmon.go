@@ -0,0 +1,161 @@
+// Package netmon contains the network monitor.
+package netmon
+
+import (
+	"net"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// settleTime is the time the network monitor waits for more changes before
+// sending an update, so bursts of changes result in a single update.
+var settleTime = 500 * time.Millisecond
+
+// Update is a network monitor update.
+type Update struct {
+	// Addresses are the local IP addresses after the network change.
+	Addresses []string
+}
+
+// watcher watches a source of network changes and signals them over the
+// changes channel.
+type watcher interface {
+	Start() error
+	Stop()
+}
+
+// notifyChange signals a network change over changes without blocking.
+// Changes that are not handled yet are merged.
+func notifyChange(changes chan struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
+
+// interfaceAddrs is net.InterfaceAddrs for testing.
+var interfaceAddrs = net.InterfaceAddrs
+
+// getAddresses returns the sorted global unicast IP addresses of the local
+// network interfaces.
+func getAddresses() []string {
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		log.WithError(err).Error("Network Monitor could not get interface addresses")
+		return nil
+	}
+	addresses := []string{}
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok || !n.IP.IsGlobalUnicast() {
+			continue
+		}
+		addresses = append(addresses, n.IP.String())
+	}
+	slices.Sort(addresses)
+	return addresses
+}
+
+// NetMon is a network monitor.
+type NetMon struct {
+	watcher watcher
+	changes chan struct{}
+	updates chan *Update
+	done    chan struct{}
+	closed  chan struct{}
+}
+
+// sendUpdate sends an update over the updates channel.
+func (n *NetMon) sendUpdate(update *Update) {
+	// send an update or abort if we are shutting down
+	select {
+	case n.updates <- update:
+	case <-n.done:
+	}
+}
+
+// handleChange handles a settled network change.
+func (n *NetMon) handleChange() {
+	addresses := getAddresses()
+	log.WithField("addresses", addresses).
+		Debug("Network Monitor got network change")
+
+	n.sendUpdate(&Update{
+		Addresses: addresses,
+	})
+}
+
+// start starts the network monitor.
+func (n *NetMon) start() {
+	defer close(n.closed)
+	defer close(n.updates)
+	defer n.watcher.Stop()
+
+	// wait for the network to settle after changes
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-n.changes:
+			if settle == nil {
+				settle = time.After(settleTime)
+			}
+		case <-settle:
+			settle = nil
+			n.handleChange()
+		case <-n.done:
+			return
+		}
+	}
+}
+
+// newNMWatcher and newNetlinkWatcher return the NetworkManager and netlink
+// watchers, for testing.
+var (
+	newNMWatcher = func(changes chan struct{}) watcher {
+		return newNetworkManagerWatcher(changes)
+	}
+	newNetlinkWatcher = func(changes chan struct{}) watcher {
+		return newNetlinkRouteWatcher(changes)
+	}
+)
+
+// Start starts the network monitor. It uses NetworkManager if it is available
+// and netlink otherwise.
+func (n *NetMon) Start() error {
+	w := newNMWatcher(n.changes)
+	if err := w.Start(); err != nil {
+		log.WithError(err).Info("Network Monitor could not use NetworkManager, using netlink")
+		w = newNetlinkWatcher(n.changes)
+		if err := w.Start(); err != nil {
+			log.WithError(err).Error("Network Monitor could not use netlink")
+			return err
+		}
+	}
+	n.watcher = w
+
+	go n.start()
+	return nil
+}
+
+// Stop stops the network monitor.
+func (n *NetMon) Stop() {
+	close(n.done)
+	<-n.closed
+}
+
+// Updates returns the channel for network updates.
+func (n *NetMon) Updates() chan *Update {
+	return n.updates
+}
+
+// NewNetMon returns a new network monitor.
+func NewNetMon() *NetMon {
+	return &NetMon{
+		changes: make(chan struct{}, 1),
+		updates: make(chan *Update),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
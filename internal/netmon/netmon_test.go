@@ -0,0 +1,179 @@
+package netmon
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testWatcher is a watcher for testing.
+type testWatcher struct {
+	err     error
+	stopped bool
+}
+
+func (t *testWatcher) Start() error { return t.err }
+func (t *testWatcher) Stop()        { t.stopped = true }
+
+// testAddrs returns interface addresses for testing.
+func testAddrs(addrs ...string) func() ([]net.Addr, error) {
+	return func() ([]net.Addr, error) {
+		a := []net.Addr{}
+		for _, s := range addrs {
+			ip, n, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, &net.IPNet{IP: ip, Mask: n.Mask})
+		}
+		return a, nil
+	}
+}
+
+// TestGetAddresses tests getAddresses.
+func TestGetAddresses(t *testing.T) {
+	oldInterfaceAddrs := interfaceAddrs
+	defer func() { interfaceAddrs = oldInterfaceAddrs }()
+
+	// test error
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return nil, errors.New("test error")
+	}
+	if got := getAddresses(); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+
+	// test addresses
+	interfaceAddrs = testAddrs(
+		"127.0.0.1/8",
+		"::1/128",
+		"fe80::1/64",
+		"192.168.1.10/24",
+		"10.0.0.2/8",
+		"2001:db8::2/64",
+	)
+	want := []string{"10.0.0.2", "192.168.1.10", "2001:db8::2"}
+	if got := getAddresses(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestNotifyChange tests notifyChange.
+func TestNotifyChange(t *testing.T) {
+	changes := make(chan struct{}, 1)
+
+	// multiple changes should not block and be merged
+	notifyChange(changes)
+	notifyChange(changes)
+	<-changes
+	select {
+	case <-changes:
+		t.Error("changes should be merged")
+	default:
+	}
+}
+
+// TestNetMonStart tests start of NetMon.
+func TestNetMonStart(t *testing.T) {
+	oldInterfaceAddrs := interfaceAddrs
+	oldSettleTime := settleTime
+	defer func() {
+		interfaceAddrs = oldInterfaceAddrs
+		settleTime = oldSettleTime
+	}()
+	settleTime = time.Millisecond
+
+	n := NewNetMon()
+	w := &testWatcher{}
+	n.watcher = w
+	go n.start()
+
+	// change
+	interfaceAddrs = testAddrs("192.168.1.10/24")
+	notifyChange(n.changes)
+	want := &Update{Addresses: []string{"192.168.1.10"}}
+	if got := <-n.Updates(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// another change
+	interfaceAddrs = testAddrs("10.0.0.2/8")
+	notifyChange(n.changes)
+	want = &Update{Addresses: []string{"10.0.0.2"}}
+	if got := <-n.Updates(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// stop
+	n.Stop()
+	if !w.stopped {
+		t.Error("watcher should be stopped")
+	}
+}
+
+// TestNetMonStartStop tests Start and Stop of NetMon.
+func TestNetMonStartStop(t *testing.T) {
+	oldNMWatcher := newNMWatcher
+	oldNetlinkWatcher := newNetlinkWatcher
+	defer func() {
+		newNMWatcher = oldNMWatcher
+		newNetlinkWatcher = oldNetlinkWatcher
+	}()
+
+	// test with NetworkManager
+	nm := &testWatcher{}
+	nl := &testWatcher{}
+	newNMWatcher = func(chan struct{}) watcher { return nm }
+	newNetlinkWatcher = func(chan struct{}) watcher { return nl }
+
+	n := NewNetMon()
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if n.watcher != nm {
+		t.Errorf("got %v, want %v", n.watcher, nm)
+	}
+	n.Stop()
+
+	// test netlink fallback
+	nm = &testWatcher{err: errors.New("test error")}
+	n = NewNetMon()
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if n.watcher != nl {
+		t.Errorf("got %v, want %v", n.watcher, nl)
+	}
+	n.Stop()
+
+	// test errors
+	nl = &testWatcher{err: errors.New("test error")}
+	if err := NewNetMon().Start(); err == nil {
+		t.Error("start should fail")
+	}
+}
+
+// TestNetMonUpdates tests Updates of NetMon.
+func TestNetMonUpdates(t *testing.T) {
+	n := NewNetMon()
+	got := n.Updates()
+	want := n.updates
+	if got != want {
+		t.Errorf("got %p, want %p", got, want)
+	}
+}
+
+// TestNewNetMon tests NewNetMon.
+func TestNewNetMon(t *testing.T) {
+	n := NewNetMon()
+	if n == nil ||
+		n.changes == nil ||
+		n.updates == nil ||
+		n.done == nil ||
+		n.closed == nil {
+
+		t.Errorf("invalid network monitor: %v", n)
+	}
+}
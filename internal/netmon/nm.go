@@ -0,0 +1,169 @@
+package netmon
+
+import (
+	"errors"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// NetworkManager object path, destination, interface, signals.
+	nmPath         = "/org/freedesktop/NetworkManager"
+	nmDest         = "org.freedesktop.NetworkManager"
+	nmIface        = nmDest
+	nmStateChanged = nmIface + ".StateChanged"
+
+	// properties interface and signal.
+	propertiesIface   = "org.freedesktop.DBus.Properties"
+	propertiesChanged = propertiesIface + ".PropertiesChanged"
+)
+
+// nmProperties are the NetworkManager properties that indicate a network
+// change, e.g., a new default route, Wi-Fi network or VPN connection.
+var nmProperties = []string{
+	"State",
+	"PrimaryConnection",
+	"ActiveConnections",
+	"Connectivity",
+}
+
+// networkManagerWatcher watches NetworkManager for network changes.
+type networkManagerWatcher struct {
+	conn    *dbus.Conn
+	signals chan *dbus.Signal
+	changes chan struct{}
+	done    chan struct{}
+	closed  chan struct{}
+}
+
+// handleSignal handles signal.
+func (w *networkManagerWatcher) handleSignal(signal *dbus.Signal) {
+	log.WithField("signal", signal).Debug("Network Monitor got NetworkManager signal")
+	if signal.Path != nmPath {
+		return
+	}
+
+	switch signal.Name {
+	case nmStateChanged:
+		notifyChange(w.changes)
+
+	case propertiesChanged:
+		if len(signal.Body) < 2 {
+			return
+		}
+		if i, ok := signal.Body[0].(string); !ok || i != nmIface {
+			return
+		}
+		changed, ok := signal.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			return
+		}
+		for _, p := range nmProperties {
+			if _, ok := changed[p]; ok {
+				notifyChange(w.changes)
+				return
+			}
+		}
+	}
+}
+
+// start starts the NetworkManager watcher.
+func (w *networkManagerWatcher) start() {
+	defer close(w.closed)
+	defer func() {
+		_ = w.conn.Close()
+	}()
+
+	for {
+		select {
+		case sig, ok := <-w.signals:
+			if !ok {
+				log.Error("Network Monitor got unexpected close of signals channel")
+				return
+			}
+			w.handleSignal(sig)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// dbusConnectSystemBus is dbus.ConnectSystemBus for testing.
+var dbusConnectSystemBus = dbus.ConnectSystemBus
+
+// nameHasOwner returns whether name is owned on conn, for testing.
+var nameHasOwner = func(conn *dbus.Conn, name string) (bool, error) {
+	owned := false
+	err := conn.BusObject().
+		Call("org.freedesktop.DBus.NameHasOwner", 0, name).
+		Store(&owned)
+	return owned, err
+}
+
+// connAddMatchSignal is conn.AddMatchSignal for testing.
+var connAddMatchSignal = func(conn *dbus.Conn, options ...dbus.MatchOption) error {
+	return conn.AddMatchSignal(options...)
+}
+
+// Start starts the NetworkManager watcher.
+func (w *networkManagerWatcher) Start() error {
+	// connect to system bus
+	conn, err := dbusConnectSystemBus()
+	if err != nil {
+		return err
+	}
+
+	// make sure NetworkManager is running
+	owned, err := nameHasOwner(conn, nmDest)
+	if err == nil && !owned {
+		err = errors.New("NetworkManager is not running")
+	}
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	// subscribe to NetworkManager signals
+	for _, options := range [][]dbus.MatchOption{
+		{
+			dbus.WithMatchObjectPath(nmPath),
+			dbus.WithMatchInterface(nmIface),
+			dbus.WithMatchMember("StateChanged"),
+		},
+		{
+			dbus.WithMatchObjectPath(nmPath),
+			dbus.WithMatchInterface(propertiesIface),
+			dbus.WithMatchMember("PropertiesChanged"),
+		},
+	} {
+		if err := connAddMatchSignal(conn, options...); err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+
+	// set channel for signals
+	w.conn = conn
+	conn.Signal(w.signals)
+
+	go w.start()
+	return nil
+}
+
+// Stop stops the NetworkManager watcher.
+func (w *networkManagerWatcher) Stop() {
+	close(w.done)
+	<-w.closed
+}
+
+// newNetworkManagerWatcher returns a new NetworkManager watcher that signals
+// network changes over changes.
+func newNetworkManagerWatcher(changes chan struct{}) *networkManagerWatcher {
+	return &networkManagerWatcher{
+		signals: make(chan *dbus.Signal, 10),
+		changes: changes,
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
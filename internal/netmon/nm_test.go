@@ -0,0 +1,161 @@
+package netmon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// testRWC is a ReadWriteCloser for testing D-Bus connections.
+type testRWC struct{}
+
+func (t *testRWC) Read([]byte) (int, error)  { return 0, nil }
+func (t *testRWC) Write([]byte) (int, error) { return 0, nil }
+func (t *testRWC) Close() error              { return nil }
+
+// testNMSignal returns a properties changed signal for testing.
+func testNMSignal(changed map[string]dbus.Variant) *dbus.Signal {
+	return &dbus.Signal{
+		Path: nmPath,
+		Name: propertiesChanged,
+		Body: []any{nmIface, changed, []string{}},
+	}
+}
+
+// TestNetworkManagerWatcherHandleSignal tests handleSignal of
+// networkManagerWatcher.
+func TestNetworkManagerWatcherHandleSignal(t *testing.T) {
+	changes := make(chan struct{}, 1)
+	w := newNetworkManagerWatcher(changes)
+
+	// test signals without change
+	for _, signal := range []*dbus.Signal{
+		{},
+		{Path: "/other", Name: nmStateChanged},
+		{Path: nmPath, Name: "other"},
+		{Path: nmPath, Name: propertiesChanged},
+		{Path: nmPath, Name: propertiesChanged, Body: []any{"other", nil}},
+		{Path: nmPath, Name: propertiesChanged, Body: []any{nmIface, nil}},
+		testNMSignal(map[string]dbus.Variant{"Other": dbus.MakeVariant(true)}),
+	} {
+		w.handleSignal(signal)
+		select {
+		case <-changes:
+			t.Errorf("signal %v should not result in change", signal)
+		default:
+		}
+	}
+
+	// test signals with change
+	for _, signal := range []*dbus.Signal{
+		{Path: nmPath, Name: nmStateChanged, Body: []any{uint32(70)}},
+		testNMSignal(map[string]dbus.Variant{"State": dbus.MakeVariant(uint32(70))}),
+		testNMSignal(map[string]dbus.Variant{"PrimaryConnection": dbus.MakeVariant(dbus.ObjectPath("/"))}),
+		testNMSignal(map[string]dbus.Variant{"ActiveConnections": dbus.MakeVariant([]dbus.ObjectPath{})}),
+		testNMSignal(map[string]dbus.Variant{"Connectivity": dbus.MakeVariant(uint32(4))}),
+	} {
+		w.handleSignal(signal)
+		select {
+		case <-changes:
+		default:
+			t.Errorf("signal %v should result in change", signal)
+		}
+	}
+}
+
+// TestNetworkManagerWatcherStartSignalsChan tests start of
+// networkManagerWatcher, signals channel.
+func TestNetworkManagerWatcherStartSignalsChan(t *testing.T) {
+	changes := make(chan struct{}, 1)
+	w := newNetworkManagerWatcher(changes)
+	conn, err := dbus.NewConn(&testRWC{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.conn = conn
+	go w.start()
+
+	// send signal
+	w.signals <- &dbus.Signal{Path: nmPath, Name: nmStateChanged}
+	<-changes
+
+	// unexpected close of signals channel should result in stop
+	close(w.signals)
+	<-w.closed
+}
+
+// TestNetworkManagerWatcherStartStop tests Start and Stop of
+// networkManagerWatcher.
+func TestNetworkManagerWatcherStartStop(t *testing.T) {
+	oldConnectSystemBus := dbusConnectSystemBus
+	oldNameHasOwner := nameHasOwner
+	oldAddMatchSignal := connAddMatchSignal
+	defer func() {
+		dbusConnectSystemBus = oldConnectSystemBus
+		nameHasOwner = oldNameHasOwner
+		connAddMatchSignal = oldAddMatchSignal
+	}()
+	dbusConnectSystemBus = func(...dbus.ConnOption) (*dbus.Conn, error) {
+		return dbus.NewConn(&testRWC{})
+	}
+	nameHasOwner = func(*dbus.Conn, string) (bool, error) {
+		return true, nil
+	}
+
+	// test errors
+	connAddMatchSignal = func(*dbus.Conn, ...dbus.MatchOption) error {
+		return errors.New("test error")
+	}
+	if err := newNetworkManagerWatcher(nil).Start(); err == nil {
+		t.Error("start should fail with match signal error")
+	}
+	nameHasOwner = func(*dbus.Conn, string) (bool, error) {
+		return false, nil
+	}
+	if err := newNetworkManagerWatcher(nil).Start(); err == nil {
+		t.Error("start should fail without NetworkManager")
+	}
+	nameHasOwner = func(*dbus.Conn, string) (bool, error) {
+		return false, errors.New("test error")
+	}
+	if err := newNetworkManagerWatcher(nil).Start(); err == nil {
+		t.Error("start should fail with name has owner error")
+	}
+	dbusConnectSystemBus = func(...dbus.ConnOption) (*dbus.Conn, error) {
+		return nil, errors.New("test error")
+	}
+	if err := newNetworkManagerWatcher(nil).Start(); err == nil {
+		t.Error("start should fail with systembus error")
+	}
+
+	// test without errors
+	dbusConnectSystemBus = func(...dbus.ConnOption) (*dbus.Conn, error) {
+		return dbus.NewConn(&testRWC{})
+	}
+	nameHasOwner = func(*dbus.Conn, string) (bool, error) {
+		return true, nil
+	}
+	connAddMatchSignal = func(*dbus.Conn, ...dbus.MatchOption) error {
+		return nil
+	}
+	w := newNetworkManagerWatcher(make(chan struct{}, 1))
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	w.Stop()
+}
+
+// TestNewNetworkManagerWatcher tests newNetworkManagerWatcher.
+func TestNewNetworkManagerWatcher(t *testing.T) {
+	changes := make(chan struct{})
+	w := newNetworkManagerWatcher(changes)
+	if w == nil ||
+		w.signals == nil ||
+		w.changes != changes ||
+		w.done == nil ||
+		w.closed == nil {
+
+		t.Errorf("invalid NetworkManager watcher: %v", w)
+	}
+}
@@ -3,6 +3,8 @@ package notify
 
 import (
 	"math/rand"
+	"sync"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 	log "github.com/sirupsen/logrus"
@@ -12,19 +14,117 @@ const (
 	iface  = "org.freedesktop.Notifications"
 	path   = "/org/freedesktop/Notifications"
 	method = "org.freedesktop.Notifications.Notify"
+
+	getCapabilities = iface + ".GetCapabilities"
+	inhibited       = iface + ".Inhibited"
+
+	// signals
+	actionInvoked      = iface + ".ActionInvoked"
+	notificationClosed = iface + ".NotificationClosed"
+)
+
+// Capabilities of the notification server.
+const (
+	capActions     = "actions"
+	capInhibitions = "inhibitions"
 )
 
 // Conn is D-Bus connection.
 type Conn interface {
 	Object(string, dbus.ObjectPath) dbus.BusObject
+	AddMatchSignal(...dbus.MatchOption) error
+	Signal(chan<- *dbus.Signal)
 	Close() error
 }
 
+// Action is an action of a notification the user can invoke, e.g., with a
+// button. Key identifies the action, Label is shown to the user.
+type Action struct {
+	Key   string
+	Label string
+}
+
+// Urgency is the urgency level of a notification.
+type Urgency int
+
+// Urgency levels.
+const (
+	UrgencyNormal Urgency = iota
+	UrgencyLow
+	UrgencyCritical
+)
+
+// hint returns the urgency as value of the "urgency" hint.
+func (u Urgency) hint() byte {
+	switch u {
+	case UrgencyLow:
+		return 0
+	case UrgencyCritical:
+		return 2
+	}
+	return 1
+}
+
+// Notification is a desktop notification.
+type Notification struct {
+	Title    string
+	Message  string
+	Icon     string
+	Urgency  Urgency
+	Category string
+
+	// Timeout is the time after which the notification expires. If it
+	// is 0, the server's default is used, if it is negative, the
+	// notification never expires.
+	Timeout time.Duration
+
+	// Actions the user can invoke, their keys are sent over the Actions
+	// channel.
+	Actions []Action
+
+	// Group is the rate limit group of the notification, see
+	// SetRateLimit. Notifications without group are not rate limited.
+	Group string
+}
+
+// timeout returns the expire timeout of the notification in milliseconds.
+func (n *Notification) timeout() int32 {
+	switch {
+	case n.Timeout == 0:
+		return -1
+	case n.Timeout < 0:
+		return 0
+	}
+	return int32(n.Timeout / time.Millisecond)
+}
+
+// group is the rate limit state of a notification group.
+type group struct {
+	last    time.Time
+	shown   *Notification
+	pending *Notification
+	timer   *time.Timer
+}
+
 // Notifier creates desktop notifications.
 type Notifier struct {
-	conn Conn
-	// used to replace notifications with newer ones
+	conn    Conn
+	signals chan *dbus.Signal
+	actions chan string
+	done    chan struct{}
+	closed  chan struct{}
+
+	// mutex protects the following fields: notificationID is used to
+	// replace notifications with newer ones, active specifies whether the
+	// current notification is shown and its actions can be invoked,
+	// capabilities of the notification server, rate limit and state of
+	// the rate limit groups
+	mutex          sync.Mutex
 	notificationID uint32
+	active         bool
+	capabilities   map[string]bool
+	rateLimit      time.Duration
+	groups         map[string]*group
 }
 
 // dbusSessionConn returns a D-Bus connection.
@@ -32,32 +132,264 @@ var dbusSessionConn = func() (Conn, error) {
 	return dbus.ConnectSessionBus()
 }
 
+// handleSignal handles the D-Bus signal s of the notification server.
+func (n *Notifier) handleSignal(s *dbus.Signal) {
+	if len(s.Body) != 2 {
+		return
+	}
+	id, ok := s.Body[0].(uint32)
+	if !ok {
+		return
+	}
+
+	n.mutex.Lock()
+	current := n.active && id == n.notificationID
+	if current && s.Name == notificationClosed {
+		n.active = false
+	}
+	n.mutex.Unlock()
+
+	if !current || s.Name != actionInvoked {
+		return
+	}
+	key, ok := s.Body[1].(string)
+	if !ok {
+		return
+	}
+	log.WithField("action", key).Debug("Notifier got invoked notification action")
+	select {
+	case n.actions <- key:
+	case <-n.done:
+	}
+}
+
+// start handles the D-Bus signals of the notification server.
+func (n *Notifier) start() {
+	defer close(n.closed)
+	for {
+		select {
+		case s, ok := <-n.signals:
+			if !ok {
+				return
+			}
+			n.handleSignal(s)
+		case <-n.done:
+			return
+		}
+	}
+}
+
 // NewNotifier returns a new Notifier.
 func NewNotifier() (*Notifier, error) {
 	conn, err := dbusSessionConn()
 	if err != nil {
 		return nil, err
 	}
-	n := &Notifier{conn: conn, notificationID: rand.Uint32()}
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchInterface(iface),
+		dbus.WithMatchObjectPath(path),
+	); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	n := &Notifier{
+		conn:           conn,
+		signals:        make(chan *dbus.Signal, 10),
+		actions:        make(chan string),
+		done:           make(chan struct{}),
+		closed:         make(chan struct{}),
+		notificationID: rand.Uint32(),
+		groups:         make(map[string]*group),
+	}
+	conn.Signal(n.signals)
+	go n.start()
 	return n, nil
 }
 
-// Notify sends a notification to the user.
-func (n *Notifier) Notify(title, message string) {
-	if n == nil {
+// getCapabilities returns the capabilities of the notification server and
+// whether they are known. They are cached after the first successful query.
+func (n *Notifier) getCapabilities() (map[string]bool, bool) {
+	if n.capabilities != nil {
+		return n.capabilities, true
+	}
+	obj := n.conn.Object(iface, dbus.ObjectPath(path))
+	caps := []string{}
+	if err := obj.Call(getCapabilities, 0).Store(&caps); err != nil {
+		log.WithError(err).Debug("Notifier could not get capabilities of notification server")
+		return nil, false
+	}
+	n.capabilities = make(map[string]bool)
+	for _, c := range caps {
+		n.capabilities[c] = true
+	}
+	return n.capabilities, true
+}
+
+// isInhibited returns whether notifications are inhibited by the notification
+// server, e.g., in do not disturb mode.
+func (n *Notifier) isInhibited(caps map[string]bool) bool {
+	if !caps[capInhibitions] {
+		return false
+	}
+	obj := n.conn.Object(iface, dbus.ObjectPath(path))
+	v, err := obj.GetProperty(inhibited)
+	if err != nil {
+		log.WithError(err).Debug("Notifier could not get inhibited state of notification server")
+		return false
+	}
+	i, ok := v.Value().(bool)
+	return ok && i
+}
+
+// send sends the notification nt to the notification server, the mutex must
+// be held.
+func (n *Notifier) send(nt *Notification) {
+	caps, known := n.getCapabilities()
+	if known && nt.Urgency != UrgencyCritical && n.isInhibited(caps) {
+		log.WithField("title", nt.Title).Debug("Notifier skipping notification, notifications are inhibited")
 		return
 	}
+	acts := []string{}
+	if !known || caps[capActions] {
+		for _, a := range nt.Actions {
+			acts = append(acts, a.Key, a.Label)
+		}
+	}
+	hints := map[string]dbus.Variant{
+		"urgency": dbus.MakeVariant(nt.Urgency.hint()),
+	}
+	if nt.Category != "" {
+		hints["category"] = dbus.MakeVariant(nt.Category)
+	}
+
 	obj := n.conn.Object(iface, dbus.ObjectPath(path))
-	call := obj.Call(method, 0, "", n.notificationID, "", title, message, []string{}, map[string]dbus.Variant{}, int32(5))
+	call := obj.Call(method, 0, "", n.notificationID, nt.Icon, nt.Title, nt.Message, acts, hints, nt.timeout())
 	if call.Err != nil {
 		log.WithError(call.Err).Error("Agent notify error")
+		return
+	}
+
+	// the server returns the id of the new notification, it may differ
+	// from the id of the replaced notification
+	var id uint32
+	if err := call.Store(&id); err == nil && id != 0 {
+		n.notificationID = id
+	}
+	n.active = true
+}
+
+// flush sends the pending notification of the rate limit group with name
+// when the rate limit is over, unless it equals the last shown notification.
+func (n *Notifier) flush(name string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	select {
+	case <-n.done:
+		return
+	default:
+	}
+
+	g := n.groups[name]
+	g.timer = nil
+	p := g.pending
+	g.pending = nil
+	if p == nil || (g.shown != nil && p.Title == g.shown.Title && p.Message == g.shown.Message) {
+		log.WithField("group", name).Debug("Notifier dropping rate limited notification")
+		return
+	}
+	n.send(p)
+	g.last = time.Now()
+	g.shown = p
+}
+
+// Send sends the notification nt to the user. If nt has a group and a rate
+// limit is set, notifications of the group are shown at most once per rate
+// limit, the last notification in between is shown when the rate limit is
+// over. Critical notifications are never rate limited, they replace the
+// pending notification of their group.
+func (n *Notifier) Send(nt *Notification) {
+	if n == nil {
+		return
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if nt.Group == "" || n.rateLimit <= 0 {
+		n.send(nt)
+		return
+	}
+	if nt.Urgency == UrgencyCritical {
+		n.send(nt)
+		if g := n.groups[nt.Group]; g != nil {
+			g.pending = nil
+		}
+		return
+	}
+
+	g := n.groups[nt.Group]
+	if g == nil {
+		g = &group{}
+		n.groups[nt.Group] = g
+	}
+	if g.timer == nil && time.Since(g.last) >= n.rateLimit {
+		n.send(nt)
+		g.last = time.Now()
+		g.shown = nt
+		return
+	}
+	g.pending = nt
+	if g.timer == nil {
+		name := nt.Group
+		g.timer = time.AfterFunc(n.rateLimit-time.Since(g.last), func() {
+			n.flush(name)
+		})
 	}
 }
 
+// Notify sends a notification with title, message and the optional actions
+// to the user.
+func (n *Notifier) Notify(title, message string, actions ...Action) {
+	n.Send(&Notification{
+		Title:   title,
+		Message: message,
+		Actions: actions,
+	})
+}
+
+// SetRateLimit sets the rate limit of notification groups to d, 0 disables
+// it.
+func (n *Notifier) SetRateLimit(d time.Duration) {
+	if n == nil {
+		return
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.rateLimit = d
+}
+
+// Actions returns the channel for keys of actions the user invoked.
+func (n *Notifier) Actions() <-chan string {
+	if n == nil {
+		return nil
+	}
+	return n.actions
+}
+
 // Close closes the Notifier.
 func (n *Notifier) Close() {
 	if n == nil {
 		return
 	}
+	close(n.done)
+	n.mutex.Lock()
+	for _, g := range n.groups {
+		if g.timer != nil {
+			g.timer.Stop()
+		}
+	}
+	n.mutex.Unlock()
+	<-n.closed
 	_ = n.conn.Close()
 }
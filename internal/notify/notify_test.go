@@ -2,7 +2,10 @@ package notify
 
 import (
 	"errors"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 )
@@ -10,26 +13,69 @@ import (
 // testObj is a dummy D-Bus object for testing.
 type testObj struct {
 	dbus.Object
-	err error
+	conn *testConn
 }
 
-func (t *testObj) Call(_ string, _ dbus.Flags, _ ...interface{}) *dbus.Call {
-	return &dbus.Call{Err: t.err}
+func (t *testObj) Call(method string, _ dbus.Flags, args ...interface{}) *dbus.Call {
+	t.conn.mutex.Lock()
+	defer t.conn.mutex.Unlock()
+
+	if method == getCapabilities {
+		if t.conn.caps == nil {
+			return &dbus.Call{Err: errors.New("test error")}
+		}
+		return &dbus.Call{Body: []interface{}{t.conn.caps}}
+	}
+	t.conn.args = args
+	t.conn.sent++
+	if t.conn.err != nil {
+		return &dbus.Call{Err: t.conn.err}
+	}
+	return &dbus.Call{Body: []interface{}{t.conn.id}}
+}
+
+func (t *testObj) GetProperty(string) (dbus.Variant, error) {
+	return dbus.MakeVariant(t.conn.inhibited), nil
 }
 
 // testConn is a dummy D-Bus connection for testing.
 type testConn struct {
-	err error
+	err       error
+	matchErr  error
+	id        uint32
+	caps      []string
+	inhibited bool
+	signals   chan<- *dbus.Signal
+
+	mutex sync.Mutex
+	args  []interface{}
+	sent  int
 }
 
 func (t *testConn) Object(string, dbus.ObjectPath) dbus.BusObject {
-	return &testObj{err: t.err}
+	return &testObj{conn: t}
+}
+
+func (t *testConn) AddMatchSignal(...dbus.MatchOption) error {
+	return t.matchErr
+}
+
+func (t *testConn) Signal(ch chan<- *dbus.Signal) {
+	t.signals = ch
 }
 
 func (t *testConn) Close() error {
 	return nil
 }
 
+// getSent returns the number of sent notifications and the arguments of the
+// last one.
+func (t *testConn) getSent() (int, []interface{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.sent, t.args
+}
+
 // TestNewNotifier tests NewNotifier
 func TestNewNotifier(t *testing.T) {
 	// test with no error
@@ -40,6 +86,7 @@ func TestNewNotifier(t *testing.T) {
 	if err != nil || n == nil || n.conn == nil {
 		t.Errorf("error creating notifier")
 	}
+	n.Close()
 
 	// test with error
 	dbusSessionConn = func() (Conn, error) {
@@ -49,10 +96,19 @@ func TestNewNotifier(t *testing.T) {
 	if err == nil || n != nil {
 		t.Errorf("notifier should not be valid")
 	}
+
+	// test with match signal error
+	dbusSessionConn = func() (Conn, error) {
+		return &testConn{matchErr: errors.New("test error")}, nil
+	}
+	n, err = NewNotifier()
+	if err == nil || n != nil {
+		t.Errorf("notifier should not be valid")
+	}
 }
 
 // TestNotify tests Notify of Notifier.
-func TestNotifierNotify(_ *testing.T) {
+func TestNotifierNotify(t *testing.T) {
 	// test nil
 	var n *Notifier
 	n.Notify("test", "this is a test")
@@ -71,7 +127,219 @@ func TestNotifierNotify(_ *testing.T) {
 	}
 	n, _ = NewNotifier()
 	n.Notify("test", "this is a test")
+	if n.active {
+		t.Error("failed notification should not be active")
+	}
 	n.Close()
+
+	// test with actions and returned id
+	conn := &testConn{id: 1234}
+	dbusSessionConn = func() (Conn, error) {
+		return conn, nil
+	}
+	n, _ = NewNotifier()
+	replaces := n.notificationID
+	n.Notify("test", "this is a test",
+		Action{Key: "relogin", Label: "Re-login"},
+		Action{Key: "details", Label: "Details"})
+	n.Close()
+
+	if conn.args[1] != replaces {
+		t.Errorf("got %v, want %d", conn.args[1], replaces)
+	}
+	want := []string{"relogin", "Re-login", "details", "Details"}
+	if !reflect.DeepEqual(conn.args[5], want) {
+		t.Errorf("got %v, want %v", conn.args[5], want)
+	}
+	if n.notificationID != 1234 || !n.active {
+		t.Errorf("invalid notification: id %d, active %t", n.notificationID, n.active)
+	}
+}
+
+// TestNotificationTimeout tests timeout of Notification.
+func TestNotificationTimeout(t *testing.T) {
+	for timeout, want := range map[time.Duration]int32{
+		0:                -1,
+		-time.Second:     0,
+		10 * time.Second: 10000,
+	} {
+		n := &Notification{Timeout: timeout}
+		if got := n.timeout(); got != want {
+			t.Errorf("got %d, want %d", got, want)
+		}
+	}
+}
+
+// TestNotifierSend tests Send of Notifier.
+func TestNotifierSend(t *testing.T) {
+	// test nil
+	var n *Notifier
+	n.Send(&Notification{})
+
+	// test with hints, icon and timeout
+	conn := &testConn{caps: []string{"body", capActions}}
+	dbusSessionConn = func() (Conn, error) {
+		return conn, nil
+	}
+	n, _ = NewNotifier()
+	defer n.Close()
+	n.Send(&Notification{
+		Title:    "test",
+		Message:  "this is a test",
+		Icon:     "security-high",
+		Urgency:  UrgencyLow,
+		Category: "network.connected",
+		Timeout:  3 * time.Second,
+		Actions:  []Action{{Key: "details", Label: "Details"}},
+	})
+	_, args := conn.getSent()
+	want := []interface{}{
+		"", n.notificationID, "security-high", "test", "this is a test",
+		[]string{"details", "Details"},
+		map[string]dbus.Variant{
+			"urgency":  dbus.MakeVariant(byte(0)),
+			"category": dbus.MakeVariant("network.connected"),
+		},
+		int32(3000),
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("got %v, want %v", args, want)
+	}
+
+	// test server without actions capability
+	conn.caps = []string{"body"}
+	n.capabilities = nil
+	n.Send(&Notification{Title: "test", Actions: []Action{{Key: "details", Label: "Details"}}})
+	if _, args := conn.getSent(); !reflect.DeepEqual(args[5], []string{}) {
+		t.Errorf("got %v, want no actions", args[5])
+	}
+
+	// test inhibited server, only critical notifications are sent
+	conn.caps = []string{capInhibitions}
+	conn.inhibited = true
+	n.capabilities = nil
+	sent, _ := conn.getSent()
+	n.Send(&Notification{Title: "normal"})
+	n.Send(&Notification{Title: "critical", Urgency: UrgencyCritical})
+	got, args := conn.getSent()
+	if got != sent+1 || args[3] != "critical" {
+		t.Errorf("got %d sent, %v, want %d sent, critical", got, args[3], sent+1)
+	}
+}
+
+// TestNotifierRateLimit tests SetRateLimit and rate limiting of Notifier.
+func TestNotifierRateLimit(t *testing.T) {
+	// test nil
+	var n *Notifier
+	n.SetRateLimit(time.Second)
+
+	conn := &testConn{}
+	dbusSessionConn = func() (Conn, error) {
+		return conn, nil
+	}
+	n, _ = NewNotifier()
+	defer n.Close()
+	n.SetRateLimit(50 * time.Millisecond)
+
+	// wait until pending notifications are flushed
+	wait := func() {
+		time.Sleep(150 * time.Millisecond)
+	}
+
+	// test flapping back to the shown notification, only the first is
+	// shown
+	n.Send(&Notification{Title: "trusted", Group: "tnd"})
+	n.Send(&Notification{Title: "untrusted", Group: "tnd"})
+	n.Send(&Notification{Title: "trusted", Group: "tnd"})
+	wait()
+	if sent, _ := conn.getSent(); sent != 1 {
+		t.Errorf("got %d sent, want 1", sent)
+	}
+
+	// test flapping to another notification, last one is shown after the
+	// rate limit
+	n.Send(&Notification{Title: "untrusted", Group: "tnd"})
+	n.Send(&Notification{Title: "trusted", Group: "tnd"})
+	n.Send(&Notification{Title: "untrusted", Group: "tnd"})
+	n.Send(&Notification{Title: "trusted", Group: "tnd"})
+	if sent, _ := conn.getSent(); sent != 2 {
+		t.Errorf("got %d sent, want 2", sent)
+	}
+	wait()
+	sent, args := conn.getSent()
+	if sent != 3 || args[3] != "trusted" {
+		t.Errorf("got %d sent, %v, want 3 sent, trusted", sent, args[3])
+	}
+
+	// test other group and no group, not rate limited
+	n.Send(&Notification{Title: "logged in", Group: "login"})
+	n.Send(&Notification{Title: "details"})
+	n.Send(&Notification{Title: "details"})
+	if sent, _ := conn.getSent(); sent != 6 {
+		t.Errorf("got %d sent, want 6", sent)
+	}
+
+	// test critical notification, not rate limited and replaces the
+	// pending notification
+	n.Send(&Notification{Title: "logged out", Group: "login"})
+	n.Send(&Notification{Title: "login failed", Group: "login", Urgency: UrgencyCritical})
+	if sent, args := conn.getSent(); sent != 7 || args[3] != "login failed" {
+		t.Errorf("got %d sent, %v, want 7 sent, login failed", sent, args[3])
+	}
+	wait()
+	if sent, args := conn.getSent(); sent != 7 || args[3] != "login failed" {
+		t.Errorf("got %d sent, %v, want 7 sent, login failed", sent, args[3])
+	}
+
+	// test close with pending notification
+	n.Send(&Notification{Title: "logged in", Group: "login"})
+	n.Send(&Notification{Title: "logged out", Group: "login"})
+}
+
+// TestNotifierActions tests Actions of Notifier.
+func TestNotifierActions(t *testing.T) {
+	// test nil
+	var n *Notifier
+	if n.Actions() != nil {
+		t.Error("nil notifier should not have actions")
+	}
+
+	// test with signals
+	conn := &testConn{id: 1234}
+	dbusSessionConn = func() (Conn, error) {
+		return conn, nil
+	}
+	n, _ = NewNotifier()
+	defer n.Close()
+	n.Notify("test", "this is a test", Action{Key: "relogin", Label: "Re-login"})
+
+	// ignored signals: invalid body, other notification
+	conn.signals <- &dbus.Signal{Name: actionInvoked, Body: []any{"invalid"}}
+	conn.signals <- &dbus.Signal{Name: actionInvoked, Body: []any{"invalid", "relogin"}}
+	conn.signals <- &dbus.Signal{Name: actionInvoked, Body: []any{uint32(1), "other"}}
+	conn.signals <- &dbus.Signal{Name: actionInvoked, Body: []any{uint32(1234), 5}}
+
+	// invoked action
+	conn.signals <- &dbus.Signal{Name: actionInvoked, Body: []any{uint32(1234), "relogin"}}
+	if got := <-n.Actions(); got != "relogin" {
+		t.Errorf("got %s, want relogin", got)
+	}
+
+	// closed notification, actions are ignored
+	conn.signals <- &dbus.Signal{Name: notificationClosed, Body: []any{uint32(1234), uint32(2)}}
+	for active := true; active; {
+		n.mutex.Lock()
+		active = n.active
+		n.mutex.Unlock()
+	}
+	n.handleSignal(&dbus.Signal{Name: actionInvoked, Body: []any{uint32(1234), "closed"}})
+
+	// new notification
+	n.Notify("test", "this is another test", Action{Key: "details", Label: "Details"})
+	conn.signals <- &dbus.Signal{Name: actionInvoked, Body: []any{uint32(1234), "details"}}
+	if got := <-n.Actions(); got != "details" {
+		t.Errorf("got %s, want details", got)
+	}
 }
 
 // TestNotifierClose tests close of Notify.
@@ -81,6 +349,18 @@ func TestNotifierClose(_ *testing.T) {
 	n.Close()
 
 	// test normal
-	n = &Notifier{conn: &testConn{}}
+	dbusSessionConn = func() (Conn, error) {
+		return &testConn{}, nil
+	}
+	n, _ = NewNotifier()
+	n.Close()
+
+	// test closed signals channel
+	conn := &testConn{}
+	dbusSessionConn = func() (Conn, error) {
+		return conn, nil
+	}
+	n, _ = NewNotifier()
+	close(n.signals)
 	n.Close()
 }
@@ -0,0 +1,71 @@
+// Package sdnotify contains the systemd service notification protocol, see
+// sd_notify(3).
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Service states.
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Status returns the service state for the status text status.
+func Status(status string) string {
+	return "STATUS=" + status
+}
+
+// Notify sends the service states in states to systemd. It does nothing if
+// the service was not started by systemd with a notification socket.
+func Notify(states ...string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// handle socket in abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{
+		Name: socket,
+		Net:  "unixgram",
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err = conn.Write([]byte(strings.Join(states, "\n")))
+	return err
+}
+
+// WatchdogInterval returns the interval in which the service must send
+// watchdog notifications to systemd or 0 if the watchdog is not enabled for
+// this process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	// make sure watchdog is meant for us if pid is set
+	if p := os.Getenv("WATCHDOG_PID"); p != "" {
+		pid, err := strconv.Atoi(p)
+		if err != nil || pid != os.Getpid() {
+			return 0
+		}
+	}
+
+	return time.Duration(usec) * time.Microsecond
+}
@@ -0,0 +1,106 @@
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestStatus tests Status.
+func TestStatus(t *testing.T) {
+	want := "STATUS=test status"
+	if got := Status("test status"); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// TestNotify tests Notify.
+func TestNotify(t *testing.T) {
+	// test without socket
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := Notify(Ready); err != nil {
+		t.Error(err)
+	}
+
+	// test with invalid socket
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "does-not-exist"))
+	if err := Notify(Ready); err == nil {
+		t.Error("notify should fail with invalid socket")
+	}
+
+	// test with socket
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{
+		Name: socket,
+		Net:  "unixgram",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	if err := Notify(Ready, Status("test")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1024)
+	n, err := conn.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "READY=1\nSTATUS=test"
+	if got := string(b[:n]); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// test with socket in abstract namespace
+	abstract := "@fw-id-agent-test-" + strconv.Itoa(os.Getpid())
+	aconn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{
+		Name: abstract,
+		Net:  "unixgram",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = aconn.Close() }()
+
+	t.Setenv("NOTIFY_SOCKET", abstract)
+	if err := Notify(Watchdog); err != nil {
+		t.Fatal(err)
+	}
+	n, err = aconn.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b[:n]); got != Watchdog {
+		t.Errorf("got %q, want %q", got, Watchdog)
+	}
+}
+
+// TestWatchdogInterval tests WatchdogInterval.
+func TestWatchdogInterval(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	for _, test := range []struct {
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{"", "", 0},
+		{"invalid", "", 0},
+		{"0", "", 0},
+		{"-1", "", 0},
+		{"30000000", "invalid", 0},
+		{"30000000", "1", 0},
+		{"30000000", "", 30 * time.Second},
+		{"30000000", pid, 30 * time.Second},
+	} {
+		t.Setenv("WATCHDOG_USEC", test.usec)
+		t.Setenv("WATCHDOG_PID", test.pid)
+		if got := WatchdogInterval(); got != test.want {
+			t.Errorf("got %v, want %v", got, test.want)
+		}
+	}
+}
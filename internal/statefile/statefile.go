@@ -0,0 +1,116 @@
+// Package statefile contains the agent state that is persisted across agent
+// restarts.
+package statefile
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is the persisted agent state.
+type State struct {
+	// LoggedIn specifies whether the client was logged in when the state
+	// was saved.
+	LoggedIn bool
+
+	// LastLogin is the time of the last successful login as unix
+	// timestamp.
+	LastLogin int64
+
+	// LastKeepAlive is the time of the last successful keep-alive as unix
+	// timestamp.
+	LastKeepAlive int64
+
+	// SourceIP is the local source IP used to reach the service at the
+	// last login.
+	SourceIP string
+
+	// KeepAlive is the keep-alive time negotiated with the service in
+	// minutes.
+	KeepAlive int
+
+	// LastError is the last login error.
+	LastError string
+}
+
+// GetKeepAlive returns the negotiated keep-alive time as Duration.
+func (s *State) GetKeepAlive() time.Duration {
+	return time.Duration(s.KeepAlive) * time.Minute
+}
+
+// SessionValid returns whether the session of the last login is still valid
+// at time now, so no immediate login is needed.
+func (s *State) SessionValid(now time.Time) bool {
+	if !s.LoggedIn || s.LastKeepAlive <= 0 || s.KeepAlive <= 0 {
+		return false
+	}
+	expires := time.Unix(s.LastKeepAlive, 0).Add(s.GetKeepAlive())
+	return now.Before(expires)
+}
+
+// userHomeDir is os.UserHomeDir for testing.
+var userHomeDir = os.UserHomeDir
+
+// Path returns the default path of the state file in $XDG_STATE_HOME.
+func Path() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := userHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "fw-id-agent", "state.json"), nil
+}
+
+// Load loads the state from the state file in path. If the file does not
+// exist, an empty state is returned.
+func Load(path string) (*State, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &State{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save saves the state to the state file in path. The file is replaced
+// atomically, so an agent crash never leaves a partially written file.
+func (s *State) Save(path string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	// create state directory
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// write temporary file and replace state file
+	f, err := os.CreateTemp(dir, ".state-*.json")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,104 @@
+package statefile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestStateSessionValid tests SessionValid of State.
+func TestStateSessionValid(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	// test invalid
+	for _, s := range []*State{
+		{},
+		{LoggedIn: false, LastKeepAlive: 900, KeepAlive: 5},
+		{LoggedIn: true, LastKeepAlive: 0, KeepAlive: 5},
+		{LoggedIn: true, LastKeepAlive: 900, KeepAlive: 0},
+		{LoggedIn: true, LastKeepAlive: 700, KeepAlive: 5},
+	} {
+		if s.SessionValid(now) {
+			t.Errorf("session should not be valid: %v", s)
+		}
+	}
+
+	// test valid
+	s := &State{LoggedIn: true, LastKeepAlive: 900, KeepAlive: 5}
+	if !s.SessionValid(now) {
+		t.Errorf("session should be valid: %v", s)
+	}
+}
+
+// TestPath tests Path.
+func TestPath(t *testing.T) {
+	defer func() { userHomeDir = os.UserHomeDir }()
+
+	// test with XDG_STATE_HOME
+	t.Setenv("XDG_STATE_HOME", "/test/state")
+	if p, err := Path(); err != nil || p != "/test/state/fw-id-agent/state.json" {
+		t.Errorf("unexpected path %s, %v", p, err)
+	}
+
+	// test with home directory
+	t.Setenv("XDG_STATE_HOME", "")
+	userHomeDir = func() (string, error) {
+		return "/home/test", nil
+	}
+	if p, err := Path(); err != nil || p != "/home/test/.local/state/fw-id-agent/state.json" {
+		t.Errorf("unexpected path %s, %v", p, err)
+	}
+
+	// test home directory error
+	userHomeDir = func() (string, error) {
+		return "", errors.New("test error")
+	}
+	if _, err := Path(); err == nil {
+		t.Error("home directory error should return error")
+	}
+}
+
+// TestLoadSave tests Load and Save of State.
+func TestLoadSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fw-id-agent", "state.json")
+
+	// test not existing file
+	s, err := Load(path)
+	if err != nil || !reflect.DeepEqual(s, &State{}) {
+		t.Errorf("got %v, %v, want empty state", s, err)
+	}
+
+	// test save and load
+	want := &State{
+		LoggedIn:      true,
+		LastLogin:     1,
+		LastKeepAlive: 2,
+		SourceIP:      "192.168.1.10",
+		KeepAlive:     5,
+		LastError:     "test error",
+	}
+	if err := want.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(path)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, %v, want %v", got, err, want)
+	}
+
+	// test invalid file
+	if err := os.WriteFile(path, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("invalid file should return error")
+	}
+
+	// test save error
+	if err := want.Save(filepath.Join(path, "invalid")); err == nil {
+		t.Error("invalid path should return error")
+	}
+}
@@ -0,0 +1,98 @@
+package tray
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/fw-id-agent/internal/agent"
+	"github.com/telekom-mms/fw-id-agent/internal/cmdline"
+	"github.com/telekom-mms/fw-id-agent/internal/i18n"
+	"github.com/telekom-mms/fw-id-agent/internal/logging"
+	"github.com/telekom-mms/fw-id-agent/pkg/config"
+)
+
+// arguments are the command line arguments of the tray.
+type arguments struct {
+	ver       bool
+	logFormat string
+	logLevel  string
+}
+
+// newCommand returns the command line definition of the tray with name. The
+// flags are bound to a.
+func newCommand(name string, a *arguments) *cmdline.Command {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.BoolVar(&a.ver, "version", false, "print version")
+	flags.StringVar(&a.logFormat, "logformat", config.LogFormatText,
+		"Set log `format` (text, json, journald)")
+	flags.StringVar(&a.logLevel, "loglevel", "info",
+		"Set log `level` (trace, debug, info, warn, error)")
+
+	return &cmdline.Command{
+		Name:  name,
+		Short: "system tray indicator of the firewall identity agent",
+		Long: "fw-id-tray shows the trusted network and login state of the " +
+			"firewall identity agent as StatusNotifierItem in the system " +
+			"tray. The tool tip shows the expiry of the Kerberos TGT, the " +
+			"menu allows to log in again, log out, pause and resume the " +
+			"agent and show its status.",
+		Flags: flags,
+		FlagValues: map[string][]string{
+			"logformat": {"text", "json", "journald"},
+			"loglevel":  {"trace", "debug", "info", "warn", "error"},
+		},
+	}
+}
+
+// Command returns the command line definition of the tray.
+func Command() *cmdline.Command {
+	return newCommand("fw-id-tray", &arguments{})
+}
+
+// run is the main function.
+func run(args []string) error {
+	// parse command line arguments
+	a := &arguments{}
+	if _, err := newCommand(args[0], a).Parse(args[1:]); err != nil {
+		return err
+	}
+	if a.ver {
+		fmt.Println(agent.Version)
+		return flag.ErrHelp
+	}
+	if err := logging.Setup(a.logFormat, a.logLevel); err != nil {
+		log.WithError(err).Error("Tray could not set up logging")
+	}
+
+	// set language of user-facing messages
+	i18n.SetLocale(i18n.EnvLocale())
+
+	// start tray
+	t := NewTray()
+	if err := t.Start(); err != nil {
+		return err
+	}
+	defer t.Stop()
+
+	// wait for interrupt or terminate signal
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+	sig := <-c
+	log.WithField("signal", sig).Info("Tray got terminate signal")
+	return nil
+}
+
+// Run is the main entry point.
+func Run() {
+	if err := run(os.Args); err != nil {
+		if err != flag.ErrHelp {
+			log.Fatal(err)
+		}
+		return
+	}
+}
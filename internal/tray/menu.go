@@ -0,0 +1,255 @@
+package tray
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// dbusmenu D-Bus interface and path.
+const (
+	menuInterface = "com.canonical.dbusmenu"
+	menuPath      = dbus.ObjectPath("/MenuBar")
+)
+
+// menuLayout is the layout of a menu item in the dbusmenu protocol.
+type menuLayout struct {
+	ID         int32
+	Properties map[string]dbus.Variant
+	Children   []dbus.Variant
+}
+
+// menuItemProperties are the properties of a menu item in the dbusmenu
+// protocol.
+type menuItemProperties struct {
+	ID         int32
+	Properties map[string]dbus.Variant
+}
+
+// menuEvent is an event of a menu item in the dbusmenu protocol.
+type menuEvent struct {
+	ID        int32
+	EventID   string
+	Data      dbus.Variant
+	Timestamp uint32
+}
+
+// menuItem is an item of the tray menu.
+type menuItem struct {
+	label     string
+	enabled   bool
+	separator bool
+	action    action
+}
+
+// properties returns the dbusmenu properties of the menu item.
+func (i *menuItem) properties() map[string]dbus.Variant {
+	if i.separator {
+		return map[string]dbus.Variant{
+			"type": dbus.MakeVariant("separator"),
+		}
+	}
+	return map[string]dbus.Variant{
+		"label":   dbus.MakeVariant(i.label),
+		"enabled": dbus.MakeVariant(i.enabled),
+	}
+}
+
+// menu implements the dbusmenu D-Bus interface of the tray menu. The root
+// item has ID 0, the menu items have their index in items plus 1 as ID.
+type menu struct {
+	mutex    sync.Mutex
+	revision uint32
+	items    []*menuItem
+
+	actions chan<- action
+	done    <-chan struct{}
+}
+
+// errUnknownID is the D-Bus error for unknown menu item IDs.
+var errUnknownID = dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs",
+	[]any{"unknown menu item id"})
+
+// item returns the menu item with id, nil if it does not exist.
+func (m *menu) item(id int32) *menuItem {
+	if id < 1 || int(id) > len(m.items) {
+		return nil
+	}
+	return m.items[id-1]
+}
+
+// rootProperties returns the dbusmenu properties of the root item.
+func rootProperties() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"children-display": dbus.MakeVariant("submenu"),
+	}
+}
+
+// GetLayout is the "GetLayout" method of the dbusmenu D-Bus interface.
+func (m *menu) GetLayout(parentID int32, _ int32, _ []string) (uint32, menuLayout, *dbus.Error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if parentID != 0 {
+		i := m.item(parentID)
+		if i == nil {
+			return 0, menuLayout{}, errUnknownID
+		}
+		return m.revision, menuLayout{
+			ID:         parentID,
+			Properties: i.properties(),
+			Children:   []dbus.Variant{},
+		}, nil
+	}
+
+	children := []dbus.Variant{}
+	for n, i := range m.items {
+		children = append(children, dbus.MakeVariant(menuLayout{
+			ID:         int32(n + 1),
+			Properties: i.properties(),
+			Children:   []dbus.Variant{},
+		}))
+	}
+	return m.revision, menuLayout{
+		ID:         0,
+		Properties: rootProperties(),
+		Children:   children,
+	}, nil
+}
+
+// GetGroupProperties is the "GetGroupProperties" method of the dbusmenu
+// D-Bus interface.
+func (m *menu) GetGroupProperties(ids []int32, _ []string) ([]menuItemProperties, *dbus.Error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	props := []menuItemProperties{}
+	for _, id := range ids {
+		if id == 0 {
+			props = append(props, menuItemProperties{id, rootProperties()})
+			continue
+		}
+		if i := m.item(id); i != nil {
+			props = append(props, menuItemProperties{id, i.properties()})
+		}
+	}
+	return props, nil
+}
+
+// GetProperty is the "GetProperty" method of the dbusmenu D-Bus interface.
+func (m *menu) GetProperty(id int32, name string) (dbus.Variant, *dbus.Error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	props := rootProperties()
+	if id != 0 {
+		i := m.item(id)
+		if i == nil {
+			return dbus.Variant{}, errUnknownID
+		}
+		props = i.properties()
+	}
+	v, ok := props[name]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs",
+			[]any{"unknown property"})
+	}
+	return v, nil
+}
+
+// event handles the event with eventID of the menu item with id and returns
+// whether the menu item exists.
+func (m *menu) event(id int32, eventID string) bool {
+	m.mutex.Lock()
+	i := m.item(id)
+	m.mutex.Unlock()
+
+	if i == nil {
+		return id == 0
+	}
+	if eventID != "clicked" || !i.enabled || i.separator {
+		return true
+	}
+	select {
+	case m.actions <- i.action:
+	case <-m.done:
+	}
+	return true
+}
+
+// Event is the "Event" method of the dbusmenu D-Bus interface.
+func (m *menu) Event(id int32, eventID string, _ dbus.Variant, _ uint32) *dbus.Error {
+	if !m.event(id, eventID) {
+		return errUnknownID
+	}
+	return nil
+}
+
+// EventGroup is the "EventGroup" method of the dbusmenu D-Bus interface.
+func (m *menu) EventGroup(events []menuEvent) ([]int32, *dbus.Error) {
+	idErrors := []int32{}
+	for _, e := range events {
+		if !m.event(e.ID, e.EventID) {
+			idErrors = append(idErrors, e.ID)
+		}
+	}
+	if len(events) > 0 && len(idErrors) == len(events) {
+		return idErrors, errUnknownID
+	}
+	return idErrors, nil
+}
+
+// AboutToShow is the "AboutToShow" method of the dbusmenu D-Bus interface.
+func (m *menu) AboutToShow(int32) (bool, *dbus.Error) {
+	return false, nil
+}
+
+// AboutToShowGroup is the "AboutToShowGroup" method of the dbusmenu D-Bus
+// interface.
+func (m *menu) AboutToShowGroup([]int32) ([]int32, []int32, *dbus.Error) {
+	return []int32{}, []int32{}, nil
+}
+
+// setItems sets the menu items and returns the new layout revision.
+func (m *menu) setItems(items []*menuItem) uint32 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.items = items
+	m.revision++
+	return m.revision
+}
+
+// menuProperties are the D-Bus properties of the dbusmenu interface.
+func menuProperties() prop.Map {
+	return prop.Map{
+		menuInterface: {
+			"Version":       {Value: uint32(3), Emit: prop.EmitFalse},
+			"TextDirection": {Value: "ltr", Emit: prop.EmitFalse},
+			"Status":        {Value: "normal", Emit: prop.EmitFalse},
+			"IconThemePath": {Value: []string{}, Emit: prop.EmitFalse},
+		},
+	}
+}
+
+// menuSignals are the D-Bus signals of the dbusmenu interface.
+var menuSignals = []introspect.Signal{
+	{
+		Name: "LayoutUpdated",
+		Args: []introspect.Arg{
+			{Name: "revision", Type: "u"},
+			{Name: "parent", Type: "i"},
+		},
+	},
+}
+
+// newMenu returns a new menu that sends actions of clicked menu items over
+// actions.
+func newMenu(actions chan<- action, done <-chan struct{}) *menu {
+	return &menu{
+		actions: actions,
+		done:    done,
+	}
+}
@@ -0,0 +1,175 @@
+package tray
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// testMenu returns a menu with test items for testing.
+func testMenu(actions chan action) *menu {
+	m := newMenu(actions, make(chan struct{}))
+	m.setItems([]*menuItem{
+		{label: "Enabled", enabled: true, action: actionReLogin},
+		{label: "Disabled", enabled: false, action: actionLogout},
+		{separator: true},
+	})
+	return m
+}
+
+// TestMenuGetLayout tests GetLayout of menu.
+func TestMenuGetLayout(t *testing.T) {
+	m := testMenu(nil)
+
+	// test root
+	rev, layout, err := m.GetLayout(0, -1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev != 1 {
+		t.Errorf("got %d, want 1", rev)
+	}
+	if layout.ID != 0 || len(layout.Children) != 3 {
+		t.Errorf("invalid layout: %v", layout)
+	}
+	child := layout.Children[0].Value().(menuLayout)
+	if child.ID != 1 || child.Properties["label"].Value() != "Enabled" {
+		t.Errorf("invalid child: %v", child)
+	}
+
+	// test item
+	_, layout, err = m.GetLayout(3, -1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.ID != 3 || layout.Properties["type"].Value() != "separator" {
+		t.Errorf("invalid layout: %v", layout)
+	}
+
+	// test unknown item
+	if _, _, err := m.GetLayout(4, -1, nil); err == nil {
+		t.Error("unknown item should return error")
+	}
+}
+
+// TestMenuGetGroupProperties tests GetGroupProperties of menu.
+func TestMenuGetGroupProperties(t *testing.T) {
+	m := testMenu(nil)
+
+	props, err := m.GetGroupProperties([]int32{0, 2, 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []menuItemProperties{
+		{0, rootProperties()},
+		{2, map[string]dbus.Variant{
+			"label":   dbus.MakeVariant("Disabled"),
+			"enabled": dbus.MakeVariant(false),
+		}},
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("got %v, want %v", props, want)
+	}
+}
+
+// TestMenuGetProperty tests GetProperty of menu.
+func TestMenuGetProperty(t *testing.T) {
+	m := testMenu(nil)
+
+	// test valid properties
+	for _, test := range []struct {
+		id   int32
+		name string
+		want any
+	}{
+		{0, "children-display", "submenu"},
+		{1, "label", "Enabled"},
+		{2, "enabled", false},
+	} {
+		v, err := m.GetProperty(test.id, test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Value() != test.want {
+			t.Errorf("got %v, want %v", v.Value(), test.want)
+		}
+	}
+
+	// test invalid properties
+	if _, err := m.GetProperty(1, "invalid"); err == nil {
+		t.Error("unknown property should return error")
+	}
+	if _, err := m.GetProperty(7, "label"); err == nil {
+		t.Error("unknown item should return error")
+	}
+}
+
+// TestMenuEvent tests Event of menu.
+func TestMenuEvent(t *testing.T) {
+	actions := make(chan action, 1)
+	m := testMenu(actions)
+
+	// test clicked enabled item
+	if err := m.Event(1, "clicked", dbus.MakeVariant(0), 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-actions; got != actionReLogin {
+		t.Errorf("got %d, want %d", got, actionReLogin)
+	}
+
+	// test ignored events
+	for _, id := range []int32{0, 2, 3} {
+		if err := m.Event(id, "clicked", dbus.MakeVariant(0), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Event(1, "hovered", dbus.MakeVariant(0), 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 {
+		t.Error("ignored events should not send actions")
+	}
+
+	// test unknown item
+	if err := m.Event(4, "clicked", dbus.MakeVariant(0), 0); err == nil {
+		t.Error("unknown item should return error")
+	}
+}
+
+// TestMenuEventGroup tests EventGroup of menu.
+func TestMenuEventGroup(t *testing.T) {
+	actions := make(chan action, 1)
+	m := testMenu(actions)
+
+	// test with valid and invalid items
+	idErrors, err := m.EventGroup([]menuEvent{
+		{ID: 1, EventID: "clicked"},
+		{ID: 9, EventID: "clicked"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(idErrors, []int32{9}) {
+		t.Errorf("got %v, want [9]", idErrors)
+	}
+	if got := <-actions; got != actionReLogin {
+		t.Errorf("got %d, want %d", got, actionReLogin)
+	}
+
+	// test with only invalid items
+	if _, err := m.EventGroup([]menuEvent{{ID: 9, EventID: "clicked"}}); err == nil {
+		t.Error("only unknown items should return error")
+	}
+}
+
+// TestMenuSetItems tests setItems of menu.
+func TestMenuSetItems(t *testing.T) {
+	m := testMenu(nil)
+	if rev := m.setItems(nil); rev != 2 {
+		t.Errorf("got %d, want 2", rev)
+	}
+	if _, layout, _ := m.GetLayout(0, -1, nil); len(layout.Children) != 0 {
+		t.Errorf("got %d children, want 0", len(layout.Children))
+	}
+}
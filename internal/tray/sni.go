@@ -0,0 +1,197 @@
+package tray
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/telekom-mms/fw-id-agent/internal/i18n"
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// StatusNotifierItem and StatusNotifierWatcher D-Bus names, interfaces and
+// paths.
+const (
+	itemInterface   = "org.kde.StatusNotifierItem"
+	itemPath        = dbus.ObjectPath("/StatusNotifierItem")
+	watcherName     = "org.kde.StatusNotifierWatcher"
+	watcherPath     = dbus.ObjectPath("/StatusNotifierWatcher")
+	watcherRegister = watcherName + ".RegisterStatusNotifierItem"
+)
+
+// StatusNotifierItem status values.
+const (
+	itemStatusActive         = "Active"
+	itemStatusNeedsAttention = "NeedsAttention"
+)
+
+// Icon names of the tray item, see the freedesktop icon naming spec.
+const (
+	iconLoggedIn   = "security-high"
+	iconLoggedOut  = "security-medium"
+	iconUntrusted  = "security-low"
+	iconNotRunning = "dialog-error"
+)
+
+// title is the title of the tray item.
+const title = "Firewall Identity Agent"
+
+// pixmap is an icon pixmap in the StatusNotifierItem protocol.
+type pixmap struct {
+	Width  int32
+	Height int32
+	Data   []byte
+}
+
+// toolTip is a tool tip in the StatusNotifierItem protocol.
+type toolTip struct {
+	IconName    string
+	IconPixmap  []pixmap
+	Title       string
+	Description string
+}
+
+// running returns whether the agent is running in status s.
+func running(s *status.Status) bool {
+	return s.AgentState != status.AgentStateUnknown
+}
+
+// iconName returns the icon name for status s.
+func iconName(s *status.Status) string {
+	switch {
+	case !running(s):
+		return iconNotRunning
+	case s.LoginState.LoggedIn():
+		return iconLoggedIn
+	case s.TrustedNetwork.Trusted():
+		return iconLoggedOut
+	}
+	return iconUntrusted
+}
+
+// itemStatus returns the StatusNotifierItem status for status s. It needs
+// attention if the agent is not running or not logged in on a trusted
+// network.
+func itemStatus(s *status.Status) string {
+	if !running(s) || (s.TrustedNetwork.Trusted() && !s.LoginState.LoggedIn() &&
+		s.AgentState != status.AgentStatePaused) {
+		return itemStatusNeedsAttention
+	}
+	return itemStatusActive
+}
+
+// statusText returns the description of status s.
+func statusText(s *status.Status) string {
+	if !running(s) {
+		return i18n.String(i18n.NotifyAgentNotRunning)
+	}
+	lines := []string{
+		fmt.Sprintf("%s: %s", i18n.String(i18n.LabelTrustedNetwork),
+			i18n.TrustedNetwork(s.TrustedNetwork)),
+		fmt.Sprintf("%s: %s", i18n.String(i18n.LabelLoginState),
+			i18n.LoginState(s.LoginState)),
+		fmt.Sprintf("%s: %s", i18n.String(i18n.LabelAgentState),
+			i18n.AgentState(s.AgentState)),
+	}
+	if s.KerberosTGT.EndTime > 0 {
+		end := time.Unix(s.KerberosTGT.EndTime, 0).Format(time.DateTime)
+		lines = append(lines, fmt.Sprintf("%s: %s",
+			i18n.String(i18n.LabelKerberosTGTExpires), end))
+	} else {
+		lines = append(lines, fmt.Sprintf("%s: %s",
+			i18n.String(i18n.LabelKerberosTGT), i18n.String(i18n.LabelNone)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// newToolTip returns the tool tip for status s.
+func newToolTip(s *status.Status) toolTip {
+	return toolTip{
+		IconName:    iconName(s),
+		IconPixmap:  []pixmap{},
+		Title:       title,
+		Description: statusText(s),
+	}
+}
+
+// item implements the methods of the StatusNotifierItem D-Bus interface.
+type item struct {
+	actions chan<- action
+	done    <-chan struct{}
+}
+
+// send sends action a to the tray.
+func (i item) send(a action) {
+	select {
+	case i.actions <- a:
+	case <-i.done:
+	}
+}
+
+// Activate is the "Activate" method of the StatusNotifierItem D-Bus
+// interface, it shows the status.
+func (i item) Activate(int32, int32) *dbus.Error {
+	i.send(actionStatus)
+	return nil
+}
+
+// SecondaryActivate is the "SecondaryActivate" method of the
+// StatusNotifierItem D-Bus interface.
+func (i item) SecondaryActivate(int32, int32) *dbus.Error {
+	return nil
+}
+
+// ContextMenu is the "ContextMenu" method of the StatusNotifierItem D-Bus
+// interface. The menu is exported with dbusmenu, so nothing to do here.
+func (i item) ContextMenu(int32, int32) *dbus.Error {
+	return nil
+}
+
+// Scroll is the "Scroll" method of the StatusNotifierItem D-Bus interface.
+func (i item) Scroll(int32, string) *dbus.Error {
+	return nil
+}
+
+// itemProperties returns the D-Bus properties of the StatusNotifierItem
+// interface for status s. Hosts are informed about changes with the New*
+// signals, so the properties do not emit PropertiesChanged signals.
+func itemProperties(s *status.Status) prop.Map {
+	p := func(v any) *prop.Prop {
+		return &prop.Prop{Value: v, Emit: prop.EmitFalse}
+	}
+	return prop.Map{
+		itemInterface: {
+			"Category":            p("ApplicationStatus"),
+			"Id":                  p("fw-id-agent"),
+			"Title":               p(title),
+			"Status":              p(itemStatus(s)),
+			"WindowId":            p(int32(0)),
+			"IconName":            p(iconName(s)),
+			"IconPixmap":          p([]pixmap{}),
+			"OverlayIconName":     p(""),
+			"OverlayIconPixmap":   p([]pixmap{}),
+			"AttentionIconName":   p(iconName(s)),
+			"AttentionIconPixmap": p([]pixmap{}),
+			"AttentionMovieName":  p(""),
+			"ToolTip":             p(newToolTip(s)),
+			"ItemIsMenu":          p(false),
+			"Menu":                p(menuPath),
+		},
+	}
+}
+
+// itemSignals are the D-Bus signals of the StatusNotifierItem interface.
+var itemSignals = []introspect.Signal{
+	{Name: "NewTitle"},
+	{Name: "NewIcon"},
+	{Name: "NewAttentionIcon"},
+	{Name: "NewOverlayIcon"},
+	{Name: "NewToolTip"},
+	{
+		Name: "NewStatus",
+		Args: []introspect.Arg{{Name: "status", Type: "s"}},
+	},
+}
@@ -0,0 +1,120 @@
+package tray
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// TestIconNameItemStatus tests iconName and itemStatus.
+func TestIconNameItemStatus(t *testing.T) {
+	for _, test := range []struct {
+		agent   status.AgentState
+		trusted status.TrustedNetwork
+		login   status.LoginState
+		icon    string
+		status  string
+	}{
+		{status.AgentStateUnknown, status.TrustedNetworkTrusted, status.LoginStateLoggedIn,
+			iconNotRunning, itemStatusNeedsAttention},
+		{status.AgentStateLoggedIn, status.TrustedNetworkTrusted, status.LoginStateLoggedIn,
+			iconLoggedIn, itemStatusActive},
+		{status.AgentStateTrustedLoggingIn, status.TrustedNetworkTrusted, status.LoginStateLoggingIn,
+			iconLoggedOut, itemStatusNeedsAttention},
+		{status.AgentStatePaused, status.TrustedNetworkTrusted, status.LoginStateLoggedOut,
+			iconLoggedOut, itemStatusActive},
+		{status.AgentStateUntrusted, status.TrustedNetworkNotTrusted, status.LoginStateLoggedOut,
+			iconUntrusted, itemStatusActive},
+	} {
+		s := status.New()
+		s.AgentState = test.agent
+		s.TrustedNetwork = test.trusted
+		s.LoginState = test.login
+
+		if got := iconName(s); got != test.icon {
+			t.Errorf("got %s, want %s", got, test.icon)
+		}
+		if got := itemStatus(s); got != test.status {
+			t.Errorf("got %s, want %s", got, test.status)
+		}
+	}
+}
+
+// TestStatusText tests statusText.
+func TestStatusText(t *testing.T) {
+	// test agent not running
+	s := status.New()
+	if got := statusText(s); got != "Agent not running" {
+		t.Errorf("got %s, want Agent not running", got)
+	}
+
+	// test without TGT
+	s.AgentState = status.AgentStateLoggedIn
+	s.TrustedNetwork = status.TrustedNetworkTrusted
+	s.LoginState = status.LoginStateLoggedIn
+	want := "Trusted Network: trusted\n" +
+		"Login State: logged in\n" +
+		"Agent State: logged in\n" +
+		"Kerberos TGT: none"
+	if got := statusText(s); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// test with TGT
+	end := time.Date(2023, 1, 1, 12, 0, 0, 0, time.Local)
+	s.KerberosTGT.EndTime = end.Unix()
+	want = "Kerberos TGT expires: 2023-01-01 12:00:00"
+	if got := statusText(s); !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, want suffix %q", got, want)
+	}
+}
+
+// TestNewToolTip tests newToolTip.
+func TestNewToolTip(t *testing.T) {
+	s := status.New()
+	tt := newToolTip(s)
+	if tt.IconName != iconNotRunning || tt.Title != title ||
+		tt.Description != statusText(s) || tt.IconPixmap == nil {
+		t.Errorf("invalid tool tip: %v", tt)
+	}
+}
+
+// TestItemActivate tests Activate of item.
+func TestItemActivate(t *testing.T) {
+	actions := make(chan action, 1)
+	done := make(chan struct{})
+	i := item{actions, done}
+
+	if err := i.Activate(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-actions; got != actionStatus {
+		t.Errorf("got %d, want %d", got, actionStatus)
+	}
+
+	// test closed tray
+	close(done)
+	actions <- actionStatus
+	if err := i.Activate(0, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestItemProperties tests itemProperties.
+func TestItemProperties(t *testing.T) {
+	s := status.New()
+	s.AgentState = status.AgentStateUntrusted
+	props := itemProperties(s)[itemInterface]
+	for name, want := range map[string]any{
+		"Title":    title,
+		"Status":   itemStatusActive,
+		"IconName": iconUntrusted,
+		"Menu":     menuPath,
+	} {
+		if got := props[name].Value; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
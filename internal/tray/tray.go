@@ -0,0 +1,374 @@
+// Package tray contains the system tray indicator of the firewall identity
+// agent. It implements the StatusNotifierItem and dbusmenu D-Bus protocols.
+package tray
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/fw-id-agent/internal/i18n"
+	"github.com/telekom-mms/fw-id-agent/internal/notify"
+	"github.com/telekom-mms/fw-id-agent/pkg/client"
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// action is a user action in the tray.
+type action int
+
+// Actions.
+const (
+	actionStatus action = iota
+	actionReLogin
+	actionLogout
+	actionPause
+	actionResume
+)
+
+// menuItems returns the menu items for status s.
+func menuItems(s *status.Status) []*menuItem {
+	paused := s.AgentState == status.AgentStatePaused
+	pause := &menuItem{label: i18n.String(i18n.ActionPause), enabled: running(s), action: actionPause}
+	if paused {
+		pause = &menuItem{label: i18n.String(i18n.ActionResume), enabled: true, action: actionResume}
+	}
+	return []*menuItem{
+		{
+			label:   i18n.String(i18n.ActionReLogin),
+			enabled: running(s) && !paused && s.TrustedNetwork.Trusted(),
+			action:  actionReLogin,
+		},
+		{
+			label:   i18n.String(i18n.ActionLogout),
+			enabled: s.LoginState.LoggedIn(),
+			action:  actionLogout,
+		},
+		pause,
+		{separator: true},
+		{label: i18n.String(i18n.ActionStatus), enabled: true, action: actionStatus},
+	}
+}
+
+// retryInterval is the interval between connection attempts to the agent.
+var retryInterval = 10 * time.Second
+
+// dbusConn is an interface for dbus.Conn to allow for testing.
+type dbusConn interface {
+	Close() error
+	Export(v any, path dbus.ObjectPath, iface string) error
+	RequestName(name string, flags dbus.RequestNameFlags) (dbus.RequestNameReply, error)
+	Emit(path dbus.ObjectPath, name string, values ...any) error
+	Object(dest string, path dbus.ObjectPath) dbus.BusObject
+	AddMatchSignal(options ...dbus.MatchOption) error
+	Signal(ch chan<- *dbus.Signal)
+}
+
+// dbusConnectSessionBus encapsulates dbus.ConnectSessionBus to allow for
+// testing.
+var dbusConnectSessionBus = func() (dbusConn, error) {
+	return dbus.ConnectSessionBus()
+}
+
+// propProperties is an interface for prop.Properties to allow for testing.
+type propProperties interface {
+	Introspection(iface string) []introspect.Property
+	SetMust(iface, property string, v any)
+}
+
+// propExport encapsulates prop.Export to allow for testing.
+var propExport = func(conn dbusConn, path dbus.ObjectPath, props prop.Map) (propProperties, error) {
+	return prop.Export(conn.(*dbus.Conn), path, props)
+}
+
+// newClient is client.NewControlClient to allow for testing.
+var newClient = client.NewControlClient
+
+// newNotifier returns a new notifier, nil if notifications are not
+// available.
+var newNotifier = func() *notify.Notifier {
+	n, err := notify.NewNotifier()
+	if err != nil {
+		log.WithError(err).Error("Tray could not create notifier")
+		return nil
+	}
+	return n
+}
+
+// Tray is the system tray indicator.
+type Tray struct {
+	conn     dbusConn
+	props    propProperties
+	menu     *menu
+	notifier *notify.Notifier
+	signals  chan *dbus.Signal
+
+	// client and status updates of the agent
+	client  client.ControlClient
+	updates chan *status.Status
+	status  *status.Status
+
+	actions chan action
+	done    chan struct{}
+	closed  chan struct{}
+}
+
+// register registers the tray item at the StatusNotifierWatcher.
+func (t *Tray) register(name string) {
+	if err := t.conn.Object(watcherName, watcherPath).
+		Call(watcherRegister, 0, name).Err; err != nil {
+		log.WithError(err).Warn("Tray could not register at StatusNotifierWatcher, waiting for it")
+		return
+	}
+	log.Debug("Tray registered at StatusNotifierWatcher")
+}
+
+// handleSignal handles a D-Bus signal, it registers the tray item again if
+// the StatusNotifierWatcher is restarted.
+func (t *Tray) handleSignal(s *dbus.Signal, name string) {
+	if s.Name != "org.freedesktop.DBus.NameOwnerChanged" || len(s.Body) != 3 {
+		return
+	}
+	if n, ok := s.Body[0].(string); !ok || n != watcherName {
+		return
+	}
+	if owner, ok := s.Body[2].(string); ok && owner != "" {
+		t.register(name)
+	}
+}
+
+// setStatus sets the status shown in the tray.
+func (t *Tray) setStatus(s *status.Status) {
+	t.status = s
+
+	// update item
+	t.props.SetMust(itemInterface, "Status", itemStatus(s))
+	t.props.SetMust(itemInterface, "IconName", iconName(s))
+	t.props.SetMust(itemInterface, "AttentionIconName", iconName(s))
+	t.props.SetMust(itemInterface, "ToolTip", newToolTip(s))
+	for _, signal := range []string{"NewIcon", "NewAttentionIcon", "NewToolTip"} {
+		if err := t.conn.Emit(itemPath, itemInterface+"."+signal); err != nil {
+			log.WithError(err).Error("Tray could not emit D-Bus signal")
+		}
+	}
+	if err := t.conn.Emit(itemPath, itemInterface+".NewStatus", itemStatus(s)); err != nil {
+		log.WithError(err).Error("Tray could not emit D-Bus signal")
+	}
+
+	// update menu
+	revision := t.menu.setItems(menuItems(s))
+	if err := t.conn.Emit(menuPath, menuInterface+".LayoutUpdated", revision, int32(0)); err != nil {
+		log.WithError(err).Error("Tray could not emit D-Bus signal")
+	}
+}
+
+// connect connects to the agent and subscribes to status updates.
+func (t *Tray) connect() error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	updates, err := c.Subscribe()
+	if err != nil {
+		_ = c.Close()
+		return err
+	}
+	t.client = c
+	t.updates = updates
+	return nil
+}
+
+// disconnect closes the connection to the agent.
+func (t *Tray) disconnect() {
+	if t.client == nil {
+		return
+	}
+	_ = t.client.Close()
+	t.client = nil
+	t.updates = nil
+}
+
+// handleAction handles the user action a.
+func (t *Tray) handleAction(a action) {
+	if a == actionStatus {
+		t.notifier.Notify(title, statusText(t.status))
+		return
+	}
+	if t.client == nil {
+		t.notifier.Notify(title, i18n.String(i18n.NotifyAgentNotRunning))
+		return
+	}
+
+	var err error
+	switch a {
+	case actionReLogin:
+		err = t.client.ReLogin()
+	case actionLogout:
+		err = t.client.Logout()
+	case actionPause:
+		err = t.client.Pause()
+	case actionResume:
+		err = t.client.Resume()
+	default:
+		err = errors.New("unknown action")
+	}
+	if err != nil {
+		log.WithError(err).Error("Tray request to agent failed")
+		t.notifier.Notify(title, i18n.Sprintf(i18n.NotifyRequestFailed, err))
+	}
+}
+
+// start starts the tray's main loop.
+func (t *Tray) start(name string) {
+	defer close(t.closed)
+	defer func() { _ = t.conn.Close() }()
+	defer t.notifier.Close()
+	defer t.disconnect()
+
+	retry := time.After(0)
+	for {
+		select {
+		case <-retry:
+			retry = nil
+			if err := t.connect(); err != nil {
+				log.WithError(err).Debug("Tray could not connect to agent, retrying")
+				retry = time.After(retryInterval)
+			}
+
+		case s, ok := <-t.updates:
+			if !ok {
+				// connection to agent lost
+				t.disconnect()
+				t.setStatus(status.New())
+				retry = time.After(retryInterval)
+				break
+			}
+			t.setStatus(s)
+
+		case a := <-t.actions:
+			t.handleAction(a)
+
+		case s := <-t.signals:
+			t.handleSignal(s, name)
+
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// Start starts the tray.
+func (t *Tray) Start() error {
+	// connect to session bus
+	conn, err := dbusConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("could not connect to D-Bus session bus: %w", err)
+	}
+	t.conn = conn
+
+	// request name
+	name := fmt.Sprintf("org.kde.StatusNotifierItem-%d-1", os.Getpid())
+	reply, err := conn.RequestName(name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("could not request D-Bus name: %w", err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("requested D-Bus name is already taken")
+	}
+
+	// item methods and properties
+	i := item{t.actions, t.done}
+	if err := conn.Export(i, itemPath, itemInterface); err != nil {
+		return fmt.Errorf("could not export D-Bus item methods: %w", err)
+	}
+	props, err := propExport(conn, itemPath, itemProperties(t.status))
+	if err != nil {
+		return fmt.Errorf("could not export D-Bus item properties: %w", err)
+	}
+	t.props = props
+
+	// menu methods and properties
+	if err := conn.Export(t.menu, menuPath, menuInterface); err != nil {
+		return fmt.Errorf("could not export D-Bus menu methods: %w", err)
+	}
+	menuProps, err := propExport(conn, menuPath, menuProperties())
+	if err != nil {
+		return fmt.Errorf("could not export D-Bus menu properties: %w", err)
+	}
+
+	// introspection
+	for _, n := range []*introspect.Node{
+		{
+			Name: string(itemPath),
+			Interfaces: []introspect.Interface{
+				introspect.IntrospectData,
+				prop.IntrospectData,
+				{
+					Name:       itemInterface,
+					Methods:    introspect.Methods(i),
+					Signals:    itemSignals,
+					Properties: props.Introspection(itemInterface),
+				},
+			},
+		},
+		{
+			Name: string(menuPath),
+			Interfaces: []introspect.Interface{
+				introspect.IntrospectData,
+				prop.IntrospectData,
+				{
+					Name:       menuInterface,
+					Methods:    introspect.Methods(t.menu),
+					Signals:    menuSignals,
+					Properties: menuProps.Introspection(menuInterface),
+				},
+			},
+		},
+	} {
+		if err := conn.Export(introspect.NewIntrospectable(n), dbus.ObjectPath(n.Name),
+			"org.freedesktop.DBus.Introspectable"); err != nil {
+			return fmt.Errorf("could not export D-Bus introspection: %w", err)
+		}
+	}
+
+	// watch for restarts of the StatusNotifierWatcher
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchInterface("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, watcherName),
+	); err != nil {
+		return fmt.Errorf("could not watch StatusNotifierWatcher: %w", err)
+	}
+	conn.Signal(t.signals)
+
+	t.setStatus(t.status)
+	t.register(name)
+	t.notifier = newNotifier()
+
+	go t.start(name)
+	return nil
+}
+
+// Stop stops the tray.
+func (t *Tray) Stop() {
+	close(t.done)
+	<-t.closed
+}
+
+// NewTray returns a new Tray.
+func NewTray() *Tray {
+	actions := make(chan action)
+	done := make(chan struct{})
+	return &Tray{
+		menu:    newMenu(actions, done),
+		signals: make(chan *dbus.Signal, 10),
+		status:  status.New(),
+		actions: actions,
+		done:    done,
+		closed:  make(chan struct{}),
+	}
+}
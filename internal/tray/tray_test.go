@@ -0,0 +1,386 @@
+package tray
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/telekom-mms/fw-id-agent/internal/notify"
+	"github.com/telekom-mms/fw-id-agent/pkg/client"
+	"github.com/telekom-mms/fw-id-agent/pkg/history"
+	"github.com/telekom-mms/fw-id-agent/pkg/status"
+)
+
+// testObject is a D-Bus object for testing.
+type testObject struct {
+	dbus.Object
+	conn *testConn
+}
+
+func (to *testObject) Call(method string, _ dbus.Flags, _ ...any) *dbus.Call {
+	to.conn.mutex.Lock()
+	defer to.conn.mutex.Unlock()
+	to.conn.calls = append(to.conn.calls, method)
+	return &dbus.Call{Err: to.conn.callErr}
+}
+
+// testConn implements the dbusConn interface for testing.
+type testConn struct {
+	mutex   sync.Mutex
+	calls   []string
+	signals []string
+	callErr error
+	err     error
+}
+
+func (tc *testConn) Close() error {
+	return nil
+}
+
+func (tc *testConn) Export(any, dbus.ObjectPath, string) error {
+	return tc.err
+}
+
+func (tc *testConn) RequestName(string, dbus.RequestNameFlags) (dbus.RequestNameReply, error) {
+	return dbus.RequestNameReplyPrimaryOwner, nil
+}
+
+func (tc *testConn) Emit(_ dbus.ObjectPath, name string, _ ...any) error {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	tc.signals = append(tc.signals, name)
+	return nil
+}
+
+func (tc *testConn) Object(string, dbus.ObjectPath) dbus.BusObject {
+	return &testObject{conn: tc}
+}
+
+func (tc *testConn) AddMatchSignal(...dbus.MatchOption) error {
+	return nil
+}
+
+func (tc *testConn) Signal(chan<- *dbus.Signal) {}
+
+// getCalls returns the method calls on the connection.
+func (tc *testConn) getCalls() []string {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	return append([]string{}, tc.calls...)
+}
+
+// testProperties implements the propProperties interface for testing.
+type testProperties struct {
+	mutex sync.Mutex
+	props map[string]any
+}
+
+func (tp *testProperties) Introspection(string) []introspect.Property {
+	return nil
+}
+
+func (tp *testProperties) SetMust(_, property string, v any) {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+	tp.props[property] = v
+}
+
+// get returns the value of property.
+func (tp *testProperties) get(property string) any {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+	return tp.props[property]
+}
+
+// testClient is a Client for testing.
+type testClient struct {
+	sub    chan *status.Status
+	err    error
+	called string
+}
+
+func (t *testClient) Ping() error                             { return t.err }
+func (t *testClient) Query() (*status.Status, error)          { return nil, t.err }
+func (t *testClient) Subscribe() (chan *status.Status, error) { return t.sub, t.err }
+func (t *testClient) ReLogin() error                          { t.called = "ReLogin"; return t.err }
+func (t *testClient) Logout() error                           { t.called = "Logout"; return t.err }
+func (t *testClient) Pause() error                            { t.called = "Pause"; return t.err }
+func (t *testClient) Resume() error                           { t.called = "Resume"; return t.err }
+func (t *testClient) Close() error                            { return nil }
+
+func (t *testClient) GetHistory(time.Time) ([]*history.Event, error) {
+	return nil, t.err
+}
+
+func (t *testClient) CollectDiagnostics() ([]byte, error) {
+	return nil, t.err
+}
+
+func (t *testClient) SetLogLevel(string, time.Duration) error {
+	return t.err
+}
+
+// TestMenuItems tests menuItems.
+func TestMenuItems(t *testing.T) {
+	labels := func(items []*menuItem) (l []string) {
+		for _, i := range items {
+			if i.separator {
+				l = append(l, "-")
+				continue
+			}
+			if i.enabled {
+				l = append(l, i.label)
+				continue
+			}
+			l = append(l, "("+i.label+")")
+		}
+		return
+	}
+
+	// test agent not running
+	s := status.New()
+	want := []string{"(Re-login)", "(Logout)", "(Pause)", "-", "Status"}
+	if got := labels(menuItems(s)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test logged in
+	s.AgentState = status.AgentStateLoggedIn
+	s.TrustedNetwork = status.TrustedNetworkTrusted
+	s.LoginState = status.LoginStateLoggedIn
+	want = []string{"Re-login", "Logout", "Pause", "-", "Status"}
+	if got := labels(menuItems(s)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test paused
+	s.AgentState = status.AgentStatePaused
+	s.LoginState = status.LoginStateLoggedOut
+	want = []string{"(Re-login)", "(Logout)", "Resume", "-", "Status"}
+	if got := labels(menuItems(s)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestTrayRegister tests register of Tray.
+func TestTrayRegister(t *testing.T) {
+	// test success
+	conn := &testConn{}
+	tr := NewTray()
+	tr.conn = conn
+	tr.register("test")
+
+	// test error
+	conn.callErr = errors.New("test error")
+	tr.register("test")
+
+	want := []string{watcherRegister, watcherRegister}
+	if got := conn.getCalls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestTrayHandleSignal tests handleSignal of Tray.
+func TestTrayHandleSignal(t *testing.T) {
+	conn := &testConn{}
+	tr := NewTray()
+	tr.conn = conn
+
+	// test ignored signals
+	for _, s := range []*dbus.Signal{
+		{Name: "org.test.Signal"},
+		{Name: "org.freedesktop.DBus.NameOwnerChanged", Body: []any{"org.test"}},
+		{Name: "org.freedesktop.DBus.NameOwnerChanged", Body: []any{"org.test", "", ":1.2"}},
+		{Name: "org.freedesktop.DBus.NameOwnerChanged", Body: []any{watcherName, ":1.1", ""}},
+	} {
+		tr.handleSignal(s, "test")
+	}
+	if got := conn.getCalls(); len(got) != 0 {
+		t.Errorf("got %v, want no calls", got)
+	}
+
+	// test restarted watcher
+	tr.handleSignal(&dbus.Signal{
+		Name: "org.freedesktop.DBus.NameOwnerChanged",
+		Body: []any{watcherName, "", ":1.2"},
+	}, "test")
+	want := []string{watcherRegister}
+	if got := conn.getCalls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestTraySetStatus tests setStatus of Tray.
+func TestTraySetStatus(t *testing.T) {
+	conn := &testConn{}
+	props := &testProperties{props: make(map[string]any)}
+	tr := NewTray()
+	tr.conn = conn
+	tr.props = props
+
+	s := status.New()
+	s.AgentState = status.AgentStateLoggedIn
+	s.LoginState = status.LoginStateLoggedIn
+	tr.setStatus(s)
+
+	if tr.status != s {
+		t.Error("status not set")
+	}
+	if got := props.get("IconName"); got != iconLoggedIn {
+		t.Errorf("got %v, want %s", got, iconLoggedIn)
+	}
+	if got := props.get("Status"); got != itemStatusActive {
+		t.Errorf("got %v, want %s", got, itemStatusActive)
+	}
+	want := []string{
+		itemInterface + ".NewIcon",
+		itemInterface + ".NewAttentionIcon",
+		itemInterface + ".NewToolTip",
+		itemInterface + ".NewStatus",
+		menuInterface + ".LayoutUpdated",
+	}
+	if !reflect.DeepEqual(conn.signals, want) {
+		t.Errorf("got %v, want %v", conn.signals, want)
+	}
+	if len(tr.menu.items) != 5 {
+		t.Errorf("got %d menu items, want 5", len(tr.menu.items))
+	}
+}
+
+// TestTrayConnect tests connect and disconnect of Tray.
+func TestTrayConnect(t *testing.T) {
+	defer func() { newClient = client.NewControlClient }()
+
+	// test client error
+	newClient = func() (client.ControlClient, error) {
+		return nil, errors.New("test error")
+	}
+	tr := NewTray()
+	if err := tr.connect(); err == nil {
+		t.Error("connect should return error")
+	}
+
+	// test subscribe error
+	c := &testClient{err: errors.New("test error")}
+	newClient = func() (client.ControlClient, error) {
+		return c, nil
+	}
+	if err := tr.connect(); err == nil {
+		t.Error("connect should return error")
+	}
+
+	// test success
+	c = &testClient{sub: make(chan *status.Status)}
+	if err := tr.connect(); err != nil {
+		t.Fatal(err)
+	}
+	if tr.client != c || tr.updates != c.sub {
+		t.Error("client not set")
+	}
+	tr.disconnect()
+	if tr.client != nil || tr.updates != nil {
+		t.Error("client not reset")
+	}
+
+	// test disconnect without client
+	tr.disconnect()
+}
+
+// TestTrayHandleAction tests handleAction of Tray.
+func TestTrayHandleAction(t *testing.T) {
+	tr := NewTray()
+
+	// test without notifier and client
+	tr.handleAction(actionStatus)
+	tr.handleAction(actionReLogin)
+
+	// test with client
+	c := &testClient{}
+	tr.client = c
+	for a, want := range map[action]string{
+		actionReLogin: "ReLogin",
+		actionLogout:  "Logout",
+		actionPause:   "Pause",
+		actionResume:  "Resume",
+		actionStatus:  "",
+	} {
+		c.called = ""
+		tr.handleAction(a)
+		if c.called != want {
+			t.Errorf("got %s, want %s", c.called, want)
+		}
+	}
+
+	// test errors
+	c.err = errors.New("test error")
+	tr.handleAction(actionLogout)
+	tr.handleAction(action(-1))
+}
+
+// TestTrayStartStop tests Start and Stop of Tray.
+func TestTrayStartStop(t *testing.T) {
+	defer func() { newClient = client.NewControlClient }()
+	defer func(d time.Duration) { retryInterval = d }(retryInterval)
+
+	conn := &testConn{}
+	dbusConnectSessionBus = func() (dbusConn, error) {
+		return conn, nil
+	}
+	props := &testProperties{props: make(map[string]any)}
+	propExport = func(dbusConn, dbus.ObjectPath, prop.Map) (propProperties, error) {
+		return props, nil
+	}
+	newNotifier = func() *notify.Notifier {
+		return nil
+	}
+	c := &testClient{sub: make(chan *status.Status)}
+	connected := make(chan struct{})
+	newClient = func() (client.ControlClient, error) {
+		defer close(connected)
+		return c, nil
+	}
+
+	tr := NewTray()
+	if err := tr.Start(); err != nil {
+		t.Fatal(err)
+	}
+	<-connected
+
+	// test status update
+	s := status.New()
+	s.AgentState = status.AgentStateUntrusted
+	c.sub <- s
+
+	// test lost connection, status is reset
+	retryInterval = time.Hour
+	close(c.sub)
+	for i := 0; props.get("IconName") != iconNotRunning; i++ {
+		if i == 100 {
+			t.Fatal("status not reset after lost connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// test action
+	tr.actions <- actionStatus
+	tr.Stop()
+
+	// test export error
+	conn.err = errors.New("test error")
+	if err := NewTray().Start(); err == nil {
+		t.Error("start should return error")
+	}
+
+	// test connect error
+	dbusConnectSessionBus = func() (dbusConn, error) {
+		return nil, errors.New("test error")
+	}
+	if err := NewTray().Start(); err == nil {
+		t.Error("start should return error")
+	}
+}
@@ -4,10 +4,12 @@ package client
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/telekom-mms/fw-id-agent/internal/dbusapi"
 	"github.com/telekom-mms/fw-id-agent/pkg/config"
+	"github.com/telekom-mms/fw-id-agent/pkg/history"
 	"github.com/telekom-mms/fw-id-agent/pkg/status"
 )
 
@@ -20,6 +22,19 @@ type Client interface {
 	Close() error
 }
 
+// ControlClient is a FW-ID-Agent client that also controls the agent and
+// gets its history and diagnostics. It extends Client, so existing
+// implementations of Client are not affected.
+type ControlClient interface {
+	Client
+	Logout() error
+	Pause() error
+	Resume() error
+	GetHistory(since time.Time) ([]*history.Event, error)
+	SetLogLevel(level string, duration time.Duration) error
+	CollectDiagnostics() ([]byte, error)
+}
+
 // DBusClient is a FW-ID-Agent client that uses the D-Bus API of FW-ID-Agent.
 type DBusClient struct {
 	mutex sync.Mutex
@@ -68,16 +83,28 @@ func updateStatusFromProperties(s *status.Status, props map[string]dbus.Variant)
 					}
 					dest.Config = c
 				}
+			case dbusapi.PropertyConfigSignature:
+				err = v.Store(&dest.ConfigSignature)
+			case dbusapi.PropertyAgentState:
+				err = v.Store(&dest.AgentState)
 			case dbusapi.PropertyTrustedNetwork:
 				err = v.Store(&dest.TrustedNetwork)
 			case dbusapi.PropertyLoginState:
 				err = v.Store(&dest.LoginState)
+			case dbusapi.PropertySourceIP:
+				err = v.Store(&dest.SourceIP)
+			case dbusapi.PropertyLastLoginAt:
+				err = v.Store(&dest.LastLogin)
 			case dbusapi.PropertyLastKeepAliveAt:
 				err = v.Store(&dest.LastKeepAlive)
 			case dbusapi.PropertyKerberosTGTStartTime:
 				err = v.Store(&dest.KerberosTGT.StartTime)
 			case dbusapi.PropertyKerberosTGTEndTime:
 				err = v.Store(&dest.KerberosTGT.EndTime)
+			case dbusapi.PropertyLogLevel:
+				err = v.Store(&dest.LogLevel)
+			case dbusapi.PropertyLoginFailures:
+				err = v.Store(&dest.LoginFailures)
 			}
 			if err != nil {
 				return err
@@ -165,16 +192,28 @@ func handlePropertiesChanged(s *dbus.Signal, stat *status.Status) *status.Status
 		switch name {
 		case dbusapi.PropertyConfig:
 			stat.Config = nil
+		case dbusapi.PropertyConfigSignature:
+			stat.ConfigSignature = config.SignatureUnknown
+		case dbusapi.PropertyAgentState:
+			stat.AgentState = status.AgentStateUnknown
 		case dbusapi.PropertyTrustedNetwork:
 			stat.TrustedNetwork = status.TrustedNetworkUnknown
 		case dbusapi.PropertyLoginState:
 			stat.LoginState = status.LoginStateUnknown
+		case dbusapi.PropertySourceIP:
+			stat.SourceIP = dbusapi.SourceIPInvalid
+		case dbusapi.PropertyLastLoginAt:
+			stat.LastLogin = dbusapi.LastLoginAtInvalid
 		case dbusapi.PropertyLastKeepAliveAt:
 			stat.LastKeepAlive = dbusapi.LastKeepAliveAtInvalid
 		case dbusapi.PropertyKerberosTGTStartTime:
 			stat.KerberosTGT.StartTime = dbusapi.KerberosTGTStartTimeInvalid
 		case dbusapi.PropertyKerberosTGTEndTime:
 			stat.KerberosTGT.EndTime = dbusapi.KerberosTGTEndTimeInvalid
+		case dbusapi.PropertyLogLevel:
+			stat.LogLevel = dbusapi.LogLevelInvalid
+		case dbusapi.PropertyLoginFailures:
+			stat.LoginFailures = dbusapi.LoginFailuresInvalid
 		}
 	}
 
@@ -289,6 +328,88 @@ func (d *DBusClient) ReLogin() error {
 	return relogin(d)
 }
 
+// logout sends a logout request to the agent.
+var logout = func(d *DBusClient) error {
+	return d.conn.Object(dbusapi.Interface, dbusapi.Path).
+		Call(dbusapi.MethodLogout, 0).Store()
+}
+
+// Logout sends a logout request to the agent.
+func (d *DBusClient) Logout() error {
+	return logout(d)
+}
+
+// pause sends a pause request to the agent.
+var pause = func(d *DBusClient) error {
+	return d.conn.Object(dbusapi.Interface, dbusapi.Path).
+		Call(dbusapi.MethodPause, 0).Store()
+}
+
+// Pause sends a pause request to the agent.
+func (d *DBusClient) Pause() error {
+	return pause(d)
+}
+
+// resume sends a resume request to the agent.
+var resume = func(d *DBusClient) error {
+	return d.conn.Object(dbusapi.Interface, dbusapi.Path).
+		Call(dbusapi.MethodResume, 0).Store()
+}
+
+// Resume sends a resume request to the agent.
+func (d *DBusClient) Resume() error {
+	return resume(d)
+}
+
+// getHistory gets the event history as JSON from the agent.
+var getHistory = func(d *DBusClient, since int64) (string, error) {
+	events := ""
+	err := d.conn.Object(dbusapi.Interface, dbusapi.Path).
+		Call(dbusapi.MethodGetHistory, 0, since).Store(&events)
+	return events, err
+}
+
+// GetHistory returns the events in the agent's event history that occurred
+// after since. If since is the zero time, all events are returned.
+func (d *DBusClient) GetHistory(since time.Time) ([]*history.Event, error) {
+	s := int64(0)
+	if !since.IsZero() {
+		s = since.Unix()
+	}
+	events, err := getHistory(d, s)
+	if err != nil {
+		return nil, err
+	}
+	return history.NewFromJSON([]byte(events))
+}
+
+// setLogLevel sends a set log level request to the agent.
+var setLogLevel = func(d *DBusClient, level string, duration int64) error {
+	return d.conn.Object(dbusapi.Interface, dbusapi.Path).
+		Call(dbusapi.MethodSetLogLevel, 0, level, duration).Store()
+}
+
+// SetLogLevel sets the log level of the agent to level for duration. If
+// duration is 0, the log level is kept until it is reset. An empty level
+// resets the log level to the configured log level.
+func (d *DBusClient) SetLogLevel(level string, duration time.Duration) error {
+	return setLogLevel(d, level, int64(duration/time.Second))
+}
+
+// collectDiagnostics gets the support bundle from the agent.
+var collectDiagnostics = func(d *DBusClient) ([]byte, error) {
+	bundle := []byte{}
+	err := d.conn.Object(dbusapi.Interface, dbusapi.Path).
+		Call(dbusapi.MethodCollectDiagnostics, 0).Store(&bundle)
+	return bundle, err
+}
+
+// CollectDiagnostics returns the support bundle of the agent as tar.gz
+// archive.
+func (d *DBusClient) CollectDiagnostics() ([]byte, error) {
+	return collectDiagnostics(d)
+}
+
 // Close closes the DBusClient.
 func (d *DBusClient) Close() error {
 	var err error
@@ -328,3 +449,8 @@ func NewDBusClient() (*DBusClient, error) {
 func NewClient() (Client, error) {
 	return NewDBusClient()
 }
+
+// NewControlClient returns a new ControlClient.
+func NewControlClient() (ControlClient, error) {
+	return NewDBusClient()
+}
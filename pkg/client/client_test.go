@@ -1,12 +1,15 @@
 package client
 
 import (
+	"bytes"
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/telekom-mms/fw-id-agent/internal/dbusapi"
+	"github.com/telekom-mms/fw-id-agent/pkg/history"
 	"github.com/telekom-mms/fw-id-agent/pkg/status"
 )
 
@@ -44,11 +47,17 @@ func TestUpdateStatusFromProperties(t *testing.T) {
 	for _, invalid := range []map[string]dbus.Variant{
 		{dbusapi.PropertyConfig: dbus.MakeVariant("invalid")},
 		{dbusapi.PropertyConfig: dbus.MakeVariant(0.123)},
+		{dbusapi.PropertyConfigSignature: dbus.MakeVariant("invalid")},
+		{dbusapi.PropertyAgentState: dbus.MakeVariant("invalid")},
 		{dbusapi.PropertyTrustedNetwork: dbus.MakeVariant("invalid")},
 		{dbusapi.PropertyLoginState: dbus.MakeVariant("invalid")},
+		{dbusapi.PropertySourceIP: dbus.MakeVariant(0.123)},
+		{dbusapi.PropertyLastLoginAt: dbus.MakeVariant("invalid")},
 		{dbusapi.PropertyLastKeepAliveAt: dbus.MakeVariant("invalid")},
 		{dbusapi.PropertyKerberosTGTStartTime: dbus.MakeVariant("invalid")},
 		{dbusapi.PropertyKerberosTGTEndTime: dbus.MakeVariant("invalid")},
+		{dbusapi.PropertyLogLevel: dbus.MakeVariant(0.123)},
+		{dbusapi.PropertyLoginFailures: dbus.MakeVariant("invalid")},
 	} {
 		s := status.New()
 		err := updateStatusFromProperties(s, invalid)
@@ -62,11 +71,20 @@ func TestUpdateStatusFromProperties(t *testing.T) {
 		{},
 		{dbusapi.PropertyConfig: dbus.MakeVariant(dbusapi.ConfigInvalid)},
 		{dbusapi.PropertyConfig: dbus.MakeVariant("{}")},
+		{dbusapi.PropertyConfigSignature: dbus.MakeVariant(dbusapi.ConfigSignatureUnknown)},
+		{dbusapi.PropertyAgentState: dbus.MakeVariant(dbusapi.AgentStateUnknown)},
 		{dbusapi.PropertyTrustedNetwork: dbus.MakeVariant(dbusapi.TrustedNetworkUnknown)},
 		{dbusapi.PropertyLoginState: dbus.MakeVariant(dbusapi.LoginStateUnknown)},
+		{dbusapi.PropertySourceIP: dbus.MakeVariant(dbusapi.SourceIPInvalid)},
+		{dbusapi.PropertySourceIP: dbus.MakeVariant("192.168.1.10")},
+		{dbusapi.PropertyLastLoginAt: dbus.MakeVariant(dbusapi.LastLoginAtInvalid)},
 		{dbusapi.PropertyLastKeepAliveAt: dbus.MakeVariant(dbusapi.LastKeepAliveAtInvalid)},
 		{dbusapi.PropertyKerberosTGTStartTime: dbus.MakeVariant(dbusapi.KerberosTGTStartTimeInvalid)},
 		{dbusapi.PropertyKerberosTGTEndTime: dbus.MakeVariant(dbusapi.KerberosTGTEndTimeInvalid)},
+		{dbusapi.PropertyLogLevel: dbus.MakeVariant(dbusapi.LogLevelInvalid)},
+		{dbusapi.PropertyLogLevel: dbus.MakeVariant("debug")},
+		{dbusapi.PropertyLoginFailures: dbus.MakeVariant(dbusapi.LoginFailuresInvalid)},
+		{dbusapi.PropertyLoginFailures: dbus.MakeVariant(uint32(3))},
 	} {
 		s := status.New()
 		err := updateStatusFromProperties(s, valid)
@@ -162,18 +180,30 @@ func TestHandlePropertiesChanged(t *testing.T) {
 		Name: "org.freedesktop.DBus.Properties.PropertiesChanged",
 		Body: []any{dbusapi.Interface, map[string]dbus.Variant{
 			dbusapi.PropertyConfig:               dbus.MakeVariant(dbusapi.ConfigInvalid),
+			dbusapi.PropertyConfigSignature:      dbus.MakeVariant(dbusapi.ConfigSignatureUnknown),
+			dbusapi.PropertyAgentState:           dbus.MakeVariant(dbusapi.AgentStateUnknown),
 			dbusapi.PropertyTrustedNetwork:       dbus.MakeVariant(dbusapi.TrustedNetworkUnknown),
 			dbusapi.PropertyLoginState:           dbus.MakeVariant(dbusapi.LoginStateUnknown),
+			dbusapi.PropertySourceIP:             dbus.MakeVariant(dbusapi.SourceIPInvalid),
+			dbusapi.PropertyLastLoginAt:          dbus.MakeVariant(dbusapi.LastLoginAtInvalid),
 			dbusapi.PropertyLastKeepAliveAt:      dbus.MakeVariant(dbusapi.LastKeepAliveAtInvalid),
 			dbusapi.PropertyKerberosTGTStartTime: dbus.MakeVariant(dbusapi.KerberosTGTStartTimeInvalid),
 			dbusapi.PropertyKerberosTGTEndTime:   dbus.MakeVariant(dbusapi.KerberosTGTEndTimeInvalid),
+			dbusapi.PropertyLogLevel:             dbus.MakeVariant(dbusapi.LogLevelInvalid),
+			dbusapi.PropertyLoginFailures:        dbus.MakeVariant(dbusapi.LoginFailuresInvalid),
 		}, []string{
 			dbusapi.PropertyConfig,
+			dbusapi.PropertyConfigSignature,
+			dbusapi.PropertyAgentState,
 			dbusapi.PropertyTrustedNetwork,
 			dbusapi.PropertyLoginState,
+			dbusapi.PropertySourceIP,
+			dbusapi.PropertyLastLoginAt,
 			dbusapi.PropertyLastKeepAliveAt,
 			dbusapi.PropertyKerberosTGTStartTime,
 			dbusapi.PropertyKerberosTGTEndTime,
+			dbusapi.PropertyLogLevel,
+			dbusapi.PropertyLoginFailures,
 		}},
 	}
 	if handlePropertiesChanged(valid, status.New()) == nil {
@@ -336,6 +366,138 @@ func TestDBusClientReLogin(t *testing.T) {
 	}
 }
 
+// TestDBusClientLogoutPauseResume tests Logout, Pause and Resume of
+// DBusClient.
+func TestDBusClientLogoutPauseResume(t *testing.T) {
+	// clean up after tests
+	oldLogout, oldPause, oldResume := logout, pause, resume
+	defer func() {
+		logout, pause, resume = oldLogout, oldPause, oldResume
+	}()
+
+	client := &DBusClient{}
+	for _, test := range []struct {
+		name   string
+		set    *func(*DBusClient) error
+		method func() error
+	}{
+		{"logout", &logout, client.Logout},
+		{"pause", &pause, client.Pause},
+		{"resume", &resume, client.Resume},
+	} {
+		// test with no error
+		*test.set = func(*DBusClient) error { return nil }
+		if err := test.method(); err != nil {
+			t.Errorf("%s returned error %v", test.name, err)
+		}
+
+		// test with error
+		*test.set = func(*DBusClient) error { return errors.New("test error") }
+		if err := test.method(); err == nil {
+			t.Errorf("%s should return error", test.name)
+		}
+	}
+}
+
+// TestDBusClientSetLogLevel tests SetLogLevel of DBusClient.
+func TestDBusClientSetLogLevel(t *testing.T) {
+	// clean up after tests
+	oldSetLogLevel := setLogLevel
+	defer func() {
+		setLogLevel = oldSetLogLevel
+	}()
+
+	// test with no error
+	client := &DBusClient{}
+	setLogLevel = func(_ *DBusClient, level string, duration int64) error {
+		if level != "debug" || duration != 600 {
+			t.Errorf("got %s, %d, want debug, 600", level, duration)
+		}
+		return nil
+	}
+	if err := client.SetLogLevel("debug", 10*time.Minute); err != nil {
+		t.Errorf("set log level returned error %v", err)
+	}
+
+	// test with error
+	setLogLevel = func(*DBusClient, string, int64) error {
+		return errors.New("test error")
+	}
+	if err := client.SetLogLevel("", 0); err == nil {
+		t.Error("set log level should return error")
+	}
+}
+
+// TestDBusClientCollectDiagnostics tests CollectDiagnostics of DBusClient.
+func TestDBusClientCollectDiagnostics(t *testing.T) {
+	// clean up after tests
+	oldCollectDiagnostics := collectDiagnostics
+	defer func() {
+		collectDiagnostics = oldCollectDiagnostics
+	}()
+
+	// test with no error
+	client := &DBusClient{}
+	want := []byte("bundle")
+	collectDiagnostics = func(*DBusClient) ([]byte, error) {
+		return want, nil
+	}
+	got, err := client.CollectDiagnostics()
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("got %s, %v, want %s", got, err, want)
+	}
+
+	// test with error
+	collectDiagnostics = func(*DBusClient) ([]byte, error) {
+		return nil, errors.New("test error")
+	}
+	if _, err := client.CollectDiagnostics(); err == nil {
+		t.Error("collect diagnostics should return error")
+	}
+}
+
+// TestDBusClientGetHistory tests GetHistory of DBusClient.
+func TestDBusClientGetHistory(t *testing.T) {
+	// clean up after tests
+	oldGetHistory := getHistory
+	defer func() {
+		getHistory = oldGetHistory
+	}()
+
+	// test with error
+	client := &DBusClient{}
+	getHistory = func(*DBusClient, int64) (string, error) {
+		return "", errors.New("test error")
+	}
+	if _, err := client.GetHistory(time.Time{}); err == nil {
+		t.Error("get history should return error")
+	}
+
+	// test with invalid history
+	getHistory = func(*DBusClient, int64) (string, error) {
+		return "invalid", nil
+	}
+	if _, err := client.GetHistory(time.Time{}); err == nil {
+		t.Error("get history should return error")
+	}
+
+	// test with valid history
+	want := []*history.Event{
+		{Time: time.Unix(2, 0).UTC(), Type: history.TypeLogin, Message: "test"},
+	}
+	getHistory = func(_ *DBusClient, since int64) (string, error) {
+		if since != 1 {
+			t.Errorf("got since %d, want 1", since)
+		}
+		b, _ := history.JSON(want)
+		return string(b), nil
+	}
+	got, err := client.GetHistory(time.Unix(1, 0))
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, %v, want %v", got, err, want)
+	}
+}
+
 // testRWC is a reader writer closer for testing.
 type testRWC struct{}
 
@@ -414,3 +576,21 @@ func TestNewClient(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// TestNewControlClient tests NewControlClient.
+func TestNewControlClient(t *testing.T) {
+	// clean up after tests
+	defer func() { dbusConnectSessionBus = dbus.ConnectSessionBus }()
+
+	// test without errors
+	dbusConnectSessionBus = func(...dbus.ConnOption) (*dbus.Conn, error) {
+		return nil, nil
+	}
+	client, err := NewControlClient()
+	if err != nil {
+		t.Error(err)
+	}
+	if err := client.Close(); err != nil {
+		t.Error(err)
+	}
+}
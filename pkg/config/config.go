@@ -3,7 +3,10 @@ package config
 
 import (
 	"encoding/json"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/telekom-mms/tnd/pkg/tnd"
@@ -54,6 +57,105 @@ func (t *TNDConfig) Valid() bool {
 	return t.Config.Valid()
 }
 
+// Notification urgency levels.
+const (
+	UrgencyLow      = "low"
+	UrgencyNormal   = "normal"
+	UrgencyCritical = "critical"
+)
+
+// NotificationEvent is the desktop notification configuration of an agent
+// event. Empty values use the defaults of the event.
+type NotificationEvent struct {
+	// Disabled disables the notifications of the event.
+	Disabled bool
+	// Urgency is the urgency level, see the urgency constants.
+	Urgency string
+	// Icon is the icon name or file path.
+	Icon string
+	// Timeout is the time after which the notification expires in
+	// seconds. If it is 0, the notification server's default is used, if
+	// it is negative, the notification never expires.
+	Timeout int
+	// Category is the notification category, e.g., "network.connected".
+	Category string
+}
+
+// Valid returns whether NotificationEvent is valid.
+func (n *NotificationEvent) Valid() bool {
+	switch n.Urgency {
+	case "", UrgencyLow, UrgencyNormal, UrgencyCritical:
+		return true
+	}
+	return false
+}
+
+// NotificationSettings is the desktop notification configuration of the
+// agent events.
+type NotificationSettings struct {
+	// RateLimit is the minimum time between notifications about the
+	// trusted network or the login state in seconds. Changes in between
+	// are merged into one notification. 0 disables the rate limit.
+	RateLimit int
+
+	TrustedNetwork   NotificationEvent
+	UntrustedNetwork NotificationEvent
+	Login            NotificationEvent
+	Logout           NotificationEvent
+	LoginFailed      NotificationEvent
+}
+
+// GetRateLimit returns the rate limit as Duration.
+func (n *NotificationSettings) GetRateLimit() time.Duration {
+	return time.Duration(n.RateLimit) * time.Second
+}
+
+// Valid returns whether NotificationSettings is valid.
+func (n *NotificationSettings) Valid() bool {
+	if n.RateLimit < 0 {
+		return false
+	}
+	for _, e := range []NotificationEvent{
+		n.TrustedNetwork,
+		n.UntrustedNetwork,
+		n.Login,
+		n.Logout,
+		n.LoginFailed,
+	} {
+		if !e.Valid() {
+			return false
+		}
+	}
+	return true
+}
+
+// Session policies.
+const (
+	// SessionPolicyKeep keeps the user logged in when the session is
+	// locked or inactive.
+	SessionPolicyKeep = "keep"
+
+	// SessionPolicyLogoutOnLock logs the user out when the session is
+	// locked for longer than the lock grace period.
+	SessionPolicyLogoutOnLock = "logout-on-lock"
+
+	// SessionPolicyLogoutOnInactive logs the user out when the session
+	// becomes inactive, e.g., when another user is switched to.
+	SessionPolicyLogoutOnInactive = "logout-on-inactive"
+)
+
+// Log formats.
+const (
+	// LogFormatText logs in logrus text format.
+	LogFormatText = "text"
+
+	// LogFormatJSON logs one JSON object per line.
+	LogFormatJSON = "json"
+
+	// LogFormatJournald logs with the native systemd journal protocol.
+	LogFormatJournald = "journald"
+)
+
 // Config is the agent configuration.
 type Config struct {
 	// ServiceURL is the URL used for requests to the service.
@@ -71,11 +173,44 @@ type Config struct {
 	// TND is the client's trusted network detection configuration.
 	TND TNDConfig
 	// Verbose specifies whether the client should show verbose output.
+	// It is only used if LogLevel is not set.
 	Verbose bool
+	// LogFormat is the log format, see the log format constants.
+	LogFormat string
+	// LogLevel is the log level, e.g., "debug" or "info". If it is empty,
+	// the log level is "debug" with Verbose and "info" otherwise.
+	LogLevel string
 	// StartDelay is the time the agent sleeps before starting in seconds.
 	StartDelay int
 	// Notifications specifies whether the agent should show desktop notifications.
 	Notifications bool
+	// KinitCommand is the command the agent runs with "/bin/sh -c" when
+	// the user invokes the "Run kinit" action of a notification, e.g., a
+	// graphical Kerberos login dialog. The action is not offered if it is
+	// empty.
+	KinitCommand string
+	// NotificationSettings are the settings of the desktop notifications
+	// of the agent events.
+	NotificationSettings NotificationSettings
+	// WakeDelay is the time the agent waits for the network to settle
+	// after wake-up before probing the trusted network in seconds.
+	WakeDelay int
+	// SessionPolicy is the policy for locked or inactive sessions, see
+	// the session policy constants.
+	SessionPolicy string
+	// LockGracePeriod is the time the agent waits after the session was
+	// locked before logging out in seconds.
+	LockGracePeriod int
+	// MetricsAddress is the local address the agent serves metrics on,
+	// either a unix socket path with prefix "unix:" or a loopback
+	// host:port. Metrics are not served if it is empty.
+	MetricsAddress string
+	// MetricsTextfileDir is the node_exporter textfile collector directory
+	// the agent writes metrics to. Metrics are not written if it is empty.
+	MetricsTextfileDir string
+
+	// signature is the signature verification status of the config file.
+	signature SignatureStatus
 }
 
 // Copy returns a copy of the configuration.
@@ -88,6 +223,12 @@ func (c *Config) Copy() *Config {
 	return &cp
 }
 
+// Signature returns the signature verification status of the config file the
+// configuration was loaded from.
+func (c *Config) Signature() SignatureStatus {
+	return c.signature
+}
+
 // GetKeepAlive returns the client keep-alive time as Duration.
 func (c *Config) GetKeepAlive() time.Duration {
 	return time.Duration(c.KeepAlive) * time.Minute
@@ -113,6 +254,74 @@ func (c *Config) GetStartDelay() time.Duration {
 	return time.Duration(c.StartDelay) * time.Second
 }
 
+// GetWakeDelay returns the agent wake-up delay as Duration.
+func (c *Config) GetWakeDelay() time.Duration {
+	return time.Duration(c.WakeDelay) * time.Second
+}
+
+// GetLockGracePeriod returns the agent lock grace period as Duration.
+func (c *Config) GetLockGracePeriod() time.Duration {
+	return time.Duration(c.LockGracePeriod) * time.Second
+}
+
+// GetLogLevel returns the log level based on LogLevel and Verbose.
+func (c *Config) GetLogLevel() string {
+	if c.LogLevel != "" {
+		return c.LogLevel
+	}
+	if c.Verbose {
+		return "debug"
+	}
+	return "info"
+}
+
+// validLogFormat returns whether the log format is valid.
+func (c *Config) validLogFormat() bool {
+	switch c.LogFormat {
+	case "", LogFormatText, LogFormatJSON, LogFormatJournald:
+		return true
+	}
+	return false
+}
+
+// validLogLevel returns whether the log level is valid.
+func (c *Config) validLogLevel() bool {
+	switch c.LogLevel {
+	case "", "trace", "debug", "info", "warn", "warning", "error":
+		return true
+	}
+	return false
+}
+
+// validSessionPolicy returns whether the session policy is valid.
+func (c *Config) validSessionPolicy() bool {
+	switch c.SessionPolicy {
+	case "", SessionPolicyKeep, SessionPolicyLogoutOnLock, SessionPolicyLogoutOnInactive:
+		return true
+	}
+	return false
+}
+
+// validMetricsAddress returns whether the metrics address is valid, i.e.,
+// empty, an absolute unix socket path or a loopback address.
+func (c *Config) validMetricsAddress() bool {
+	if c.MetricsAddress == "" {
+		return true
+	}
+	if path, ok := strings.CutPrefix(c.MetricsAddress, "unix:"); ok {
+		return filepath.IsAbs(path)
+	}
+	host, _, err := net.SplitHostPort(c.MetricsAddress)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 // Valid returns whether Config is valid.
 func (c *Config) Valid() bool {
 	if c == nil ||
@@ -123,7 +332,15 @@ func (c *Config) Valid() bool {
 		c.LogoutTimeout < 0 ||
 		c.RetryTimer < 0 ||
 		!c.TND.Valid() ||
-		c.StartDelay < 0 {
+		c.StartDelay < 0 ||
+		!c.NotificationSettings.Valid() ||
+		c.WakeDelay < 0 ||
+		!c.validLogFormat() ||
+		!c.validLogLevel() ||
+		!c.validSessionPolicy() ||
+		c.LockGracePeriod < 0 ||
+		!c.validMetricsAddress() ||
+		(c.MetricsTextfileDir != "" && !filepath.IsAbs(c.MetricsTextfileDir)) {
 		return false
 	}
 	return true
@@ -143,13 +360,19 @@ func (c *Config) String() string {
 // Default returns a new config with default values.
 func Default() *Config {
 	return &Config{
-		KeepAlive:     5,
-		LoginTimeout:  15,
-		LogoutTimeout: 5,
-		RetryTimer:    15,
-		TND:           TNDConfig{Config: tnd.NewConfig()},
-		StartDelay:    0,
-		Notifications: true,
+		KeepAlive:       5,
+		LoginTimeout:    15,
+		LogoutTimeout:   5,
+		RetryTimer:      15,
+		TND:             TNDConfig{Config: tnd.NewConfig()},
+		LogFormat:       LogFormatText,
+		StartDelay:      0,
+		Notifications:   true,
+		WakeDelay:       2,
+		SessionPolicy:   SessionPolicyKeep,
+		LockGracePeriod: 60,
+
+		NotificationSettings: NotificationSettings{RateLimit: 10},
 	}
 }
 
@@ -164,7 +387,11 @@ func NewFromJSON(b []byte) (*Config, error) {
 	return s, nil
 }
 
-// Load loads the json configuration from file path.
+// Load loads the configuration from file path. The file format is detected
+// from the file extension: ".yaml" and ".yml" files are parsed as YAML,
+// ".toml" files as TOML and all other files as JSON. If a public key for
+// config signatures is configured, the file is only loaded if it has a valid
+// detached signature, see Verify.
 func Load(path string) (*Config, error) {
 	// read file contents
 	file, err := os.ReadFile(path)
@@ -172,11 +399,18 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	// verify signature
+	signature, err := Verify(path, file)
+	if err != nil {
+		return nil, err
+	}
+
 	// parse config
 	cfg := Default()
-	if err := json.Unmarshal(file, cfg); err != nil {
+	if err := unmarshal(file, FormatFromPath(path), cfg); err != nil {
 		return nil, err
 	}
+	cfg.signature = signature
 
 	return cfg, nil
 }
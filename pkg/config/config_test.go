@@ -84,6 +84,46 @@ func TestConfigGetStartDelay(t *testing.T) {
 	}
 }
 
+// TestConfigGetWakeDelay tests GetWakeDelay of Config.
+func TestConfigGetWakeDelay(t *testing.T) {
+	config := &Config{WakeDelay: 3}
+	want := 3 * time.Second
+	got := config.GetWakeDelay()
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestConfigGetLockGracePeriod tests GetLockGracePeriod of Config.
+func TestConfigGetLockGracePeriod(t *testing.T) {
+	config := &Config{LockGracePeriod: 30}
+	want := 30 * time.Second
+	got := config.GetLockGracePeriod()
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestConfigGetLogLevel tests GetLogLevel of Config.
+func TestConfigGetLogLevel(t *testing.T) {
+	for _, test := range []struct {
+		level   string
+		verbose bool
+		want    string
+	}{
+		{"", false, "info"},
+		{"", true, "debug"},
+		{"warn", false, "warn"},
+		{"error", true, "error"},
+	} {
+		config := &Config{LogLevel: test.level, Verbose: test.verbose}
+		got := config.GetLogLevel()
+		if got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
+
 // TestConfigValid tests Valid of Config.
 func TestConfigValid(t *testing.T) {
 	// invalid
@@ -121,6 +161,83 @@ func TestConfigValid(t *testing.T) {
 	if got != want {
 		t.Errorf("got %t, want %t", got, want)
 	}
+
+	// valid metrics settings
+	for _, address := range []string{
+		"unix:/run/user/1000/fw-id-agent/metrics.sock",
+		"127.0.0.1:9101",
+		"[::1]:9101",
+		"localhost:9101",
+	} {
+		valid.MetricsAddress = address
+		valid.MetricsTextfileDir = "/var/lib/node_exporter/textfile"
+		if !valid.Valid() {
+			t.Errorf("metrics settings should be valid: %s", address)
+		}
+	}
+
+	// invalid metrics settings
+	for _, address := range []string{
+		"unix:relative.sock",
+		"192.168.1.10:9101",
+		"example.com:9101",
+		":9101",
+		"127.0.0.1",
+	} {
+		valid.MetricsAddress = address
+		if valid.Valid() {
+			t.Errorf("metrics address should not be valid: %s", address)
+		}
+	}
+	valid.MetricsAddress = ""
+	valid.MetricsTextfileDir = "relative/dir"
+	if valid.Valid() {
+		t.Error("relative metrics textfile dir should not be valid")
+	}
+	valid.MetricsTextfileDir = ""
+
+	// notification settings
+	for _, urgency := range []string{"", UrgencyLow, UrgencyNormal, UrgencyCritical} {
+		valid.NotificationSettings.LoginFailed.Urgency = urgency
+		if !valid.Valid() {
+			t.Errorf("notification urgency should be valid: %s", urgency)
+		}
+	}
+	valid.NotificationSettings.Logout.Urgency = "invalid"
+	if valid.Valid() {
+		t.Error("invalid notification urgency should not be valid")
+	}
+	valid.NotificationSettings.Logout.Urgency = ""
+	valid.NotificationSettings.RateLimit = -1
+	if valid.Valid() {
+		t.Error("negative notification rate limit should not be valid")
+	}
+	valid.NotificationSettings.RateLimit = 0
+
+	// log settings
+	for _, format := range []string{"", LogFormatText, LogFormatJSON, LogFormatJournald} {
+		valid.LogFormat = format
+		valid.LogLevel = "warn"
+		if !valid.Valid() {
+			t.Errorf("log format should be valid: %s", format)
+		}
+	}
+	valid.LogFormat = "invalid"
+	if valid.Valid() {
+		t.Error("invalid log format should not be valid")
+	}
+	valid.LogFormat = LogFormatText
+	valid.LogLevel = "invalid"
+	if valid.Valid() {
+		t.Error("invalid log level should not be valid")
+	}
+	valid.LogLevel = ""
+
+	// invalid session policy
+	valid.SessionPolicy = "invalid"
+	if valid.Valid() {
+		t.Error("invalid session policy should not be valid")
+	}
 }
 
 // TestConfigString tests String of Config.
@@ -142,13 +259,19 @@ func TestConfigString(t *testing.T) {
 // TestDefault tests Default.
 func TestDefault(t *testing.T) {
 	want := &Config{
-		KeepAlive:     5,
-		LoginTimeout:  15,
-		LogoutTimeout: 5,
-		RetryTimer:    15,
-		TND:           TNDConfig{Config: tnd.NewConfig()},
-		StartDelay:    0,
-		Notifications: true,
+		KeepAlive:       5,
+		LoginTimeout:    15,
+		LogoutTimeout:   5,
+		RetryTimer:      15,
+		TND:             TNDConfig{Config: tnd.NewConfig()},
+		LogFormat:       LogFormatText,
+		StartDelay:      0,
+		Notifications:   true,
+		WakeDelay:       2,
+		SessionPolicy:   SessionPolicyKeep,
+		LockGracePeriod: 60,
+
+		NotificationSettings: NotificationSettings{RateLimit: 10},
 	}
 	got := Default()
 	if !reflect.DeepEqual(got, want) {
@@ -290,9 +413,16 @@ func TestLoad(t *testing.T) {
 				},
 				tnd.NewConfig(),
 			},
-			Verbose:       true,
-			StartDelay:    0,
-			Notifications: true,
+			Verbose:         true,
+			LogFormat:       LogFormatText,
+			StartDelay:      0,
+			Notifications:   true,
+			WakeDelay:       2,
+			SessionPolicy:   SessionPolicyKeep,
+			LockGracePeriod: 60,
+			signature:       SignatureNotVerified,
+
+			NotificationSettings: NotificationSettings{RateLimit: 10},
 		}
 		if !reflect.DeepEqual(want.TND.Config, cfg.TND.Config) {
 			t.Errorf("got %v, want %v", cfg.TND.Config, want.TND.Config)
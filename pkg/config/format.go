@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.yaml.in/yaml/v3"
+)
+
+// Format is a configuration file format.
+type Format string
+
+// Formats.
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// FormatFromPath returns the configuration file format based on the file
+// extension in path. Files with unknown extensions are treated as JSON.
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+// ParseFormat returns the Format with name s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatJSON, FormatYAML, FormatTOML:
+		return f, nil
+	case "yml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("unknown config format %q", s)
+}
+
+// normalize converts the JSON numbers in v to int64 or float64 values and
+// removes null values, so v can be encoded in other formats.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if e == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = normalize(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// toJSON converts the YAML or TOML document in b in format f to JSON.
+func toJSON(b []byte, f Format) ([]byte, error) {
+	var v any
+	switch f {
+	case FormatYAML:
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		m := map[string]any{}
+		if err := toml.Unmarshal(b, &m); err != nil {
+			return nil, err
+		}
+		v = m
+	default:
+		return b, nil
+	}
+	if v == nil {
+		return nil, errors.New("empty config document")
+	}
+	return json.Marshal(v)
+}
+
+// fromJSON converts the JSON document in b to format f.
+func fromJSON(b []byte, f Format) ([]byte, error) {
+	if f == FormatJSON {
+		return b, nil
+	}
+
+	// decode json keeping numbers intact
+	var v any
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	v = normalize(v)
+
+	switch f {
+	case FormatYAML:
+		return yaml.Marshal(v)
+	case FormatTOML:
+		buf := &bytes.Buffer{}
+		if err := toml.NewEncoder(buf).Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unknown config format %q", f)
+}
+
+// unmarshal parses the configuration in b in format f into cfg.
+func unmarshal(b []byte, f Format, cfg *Config) error {
+	j, err := toJSON(b, f)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(j, cfg)
+}
+
+// Marshal returns Config in format f.
+func (c *Config) Marshal(f Format) ([]byte, error) {
+	if f == FormatJSON {
+		return json.MarshalIndent(c, "", "\t")
+	}
+	b, err := c.JSON()
+	if err != nil {
+		return nil, err
+	}
+	return fromJSON(b, f)
+}
+
+// NewFromYAML returns a new config parsed from YAML in b.
+func NewFromYAML(b []byte) (*Config, error) {
+	s := &Config{}
+	if err := unmarshal(b, FormatYAML, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewFromTOML returns a new config parsed from TOML in b.
+func NewFromTOML(b []byte) (*Config, error) {
+	s := &Config{}
+	if err := unmarshal(b, FormatTOML, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Convert converts the configuration in b from format from to format to. The
+// configuration is parsed and validated before conversion.
+func Convert(b []byte, from, to Format) ([]byte, error) {
+	cfg := Default()
+	if err := unmarshal(b, from, cfg); err != nil {
+		return nil, err
+	}
+	if !cfg.Valid() {
+		return nil, errors.New("invalid config")
+	}
+	return cfg.Marshal(to)
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/telekom-mms/tnd/pkg/tnd"
+)
+
+// testValidConfig returns a valid config for testing.
+func testValidConfig() *Config {
+	c := Default()
+	c.ServiceURL = "https://myservice.mycompany.com:443"
+	c.Realm = "MYKERBEROSREALM.COM"
+	c.TND.HTTPSServers = []TNDHTTPSConfig{
+		{
+			URL:  "https://tnd1.mycompany.com:443",
+			Hash: "ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789",
+		},
+	}
+	c.Verbose = true
+	return c
+}
+
+// TestFormatFromPath tests FormatFromPath.
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]Format{
+		"/etc/fw-id-agent.json": FormatJSON,
+		"/etc/fw-id-agent.yaml": FormatYAML,
+		"/etc/fw-id-agent.yml":  FormatYAML,
+		"/etc/fw-id-agent.YML":  FormatYAML,
+		"/etc/fw-id-agent.toml": FormatTOML,
+		"/etc/fw-id-agent":      FormatJSON,
+		"/etc/fw-id-agent.conf": FormatJSON,
+	} {
+		got := FormatFromPath(path)
+		if got != want {
+			t.Errorf("%s: got %s, want %s", path, got, want)
+		}
+	}
+}
+
+// TestParseFormat tests ParseFormat.
+func TestParseFormat(t *testing.T) {
+	for s, want := range map[string]Format{
+		"json": FormatJSON,
+		"YAML": FormatYAML,
+		"yml":  FormatYAML,
+		"toml": FormatTOML,
+	} {
+		got, err := ParseFormat(s)
+		if err != nil || got != want {
+			t.Errorf("%s: got %s, %v, want %s", s, got, err, want)
+		}
+	}
+
+	if _, err := ParseFormat("ini"); err == nil {
+		t.Error("unknown format should return error")
+	}
+}
+
+// TestNewFromYAML tests NewFromYAML.
+func TestNewFromYAML(t *testing.T) {
+	// test invalid
+	for _, invalid := range []string{
+		"",
+		"ServiceURL: [",
+		"KeepAlive: invalid",
+	} {
+		if _, err := NewFromYAML([]byte(invalid)); err == nil {
+			t.Errorf("invalid yaml %q should return error", invalid)
+		}
+	}
+
+	// test valid
+	want := testValidConfig()
+	b, err := want.Marshal(FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewFromYAML(b)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestNewFromTOML tests NewFromTOML.
+func TestNewFromTOML(t *testing.T) {
+	// test invalid
+	for _, invalid := range []string{
+		"ServiceURL = ",
+		"KeepAlive = \"invalid\"",
+	} {
+		if _, err := NewFromTOML([]byte(invalid)); err == nil {
+			t.Errorf("invalid toml %q should return error", invalid)
+		}
+	}
+
+	// test valid
+	want := testValidConfig()
+	b, err := want.Marshal(FormatTOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewFromTOML(b)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestConvert tests Convert.
+func TestConvert(t *testing.T) {
+	valid, err := testValidConfig().Marshal(FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// test invalid input
+	if _, err := Convert([]byte("{"), FormatJSON, FormatYAML); err == nil {
+		t.Error("invalid input should return error")
+	}
+
+	// test invalid config
+	if _, err := Convert([]byte("{}"), FormatJSON, FormatYAML); err == nil {
+		t.Error("invalid config should return error")
+	}
+
+	// test all conversions
+	for _, from := range []Format{FormatJSON, FormatYAML, FormatTOML} {
+		in, err := Convert(valid, FormatJSON, from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, to := range []Format{FormatJSON, FormatYAML, FormatTOML} {
+			out, err := Convert(in, from, to)
+			if err != nil {
+				t.Errorf("%s to %s: %v", from, to, err)
+				continue
+			}
+			got := Default()
+			if err := unmarshal(out, to, got); err != nil {
+				t.Errorf("%s to %s: %v", from, to, err)
+			}
+			if !reflect.DeepEqual(got, testValidConfig()) {
+				t.Errorf("%s to %s: got %v, want %v", from, to,
+					got, testValidConfig())
+			}
+		}
+	}
+}
+
+// TestLoadFormats tests Load with YAML and TOML files.
+func TestLoadFormats(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"config.yaml": `# service used for logins
+ServiceURL: https://myservice.mycompany.com:443
+Realm: MYKERBEROSREALM.COM
+TND:
+  HTTPSServers:
+    - URL: https://tnd1.mycompany.com:443
+      Hash: ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789
+  Config:
+    # 5s, values are in nanoseconds
+    HTTPSTimeout: 5000000000
+Verbose: true
+`,
+		"config.toml": `# service used for logins
+ServiceURL = "https://myservice.mycompany.com:443"
+Realm = "MYKERBEROSREALM.COM"
+Verbose = true
+
+[[TND.HTTPSServers]]
+URL = "https://tnd1.mycompany.com:443"
+Hash = "ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789"
+
+[TND.Config]
+# 5s, values are in nanoseconds
+HTTPSTimeout = 5000000000
+`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Load(path)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		want := testValidConfig()
+		want.TND.Config = tnd.NewConfig()
+		want.signature = SignatureNotVerified
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
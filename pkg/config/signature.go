@@ -0,0 +1,170 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+)
+
+var (
+	// PublicKey is the base64 encoded ed25519 public key used to verify
+	// config file signatures, to be set at compile time.
+	PublicKey = ""
+
+	// PublicKeyFile is the file containing the ed25519 public key used to
+	// verify config file signatures, if PublicKey is not set. The file
+	// must be owned by root and must not be writable by other users.
+	PublicKeyFile = "/etc/fw-id-agent.pub"
+
+	// SignatureExtension is appended to the config file path to get the
+	// path of the detached signature file.
+	SignatureExtension = ".sig"
+)
+
+// SignatureStatus is the signature verification status of a config.
+type SignatureStatus uint32
+
+// SignatureStatus states.
+const (
+	SignatureUnknown SignatureStatus = iota
+	SignatureNotVerified
+	SignatureValid
+)
+
+// String returns s as string.
+func (s SignatureStatus) String() string {
+	switch s {
+	case SignatureUnknown:
+		return "unknown"
+	case SignatureNotVerified:
+		return "not verified"
+	case SignatureValid:
+		return "valid"
+	}
+	return ""
+}
+
+// parsePublicKey parses the public key in b, either as base64 encoded raw
+// key or as PEM encoded PKIX key.
+func parsePublicKey(b []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(b); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not an ed25519 key")
+		}
+		return pub, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size %d", len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// osStat is os.Stat for testing.
+var osStat = os.Stat
+
+// checkKeyFile checks that the public key file is owned by root and not
+// writable by other users.
+func checkKeyFile(path string) error {
+	fi, err := osStat(path)
+	if err != nil {
+		return err
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); !ok || st.Uid != 0 {
+		return fmt.Errorf("public key file %s is not owned by root", path)
+	}
+	if fi.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("public key file %s is writable by group or others", path)
+	}
+	return nil
+}
+
+// publicKey returns the public key used to verify config file signatures or
+// nil if no public key is configured.
+func publicKey() (ed25519.PublicKey, error) {
+	if PublicKey != "" {
+		return parsePublicKey([]byte(PublicKey))
+	}
+
+	if err := checkKeyFile(PublicKeyFile); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// no public key configured
+			return nil, nil
+		}
+		return nil, err
+	}
+	b, err := os.ReadFile(PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return parsePublicKey(b)
+}
+
+// SignatureRequired returns whether a public key is configured and config
+// files must have a valid signature.
+func SignatureRequired() (bool, error) {
+	key, err := publicKey()
+	if err != nil {
+		return false, fmt.Errorf("could not load public key: %w", err)
+	}
+	return key != nil, nil
+}
+
+// parseSignature parses the signature in b, either as raw signature or as
+// base64 encoded signature.
+func parseSignature(b []byte) ([]byte, error) {
+	if len(b) == ed25519.SignatureSize {
+		return b, nil
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("invalid signature size %d", len(sig))
+	}
+	return sig, nil
+}
+
+// Verify verifies the contents b of the config file in path with the detached
+// signature in the signature file. If no public key is configured, the config
+// file is not verified. If a public key is configured, a missing or invalid
+// signature results in an error.
+func Verify(path string, b []byte) (SignatureStatus, error) {
+	key, err := publicKey()
+	if err != nil {
+		return SignatureUnknown, fmt.Errorf("could not load public key: %w", err)
+	}
+	if key == nil {
+		return SignatureNotVerified, nil
+	}
+
+	s, err := os.ReadFile(path + SignatureExtension)
+	if err != nil {
+		return SignatureUnknown, fmt.Errorf("could not read signature: %w", err)
+	}
+	sig, err := parseSignature(s)
+	if err != nil {
+		return SignatureUnknown, fmt.Errorf("could not parse signature: %w", err)
+	}
+	if !ed25519.Verify(key, b, sig) {
+		return SignatureUnknown, errors.New("invalid signature")
+	}
+	return SignatureValid, nil
+}
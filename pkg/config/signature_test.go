@@ -0,0 +1,256 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testKeyPair returns a new ed25519 key pair for testing.
+func testKeyPair(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+// TestSignatureStatusString tests String of SignatureStatus.
+func TestSignatureStatusString(t *testing.T) {
+	for v, want := range map[SignatureStatus]string{
+		SignatureUnknown:     "unknown",
+		SignatureNotVerified: "not verified",
+		SignatureValid:       "valid",
+		123:                  "",
+	} {
+		got := v.String()
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
+// TestParsePublicKey tests parsePublicKey.
+func TestParsePublicKey(t *testing.T) {
+	pub, _ := testKeyPair(t)
+
+	// test invalid
+	for _, invalid := range [][]byte{
+		[]byte("invalid"),
+		[]byte(base64.StdEncoding.EncodeToString([]byte("too short"))),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("invalid")}),
+	} {
+		if _, err := parsePublicKey(invalid); err == nil {
+			t.Errorf("invalid key %s should return error", invalid)
+		}
+	}
+
+	// test base64
+	got, err := parsePublicKey([]byte(base64.StdEncoding.EncodeToString(pub) + "\n"))
+	if err != nil || !pub.Equal(got) {
+		t.Errorf("got %v, %v, want %v", got, err, pub)
+	}
+
+	// test pem
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = parsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil || !pub.Equal(got) {
+		t.Errorf("got %v, %v, want %v", got, err, pub)
+	}
+}
+
+// testFileInfo is a fs.FileInfo for testing.
+type testFileInfo struct {
+	mode fs.FileMode
+	uid  uint32
+}
+
+func (t *testFileInfo) Name() string       { return "test" }
+func (t *testFileInfo) Size() int64        { return 0 }
+func (t *testFileInfo) Mode() fs.FileMode  { return t.mode }
+func (t *testFileInfo) ModTime() time.Time { return time.Time{} }
+func (t *testFileInfo) IsDir() bool        { return false }
+func (t *testFileInfo) Sys() any           { return &syscall.Stat_t{Uid: t.uid} }
+
+// TestCheckKeyFile tests checkKeyFile.
+func TestCheckKeyFile(t *testing.T) {
+	defer func() { osStat = os.Stat }()
+
+	// test stat error
+	osStat = func(string) (fs.FileInfo, error) {
+		return nil, errors.New("test error")
+	}
+	if err := checkKeyFile("test"); err == nil {
+		t.Error("stat error should return error")
+	}
+
+	// test invalid owner and permissions
+	for _, fi := range []*testFileInfo{
+		{mode: 0644, uid: 1000},
+		{mode: 0664, uid: 0},
+		{mode: 0646, uid: 0},
+	} {
+		osStat = func(string) (fs.FileInfo, error) {
+			return fi, nil
+		}
+		if err := checkKeyFile("test"); err == nil {
+			t.Errorf("%v should return error", fi)
+		}
+	}
+
+	// test valid
+	osStat = func(string) (fs.FileInfo, error) {
+		return &testFileInfo{mode: 0644, uid: 0}, nil
+	}
+	if err := checkKeyFile("test"); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestVerify tests Verify.
+func TestVerify(t *testing.T) {
+	oldPublicKey := PublicKey
+	oldPublicKeyFile := PublicKeyFile
+	defer func() {
+		PublicKey = oldPublicKey
+		PublicKeyFile = oldPublicKeyFile
+		osStat = os.Stat
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	content := []byte(`{"ServiceURL": "https://myservice.mycompany.com:443"}`)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pub, priv := testKeyPair(t)
+	_, other := testKeyPair(t)
+
+	// test no public key
+	PublicKey = ""
+	PublicKeyFile = filepath.Join(dir, "does-not-exist.pub")
+	if s, err := Verify(path, content); err != nil || s != SignatureNotVerified {
+		t.Errorf("got %s, %v, want %s", s, err, SignatureNotVerified)
+	}
+
+	// test invalid public key
+	PublicKey = "invalid"
+	if _, err := Verify(path, content); err == nil {
+		t.Error("invalid public key should return error")
+	}
+
+	// test missing signature
+	PublicKey = base64.StdEncoding.EncodeToString(pub)
+	if _, err := Verify(path, content); err == nil {
+		t.Error("missing signature should return error")
+	}
+
+	// test invalid signatures
+	for _, sig := range [][]byte{
+		[]byte("invalid"),
+		[]byte(base64.StdEncoding.EncodeToString([]byte("too short"))),
+		ed25519.Sign(other, content),
+		ed25519.Sign(priv, []byte("other content")),
+	} {
+		if err := os.WriteFile(path+SignatureExtension, sig, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Verify(path, content); err == nil {
+			t.Errorf("invalid signature %x should return error", sig)
+		}
+	}
+
+	// test valid raw and base64 signatures
+	sig := ed25519.Sign(priv, content)
+	for _, s := range [][]byte{
+		sig,
+		[]byte(base64.StdEncoding.EncodeToString(sig) + "\n"),
+	} {
+		if err := os.WriteFile(path+SignatureExtension, s, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if s, err := Verify(path, content); err != nil || s != SignatureValid {
+			t.Errorf("got %s, %v, want %s", s, err, SignatureValid)
+		}
+	}
+
+	// test public key file
+	PublicKey = ""
+	PublicKeyFile = filepath.Join(dir, "key.pub")
+	if err := os.WriteFile(PublicKeyFile, []byte(base64.StdEncoding.EncodeToString(pub)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	osStat = func(string) (fs.FileInfo, error) {
+		return &testFileInfo{mode: 0644, uid: 0}, nil
+	}
+	if s, err := Verify(path, content); err != nil || s != SignatureValid {
+		t.Errorf("got %s, %v, want %s", s, err, SignatureValid)
+	}
+
+	// test insecure public key file
+	osStat = func(string) (fs.FileInfo, error) {
+		return &testFileInfo{mode: 0666, uid: 0}, nil
+	}
+	if _, err := Verify(path, content); err == nil {
+		t.Error("insecure public key file should return error")
+	}
+
+	// test load with invalid signature
+	PublicKey = base64.StdEncoding.EncodeToString(pub)
+	if err := os.WriteFile(path+SignatureExtension, ed25519.Sign(other, content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("load with invalid signature should return error")
+	}
+
+	// test load with valid signature
+	if err := os.WriteFile(path+SignatureExtension, sig, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil || cfg.Signature() != SignatureValid {
+		t.Errorf("load with valid signature failed: %v", err)
+	}
+}
+
+// TestSignatureRequired tests SignatureRequired.
+func TestSignatureRequired(t *testing.T) {
+	oldPublicKey := PublicKey
+	oldPublicKeyFile := PublicKeyFile
+	defer func() {
+		PublicKey = oldPublicKey
+		PublicKeyFile = oldPublicKeyFile
+	}()
+
+	// test no public key
+	PublicKey = ""
+	PublicKeyFile = filepath.Join(t.TempDir(), "does-not-exist.pub")
+	if ok, err := SignatureRequired(); ok || err != nil {
+		t.Errorf("got %t, %v, want false, nil", ok, err)
+	}
+
+	// test invalid public key
+	PublicKey = "invalid"
+	if _, err := SignatureRequired(); err == nil {
+		t.Error("invalid public key should return error")
+	}
+
+	// test public key
+	pub, _ := testKeyPair(t)
+	PublicKey = base64.StdEncoding.EncodeToString(pub)
+	if ok, err := SignatureRequired(); !ok || err != nil {
+		t.Errorf("got %t, %v, want true, nil", ok, err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package history contains the agent event history.
+package history
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Type is the type of an event.
+type Type string
+
+// Event types.
+const (
+	TypeTND          Type = "tnd"
+	TypeLogin        Type = "login"
+	TypeLogout       Type = "logout"
+	TypeKeepAlive    Type = "keep-alive"
+	TypeSleep        Type = "sleep"
+	TypeSession      Type = "session"
+	TypeCCache       Type = "ccache"
+	TypeDBus         Type = "dbus"
+	TypeState        Type = "state"
+	TypeNetwork      Type = "network"
+	TypeConfig       Type = "config"
+	TypeNotification Type = "notification"
+)
+
+// Event is an agent event.
+type Event struct {
+	Time    time.Time
+	Type    Type
+	Message string
+	Error   string            `json:",omitempty"`
+	Details map[string]string `json:",omitempty"`
+}
+
+// NewEvent returns a new event of type t with message at the current time.
+func NewEvent(t Type, message string) *Event {
+	return &Event{
+		Time:    time.Now(),
+		Type:    t,
+		Message: message,
+	}
+}
+
+// WithError sets the error of the event to err and returns the event.
+func (e *Event) WithError(err error) *Event {
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return e
+}
+
+// WithDetail adds detail key with value to the event and returns the event.
+func (e *Event) WithDetail(key, value string) *Event {
+	if e.Details == nil {
+		e.Details = make(map[string]string)
+	}
+	e.Details[key] = value
+	return e
+}
+
+// JSON returns events as JSON.
+func JSON(events []*Event) ([]byte, error) {
+	if events == nil {
+		events = []*Event{}
+	}
+	return json.Marshal(events)
+}
+
+// JSONIndent returns events as indented JSON.
+func JSONIndent(events []*Event) ([]byte, error) {
+	if events == nil {
+		events = []*Event{}
+	}
+	return json.MarshalIndent(events, "", "  ")
+}
+
+// NewFromJSON returns new events parsed from JSON in b.
+func NewFromJSON(b []byte) ([]*Event, error) {
+	events := []*Event{}
+	if err := json.Unmarshal(b, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// History is a bounded event history, if it is full, the oldest events are
+// overwritten by new events.
+type History struct {
+	mutex  sync.Mutex
+	events []*Event
+	next   int
+	full   bool
+}
+
+// Add adds event e to the history.
+func (h *History) Add(e *Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.events[h.next] = e
+	h.next = (h.next + 1) % len(h.events)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Events returns all events in the history that occurred after since, ordered
+// from oldest to newest.
+func (h *History) Events(since time.Time) []*Event {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	// get events in order
+	ordered := h.events[:h.next]
+	if h.full {
+		ordered = make([]*Event, 0, len(h.events))
+		ordered = append(ordered, h.events[h.next:]...)
+		ordered = append(ordered, h.events[:h.next]...)
+	}
+
+	// filter events
+	events := []*Event{}
+	for _, e := range ordered {
+		if e.Time.After(since) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// New returns a new History with space for size events.
+func New(size int) *History {
+	if size < 1 {
+		size = 1
+	}
+	return &History{
+		events: make([]*Event, size),
+	}
+}
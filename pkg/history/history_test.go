@@ -0,0 +1,125 @@
+package history
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestEventWith tests WithError and WithDetail of Event.
+func TestEventWith(t *testing.T) {
+	e := NewEvent(TypeLogin, "test").
+		WithError(nil).
+		WithError(errors.New("test error")).
+		WithDetail("key1", "value1").
+		WithDetail("key2", "value2")
+
+	if e.Type != TypeLogin || e.Message != "test" || e.Time.IsZero() {
+		t.Errorf("invalid event: %v", e)
+	}
+	if e.Error != "test error" {
+		t.Errorf("got %s, want test error", e.Error)
+	}
+	want := map[string]string{"key1": "value1", "key2": "value2"}
+	if !reflect.DeepEqual(e.Details, want) {
+		t.Errorf("got %v, want %v", e.Details, want)
+	}
+}
+
+// TestJSON tests JSON and NewFromJSON.
+func TestJSON(t *testing.T) {
+	// test nil
+	b, err := JSON(nil)
+	if err != nil || string(b) != "[]" {
+		t.Errorf("got %s, %v, want []", b, err)
+	}
+
+	// test nil indented
+	b, err = JSONIndent(nil)
+	if err != nil || string(b) != "[]" {
+		t.Errorf("got %s, %v, want []", b, err)
+	}
+
+	// test invalid
+	if _, err := NewFromJSON([]byte("invalid")); err == nil {
+		t.Error("invalid json should return error")
+	}
+
+	// test events
+	want := []*Event{
+		{Time: time.Unix(1, 0).UTC(), Type: TypeTND, Message: "trusted"},
+		{Time: time.Unix(2, 0).UTC(), Type: TypeLogin, Message: "failed", Error: "test error",
+			Details: map[string]string{"key": "value"}},
+	}
+	b, err = JSON(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewFromJSON(b)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, %v, want %v", got, err, want)
+	}
+
+	// test events indented
+	b, err = JSONIndent(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = NewFromJSON(b)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, %v, want %v", got, err, want)
+	}
+}
+
+// TestHistory tests Add and Events of History.
+func TestHistory(t *testing.T) {
+	h := New(3)
+
+	// test empty
+	if got := h.Events(time.Time{}); len(got) != 0 {
+		t.Errorf("got %v, want empty", got)
+	}
+
+	// test not full
+	events := []*Event{}
+	for i := int64(1); i <= 5; i++ {
+		events = append(events, &Event{Time: time.Unix(i, 0)})
+	}
+	h.Add(events[0])
+	h.Add(events[1])
+	want := events[:2]
+	if got := h.Events(time.Time{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test full
+	h.Add(events[2])
+	want = events[:3]
+	if got := h.Events(time.Time{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test overwrite oldest
+	h.Add(events[3])
+	h.Add(events[4])
+	want = events[2:]
+	if got := h.Events(time.Time{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test since
+	want = events[3:]
+	if got := h.Events(time.Unix(3, 0)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test invalid size
+	h = New(0)
+	h.Add(events[0])
+	h.Add(events[1])
+	want = events[1:2]
+	if got := h.Events(time.Time{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -69,6 +69,41 @@ func (l LoginState) String() string {
 	return ""
 }
 
+// AgentState is the current high-level state of the agent.
+type AgentState uint32
+
+// AgentState states.
+const (
+	AgentStateUnknown AgentState = iota
+	AgentStateWaitingForTicket
+	AgentStateUntrusted
+	AgentStateTrustedLoggingIn
+	AgentStateLoggedIn
+	AgentStatePaused
+	AgentStateSleeping
+)
+
+// String returns a as string.
+func (a AgentState) String() string {
+	switch a {
+	case AgentStateUnknown:
+		return "unknown"
+	case AgentStateWaitingForTicket:
+		return "waiting for ticket"
+	case AgentStateUntrusted:
+		return "untrusted"
+	case AgentStateTrustedLoggingIn:
+		return "trusted, logging in"
+	case AgentStateLoggedIn:
+		return "logged in"
+	case AgentStatePaused:
+		return "paused"
+	case AgentStateSleeping:
+		return "sleeping"
+	}
+	return ""
+}
+
 // KerberosTicket is kerberos ticket info in the agent status.
 type KerberosTicket struct {
 	StartTime int64
@@ -82,21 +117,33 @@ func (k *KerberosTicket) TimesEqual(start, end int64) bool {
 
 // Status is the agent status.
 type Status struct {
-	Config         *config.Config
-	TrustedNetwork TrustedNetwork
-	LoginState     LoginState
-	LastKeepAlive  int64
-	KerberosTGT    KerberosTicket
+	Config          *config.Config
+	ConfigSignature config.SignatureStatus
+	AgentState      AgentState
+	TrustedNetwork  TrustedNetwork
+	LoginState      LoginState
+	SourceIP        string
+	LastLogin       int64
+	LastKeepAlive   int64
+	KerberosTGT     KerberosTicket
+	LogLevel        string
+	LoginFailures   uint32
 }
 
 // Copy returns a copy of Status.
 func (s *Status) Copy() *Status {
 	return &Status{
-		Config:         s.Config.Copy(),
-		TrustedNetwork: s.TrustedNetwork,
-		LoginState:     s.LoginState,
-		LastKeepAlive:  s.LastKeepAlive,
-		KerberosTGT:    s.KerberosTGT,
+		Config:          s.Config.Copy(),
+		ConfigSignature: s.ConfigSignature,
+		AgentState:      s.AgentState,
+		TrustedNetwork:  s.TrustedNetwork,
+		LoginState:      s.LoginState,
+		SourceIP:        s.SourceIP,
+		LastLogin:       s.LastLogin,
+		LastKeepAlive:   s.LastKeepAlive,
+		KerberosTGT:     s.KerberosTGT,
+		LogLevel:        s.LogLevel,
+		LoginFailures:   s.LoginFailures,
 	}
 }
 
@@ -73,6 +73,24 @@ func TestLoginStateString(t *testing.T) {
 	}
 }
 
+// TestAgentStateString tests String of AgentState.
+func TestAgentStateString(t *testing.T) {
+	for k, v := range map[AgentState]string{
+		AgentStateUnknown:          "unknown",
+		AgentStateWaitingForTicket: "waiting for ticket",
+		AgentStateUntrusted:        "untrusted",
+		AgentStateTrustedLoggingIn: "trusted, logging in",
+		AgentStateLoggedIn:         "logged in",
+		AgentStatePaused:           "paused",
+		AgentStateSleeping:         "sleeping",
+		23:                         "",
+	} {
+		if k.String() != v {
+			t.Errorf("String of %v should return %s", k, v)
+		}
+	}
+}
+
 // TestKerberosTicketTimesEqual tests TimesEqual of KerberosTicket.
 func TestKerberosTicketTimesEqual(t *testing.T) {
 	// test not equal
@@ -99,11 +117,14 @@ func TestStatusCopy(t *testing.T) {
 		Config:         config.Default(),
 		TrustedNetwork: TrustedNetworkTrusted,
 		LoginState:     LoginStateLoggedIn,
+		SourceIP:       "192.168.1.10",
 		LastKeepAlive:  2023,
 		KerberosTGT: KerberosTicket{
 			StartTime: 2023,
 			EndTime:   2024,
 		},
+		LogLevel:      "debug",
+		LoginFailures: 3,
 	}
 	got := want.Copy()
 	if !reflect.DeepEqual(got, want) {
@@ -31,11 +31,17 @@ func main() {
 
 	// get initial values of properties
 	config := dbusapi.ConfigInvalid
+	configSignature := dbusapi.ConfigSignatureUnknown
+	agentState := dbusapi.AgentStateUnknown
 	trustedNetwork := dbusapi.TrustedNetworkUnknown
 	loginState := dbusapi.LoginStateUnknown
+	sourceIP := dbusapi.SourceIPInvalid
+	lastLoginAt := dbusapi.LastLoginAtInvalid
 	lastKeepAliveAt := dbusapi.LastKeepAliveAtInvalid
 	kerberosTGTStartTime := dbusapi.KerberosTGTStartTimeInvalid
 	kerberosTGTEndTime := dbusapi.KerberosTGTEndTimeInvalid
+	logLevel := dbusapi.LogLevelInvalid
+	loginFailures := dbusapi.LoginFailuresInvalid
 
 	getProperty := func(name string, val any) {
 		err = conn.Object(dbusapi.Interface, dbusapi.Path).
@@ -45,18 +51,30 @@ func main() {
 		}
 	}
 	getProperty(dbusapi.PropertyConfig, &config)
+	getProperty(dbusapi.PropertyConfigSignature, &configSignature)
+	getProperty(dbusapi.PropertyAgentState, &agentState)
 	getProperty(dbusapi.PropertyTrustedNetwork, &trustedNetwork)
 	getProperty(dbusapi.PropertyLoginState, &loginState)
+	getProperty(dbusapi.PropertySourceIP, &sourceIP)
+	getProperty(dbusapi.PropertyLastLoginAt, &lastLoginAt)
 	getProperty(dbusapi.PropertyLastKeepAliveAt, &lastKeepAliveAt)
 	getProperty(dbusapi.PropertyKerberosTGTStartTime, &kerberosTGTStartTime)
 	getProperty(dbusapi.PropertyKerberosTGTEndTime, &kerberosTGTEndTime)
+	getProperty(dbusapi.PropertyLogLevel, &logLevel)
+	getProperty(dbusapi.PropertyLoginFailures, &loginFailures)
 
 	log.Println("Config:", config)
+	log.Println("ConfigSignature:", configSignature)
+	log.Println("AgentState:", agentState)
 	log.Println("TrustedNetwork:", trustedNetwork)
 	log.Println("LoginState:", loginState)
+	log.Println("SourceIP:", sourceIP)
+	log.Println("LastLoginAt:", lastLoginAt)
 	log.Println("LastKeepAliveAt:", lastKeepAliveAt)
 	log.Println("KerberosTGTStartTime:", kerberosTGTStartTime)
 	log.Println("KerberosTGTEndTime:", kerberosTGTEndTime)
+	log.Println("LogLevel:", logLevel)
+	log.Println("LoginFailures:", loginFailures)
 
 	// handle signals
 	c := make(chan *dbus.Signal, 10)
@@ -89,6 +107,16 @@ func main() {
 					log.Fatal(err)
 				}
 				fmt.Println(config)
+			case dbusapi.PropertyConfigSignature:
+				if err := value.Store(&configSignature); err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(configSignature)
+			case dbusapi.PropertyAgentState:
+				if err := value.Store(&agentState); err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(agentState)
 			case dbusapi.PropertyTrustedNetwork:
 				if err := value.Store(&trustedNetwork); err != nil {
 					log.Fatal(err)
@@ -99,6 +127,16 @@ func main() {
 					log.Fatal(err)
 				}
 				fmt.Println(loginState)
+			case dbusapi.PropertySourceIP:
+				if err := value.Store(&sourceIP); err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(sourceIP)
+			case dbusapi.PropertyLastLoginAt:
+				if err := value.Store(&lastLoginAt); err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(lastLoginAt)
 			case dbusapi.PropertyLastKeepAliveAt:
 				if err := value.Store(&lastKeepAliveAt); err != nil {
 					log.Fatal(err)
@@ -114,6 +152,16 @@ func main() {
 					log.Fatal(err)
 				}
 				fmt.Println(kerberosTGTEndTime)
+			case dbusapi.PropertyLogLevel:
+				if err := value.Store(&logLevel); err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(logLevel)
+			case dbusapi.PropertyLoginFailures:
+				if err := value.Store(&loginFailures); err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(loginFailures)
 			}
 		}
 
@@ -127,16 +175,28 @@ func main() {
 			switch name {
 			case dbusapi.PropertyConfig:
 				config = dbusapi.ConfigInvalid
+			case dbusapi.PropertyConfigSignature:
+				configSignature = dbusapi.ConfigSignatureUnknown
+			case dbusapi.PropertyAgentState:
+				agentState = dbusapi.AgentStateUnknown
 			case dbusapi.PropertyTrustedNetwork:
 				trustedNetwork = dbusapi.TrustedNetworkUnknown
 			case dbusapi.PropertyLoginState:
 				loginState = dbusapi.LoginStateUnknown
+			case dbusapi.PropertySourceIP:
+				sourceIP = dbusapi.SourceIPInvalid
+			case dbusapi.PropertyLastLoginAt:
+				lastLoginAt = dbusapi.LastLoginAtInvalid
 			case dbusapi.PropertyLastKeepAliveAt:
 				lastKeepAliveAt = dbusapi.LastKeepAliveAtInvalid
 			case dbusapi.PropertyKerberosTGTStartTime:
 				kerberosTGTStartTime = dbusapi.KerberosTGTStartTimeInvalid
 			case dbusapi.PropertyKerberosTGTEndTime:
 				kerberosTGTEndTime = dbusapi.KerberosTGTEndTimeInvalid
+			case dbusapi.PropertyLogLevel:
+				logLevel = dbusapi.LogLevelInvalid
+			case dbusapi.PropertyLoginFailures:
+				loginFailures = dbusapi.LoginFailuresInvalid
 			}
 			fmt.Printf("Invalidated property: %s\n", name)
 		}
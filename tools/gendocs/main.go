@@ -0,0 +1,67 @@
+/*
+Gendocs generates the shell completion scripts and man pages of fw-id-agent,
+fw-id-cli and fw-id-tray from their command line definitions.
+*/
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/fw-id-agent/internal/agent"
+	"github.com/telekom-mms/fw-id-agent/internal/cli"
+	"github.com/telekom-mms/fw-id-agent/internal/cmdline"
+	"github.com/telekom-mms/fw-id-agent/internal/tray"
+)
+
+// writeFile writes the output of write to file in dir.
+func writeFile(dir, file string, write func(*bytes.Buffer) error) {
+	b := &bytes.Buffer{}
+	if err := write(b); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), b.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	output := flag.String("output", "build/docs", "write files to `directory`")
+	version := flag.String("version", agent.Version, "set `version` in man pages")
+	date := flag.String("date", time.Now().Format(time.DateOnly), "set `date` in man pages")
+	flag.Parse()
+
+	for _, cmd := range []*cmdline.Command{agent.Command(), cli.Command(), tray.Command()} {
+		// shell completions
+		completions := filepath.Join(*output, "completions")
+		for shell, file := range map[string]string{
+			"bash": cmd.Name,
+			"zsh":  "_" + cmd.Name,
+			"fish": cmd.Name + ".fish",
+		} {
+			writeFile(filepath.Join(completions, shell), file, func(b *bytes.Buffer) error {
+				return cmd.Completion(b, shell)
+			})
+		}
+
+		// gzipped man page
+		writeFile(filepath.Join(*output, "man"), cmd.Name+".1.gz", func(b *bytes.Buffer) error {
+			w, err := gzip.NewWriterLevel(b, gzip.BestCompression)
+			if err != nil {
+				return err
+			}
+			if err := cmd.ManPage(w, 1, "fw-id-agent "+*version, *date); err != nil {
+				return err
+			}
+			return w.Close()
+		})
+	}
+}